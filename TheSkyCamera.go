@@ -0,0 +1,98 @@
+package goTheSkyX
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+//	Camera capability discovery.  Not every camera can take bias frames, and the capture calls
+//	can't tell in advance.  CameraCapabilities reads the sensor size and pixel size from TheSkyX,
+//	and the service remembers them (until Close) to check subframes against.  TheSkyX has no
+//	property saying whether bias frames can be taken, so we find out by trying: a camera that
+//	refuses a bias frame with ERR_CMDFAILED is remembered as unable to take them, and that bias
+//	frame and later ones are replaced by the shortest possible dark frame.
+
+// CameraCapabilities describes what the connected camera can do.  Sizes TheSkyX doesn't
+// report are zero.
+type CameraCapabilities struct {
+	WidthPixels      int // Sensor size, unbinned
+	HeightPixels     int
+	PixelSizeMicrons float64 // At 1x1 binning
+	FrameTypes       []FrameType
+}
+
+// Supports reports whether the camera can take frames of the given type
+func (capabilities CameraCapabilities) Supports(frameType FrameType) bool {
+	return slices.Contains(capabilities.FrameTypes, frameType)
+}
+
+// ErrBinningNotSupported is returned (wrapped) when a capture asks for a binning no camera
+// can do
+var ErrBinningNotSupported = errors.New("binning not supported by camera")
+
+// minimumDarkExposure is the exposure of the dark frame taken in place of a bias frame by a
+// camera that can't take bias frames: short enough to be at or below any camera's minimum
+const minimumDarkExposure = 0.001
+
+// CameraCapabilities asks the camera what it can do, and remembers the answer so that later
+// subframes can be checked against it
+func (service *TheSkyServiceInstance) CameraCapabilities() (CameraCapabilities, error) {
+	return service.CameraCapabilitiesContext(context.Background())
+}
+
+func (service *TheSkyServiceInstance) CameraCapabilitiesContext(ctx context.Context) (CameraCapabilities, error) {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Println("TheSkyServiceInstance/CameraCapabilities ")
+	}
+	if !service.isOpen {
+		return CameraCapabilities{}, errors.New("TheSkyServiceInstance/CameraCapabilities: Connection not open")
+	}
+	if err := ctx.Err(); err != nil {
+		return CameraCapabilities{}, err
+	}
+	capabilities, err := service.driverFor(ctx).GetCameraCapabilities()
+	if err != nil {
+		fmt.Println("TheSkyServiceInstance/CameraCapabilities error from driver:", err)
+		return CameraCapabilities{}, err
+	}
+	if service.biasFramesRefused {
+		capabilities.FrameTypes = withoutBiasFrames(capabilities.FrameTypes)
+	}
+	service.cameraCapabilities = &capabilities
+	return capabilities, nil
+}
+
+// checkBinning refuses a binning below 1x1.  TheSkyX doesn't report the camera's largest
+// binning, so higher binnings are let through for the camera to refuse.
+func (service *TheSkyServiceInstance) checkBinning(binning int) error {
+	if binning < 1 {
+		return fmt.Errorf("%w: %dx%d", ErrBinningNotSupported, binning, binning)
+	}
+	return nil
+}
+
+// biasFrameRefused reports whether an error starting a bias frame means the camera can't take
+// them, and if so remembers that
+func (service *TheSkyServiceInstance) biasFrameRefused(err error) bool {
+	if !errors.Is(err, ErrCommandFailed) {
+		return false
+	}
+	service.biasFramesRefused = true
+	if service.cameraCapabilities != nil {
+		service.cameraCapabilities.FrameTypes = withoutBiasFrames(service.cameraCapabilities.FrameTypes)
+	}
+	return true
+}
+
+// biasFramesUnsupported reports whether the camera has refused a bias frame
+func (service *TheSkyServiceInstance) biasFramesUnsupported() bool {
+	return service.biasFramesRefused
+}
+
+func withoutBiasFrames(frameTypes []FrameType) []FrameType {
+	return slices.DeleteFunc(slices.Clone(frameTypes), func(frameType FrameType) bool {
+		return frameType == BiasFrame
+	})
+}
@@ -0,0 +1,103 @@
+package goTheSkyX
+
+import (
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCameraCapabilitiesDriver(t *testing.T) {
+
+	t.Run("reads capabilities from fake server", func(t *testing.T) {
+		fake, driver := startFakeForDriver(t)
+		defer fake.Close()
+		defer driver.Close()
+
+		capabilities, err := driver.GetCameraCapabilities()
+		require.Nil(t, err, "Unable to get capabilities")
+		require.Equal(t, 4656, capabilities.WidthPixels)
+		require.Equal(t, 3520, capabilities.HeightPixels)
+		require.Equal(t, 3.8, capabilities.PixelSizeMicrons)
+		require.True(t, capabilities.Supports(BiasFrame), "Bias frames assumed until refused")
+	})
+
+	t.Run("property not reported leaves the others", func(t *testing.T) {
+		fake, driver := startFakeForDriver(t)
+		defer fake.Close()
+		defer driver.Close()
+		fake.SetSensor(1920, 1080, 0.0)
+
+		capabilities, err := driver.GetCameraCapabilities()
+		require.Nil(t, err, "Unable to get capabilities")
+		require.Equal(t, 1920, capabilities.WidthPixels)
+		require.Equal(t, 1080, capabilities.HeightPixels)
+		require.Equal(t, 0.0, capabilities.PixelSizeMicrons, "Pixel size should be unknown")
+	})
+}
+
+func TestCameraCapabilitiesService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sensor := CameraCapabilities{
+		WidthPixels: 3000, HeightPixels: 2000, PixelSizeMicrons: 5.4,
+		FrameTypes: []FrameType{DarkFrame, BiasFrame, FlatFrame},
+	}
+
+	t.Run("refused bias falls back to minimum dark", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		mockDriver.EXPECT().StartBiasFrameCapture(1, 2.0).Return(&TheSkyXError{Code: 206, Message: "TypeError: Not supported."})
+		mockDriver.EXPECT().StartDarkFrameCapture(1, minimumDarkExposure, 2.0).Return(nil)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil)
+		mockDriver.EXPECT().IsCaptureDone().Return(true, nil)
+		require.Nil(t, service.CaptureBiasFrame(1, 2.0))
+
+		//	Having been refused once, we don't try again
+		mockDriver.EXPECT().StartDarkFrameCapture(1, minimumDarkExposure, 2.0).Return(nil)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil)
+		mockDriver.EXPECT().IsCaptureDone().Return(true, nil)
+		require.Nil(t, service.CaptureBiasFrame(1, 2.0))
+
+		mockDriver.EXPECT().GetCameraCapabilities().Return(sensor, nil)
+		capabilities, err := service.CameraCapabilities()
+		require.Nil(t, err)
+		require.False(t, capabilities.Supports(BiasFrame))
+		require.True(t, capabilities.Supports(DarkFrame))
+	})
+
+	t.Run("other bias errors are returned", func(t *testing.T) {
+		service, mockDriver, _ := setUpConnectedMockService(ctrl)
+		mockDriver.EXPECT().StartBiasFrameCapture(1, 2.0).Return(ErrNoLink)
+		require.ErrorIs(t, service.CaptureBiasFrame(1, 2.0), ErrNoLink)
+		mockDriver.EXPECT().StartBiasFrameCapture(1, 2.0).Return(ErrNoLink)
+		require.ErrorIs(t, service.CaptureBiasFrame(1, 2.0), ErrNoLink, "Should still try bias frames")
+	})
+
+	t.Run("binning below 1 is refused before capture", func(t *testing.T) {
+		service, _, _ := setUpConnectedMockService(ctrl)
+		require.ErrorIs(t, service.CaptureDarkFrame(0, 10.0, 2.0), ErrBinningNotSupported)
+		_, err := service.CaptureAndMeasureFlatFrame(1.0, -1, 1, 2.0, true)
+		require.ErrorIs(t, err, ErrBinningNotSupported)
+		_, err = service.MeasureDownloadTime(0)
+		require.ErrorIs(t, err, ErrBinningNotSupported)
+	})
+
+	t.Run("refusal forgotten on close", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		mockDriver.EXPECT().StartBiasFrameCapture(1, 2.0).Return(ErrCommandFailed)
+		mockDriver.EXPECT().StartDarkFrameCapture(1, minimumDarkExposure, 2.0).Return(nil)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil)
+		mockDriver.EXPECT().IsCaptureDone().Return(true, nil)
+		require.Nil(t, service.CaptureBiasFrame(1, 2.0))
+		mockDriver.EXPECT().Close().Return(nil)
+		require.Nil(t, service.Close())
+
+		mockDriver.EXPECT().Connect("localhost", 3040).Return(nil)
+		mockDriver.EXPECT().ConnectCamera().Return(nil)
+		require.Nil(t, service.Connect("localhost", 3040))
+		mockDriver.EXPECT().StartBiasFrameCapture(1, 2.0).Return(nil)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil)
+		mockDriver.EXPECT().IsCaptureDone().Return(true, nil)
+		require.Nil(t, service.CaptureBiasFrame(1, 2.0), "Bias should be taken as a bias frame again")
+	})
+}
@@ -0,0 +1,55 @@
+package goTheSkyX
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+//	Details of a captured frame, for indexing the frames afterwards (e.g. by a stacking tool).
+//	The capture calls that return a CaptureResult ask TheSkyX about the image once it has
+//	been taken, so each costs one more exchange with the server than the plain call.
+
+// CaptureResult describes a frame that has been captured
+type CaptureResult struct {
+	Path        string    // Where the frame was saved; "" if it wasn't
+	StartTime   time.Time // When the exposure started (UTC), from the image's DATE-OBS
+	Temperature float64   // Sensor temperature during the exposure, from the image's CCD-TEMP
+	FrameType   FrameType
+	Exposure    float64 // Seconds; 0 for bias frames
+	Binning     int
+	FilterSlot  int   // FilterSlotNoFilter for darks and bias frames
+	ADU         int64 // Average ADU, for flat frames only
+}
+
+// dateObsLayouts are the forms TheSkyX writes DATE-OBS in, with and without fractional seconds
+var dateObsLayouts = []string{"2006-01-02T15:04:05.999999999", "2006-01-02T15:04:05"}
+
+// parseDateObs interprets a FITS DATE-OBS value, which is UTC
+func parseDateObs(value string) (time.Time, error) {
+	for _, layout := range dateObsLayouts {
+		if parsed, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("DATE-OBS %q is not a date and time", value)
+}
+
+// captureResult describes the frame just captured.  The driver supplies what only TheSkyX
+// knows (path, start time, temperature) and the rest comes from the capture request.  If the
+// caller doesn't want a result, TheSkyX isn't asked and an empty result is returned.
+func (service *TheSkyServiceInstance) captureResult(ctx context.Context, frame capturedFrame, wantResult bool) (CaptureResult, error) {
+	if !wantResult {
+		return CaptureResult{}, nil
+	}
+	result, err := service.driverFor(ctx).GetCapturedImageInfo()
+	if err != nil {
+		fmt.Println("TheSkyServiceInstance error from driver getting captured image details:", err)
+		return CaptureResult{}, err
+	}
+	result.FrameType = frame.frameType
+	result.Exposure = frame.exposure
+	result.Binning = frame.binning
+	result.FilterSlot = frame.filterSlot
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package goTheSkyX
+
+import (
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestCaptureResult(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	sessionStart := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+
+	t.Run("dark saved by AutoSave", func(t *testing.T) {
+		fake, service := startFakeForSaving(t, ctrl)
+		defer fake.Close()
+		defer service.Close()
+
+		result, err := service.CaptureDarkFrameResult(2, 30.0, 0.0)
+		require.Nil(t, err, "Capture failed")
+		require.Regexp(t, `/Camera AutoSave/Image\.00000001\.Dark\.fit$`, result.Path)
+		require.Equal(t, sessionStart, result.StartTime)
+		require.Equal(t, -10.0, result.Temperature)
+		require.Equal(t, DarkFrame, result.FrameType)
+		require.Equal(t, 30.0, result.Exposure)
+		require.Equal(t, 2, result.Binning)
+		require.Equal(t, FilterSlotNoFilter, result.FilterSlot)
+	})
+
+	t.Run("bias saved with save settings", func(t *testing.T) {
+		fake, service := startFakeForSaving(t, ctrl)
+		defer fake.Close()
+		defer service.Close()
+		require.Nil(t, service.SetSaveSettings(SaveSettings{Directory: "/library"}))
+
+		result, err := service.CaptureBiasFrameResult(1, 0.0)
+		require.Nil(t, err, "Capture failed")
+		require.Equal(t, "/library/bias_0s_1x1_0001.fit", result.Path)
+		require.Equal(t, BiasFrame, result.FrameType)
+	})
+
+	t.Run("bias replaced by dark is reported as dark", func(t *testing.T) {
+		fake, service := startFakeForSaving(t, ctrl)
+		defer fake.Close()
+		defer service.Close()
+		fake.SetSupportsBiasFrames(false)
+
+		result, err := service.CaptureBiasFrameResult(1, 0.0)
+		require.Nil(t, err, "Capture failed")
+		require.Equal(t, DarkFrame, result.FrameType)
+		require.Equal(t, minimumDarkExposure, result.Exposure)
+	})
+
+	t.Run("unsaved flat has no path", func(t *testing.T) {
+		fake, service := startFakeForSaving(t, ctrl)
+		defer fake.Close()
+		defer service.Close()
+
+		result, err := service.CaptureAndMeasureFlatFrameResult(2.0, 1, 2, 0.0, false)
+		require.Nil(t, err, "Capture failed")
+		require.Equal(t, "", result.Path)
+		require.Equal(t, FlatFrame, result.FrameType)
+		require.Equal(t, 2, result.FilterSlot)
+		require.Greater(t, result.ADU, int64(0))
+	})
+
+	t.Run("parse image details", func(t *testing.T) {
+		result, err := parseCapturedImageInfo("'2024-03-05T01:02:03.250'\tC:\\Images\\dark.fit\t-9.75")
+		require.Nil(t, err)
+		require.Equal(t, "C:\\Images\\dark.fit", result.Path)
+		require.Equal(t, time.Date(2024, 3, 5, 1, 2, 3, 250000000, time.UTC), result.StartTime)
+		require.Equal(t, -9.75, result.Temperature)
+
+		result, err = parseCapturedImageInfo("2024-03-05T01:02:03\t\t0")
+		require.Nil(t, err)
+		require.Equal(t, "", result.Path)
+		require.Equal(t, time.Date(2024, 3, 5, 1, 2, 3, 0, time.UTC), result.StartTime)
+	})
+
+	t.Run("parse malformed image details", func(t *testing.T) {
+		var malformed *MalformedReplyError
+		_, err := parseCapturedImageInfo("2024-03-05T01:02:03\tdark.fit")
+		require.ErrorAs(t, err, &malformed)
+		_, err = parseCapturedImageInfo("yesterday\tdark.fit\t-10")
+		require.ErrorAs(t, err, &malformed)
+		_, err = parseCapturedImageInfo("2024-03-05T01:02:03\tdark.fit\tcold")
+		require.ErrorAs(t, err, &malformed)
+	})
+}
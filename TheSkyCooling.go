@@ -0,0 +1,242 @@
+package goTheSkyX
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+//	Cooling services built on the basic StartCooling / GetCameraTemperature calls: waiting
+//	for the camera to reach and hold its target temperature.
+
+// ErrCoolingTimeout is returned (wrapped, with details) when the camera does not settle at
+// its target temperature within the allowed time
+var ErrCoolingTimeout = errors.New("camera did not reach target temperature")
+
+// CoolingWaitSettings describes how WaitForTargetTemperature should wait
+type CoolingWaitSettings struct {
+	TargetTemperature      float64 // The set point given to StartCooling
+	Tolerance              float64 // How close, in degrees, counts as "at target"
+	SettleSeconds          int     // How long the temperature must stay within tolerance
+	PollingIntervalSeconds int     // Time between temperature readings
+	TimeoutMinutes         int     // Give up after this long
+	Progress               func(progress CoolingProgress)
+}
+
+// CoolingProgress is reported to the progress callback after each temperature reading
+type CoolingProgress struct {
+	Temperature    float64
+	CoolerPower    float64 // Percent
+	SecondsElapsed int     // Since the wait started
+	SecondsStable  int     // How long the temperature has been within tolerance
+}
+
+// CoolerStatus is a snapshot of the camera's cooling state
+type CoolerStatus struct {
+	Temperature        float64 // Current sensor temperature
+	SetPoint           float64 // Target temperature the cooler is regulating to
+	Regulating         bool    // Whether temperature regulation (the cooler) is switched on
+	CoolerPowerPercent float64 // Thermoelectric cooler power, 0 to 100
+}
+
+// Saturated reports whether the cooler is running flat out without having reached its set
+// point - typically a sign that the set point is too cold for tonight's ambient temperature.
+func (status CoolerStatus) Saturated() bool {
+	return status.Regulating &&
+		status.CoolerPowerPercent >= saturatedCoolerPower &&
+		status.Temperature > status.SetPoint
+}
+
+// saturatedCoolerPower is the cooler power, in percent, at which we consider it to be
+// working flat out
+const saturatedCoolerPower = 99.0
+
+// WaitForTargetTemperature waits for the camera to reach and hold the target temperature.
+// The camera should already have been told to cool (StartCooling).  It returns the final
+// temperature and cooler power.  If the temperature has not settled within the timeout,
+// it returns an error wrapping ErrCoolingTimeout.
+func (service *TheSkyServiceInstance) WaitForTargetTemperature(settings CoolingWaitSettings) (float64, float64, error) {
+	return service.WaitForTargetTemperatureContext(context.Background(), settings)
+}
+
+func (service *TheSkyServiceInstance) WaitForTargetTemperatureContext(ctx context.Context, settings CoolingWaitSettings) (float64, float64, error) {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Printf("TheSkyServiceInstance/WaitForTargetTemperature(%g +/- %g, settle %d)\n",
+			settings.TargetTemperature, settings.Tolerance, settings.SettleSeconds)
+	}
+	if !service.isOpen {
+		return 0.0, 0.0, errors.New("TheSkyServiceInstance/WaitForTargetTemperature: Connection not open")
+	}
+	if settings.PollingIntervalSeconds < 1 {
+		return 0.0, 0.0, errors.New("TheSkyServiceInstance/WaitForTargetTemperature: polling interval must be at least 1 second")
+	}
+	timeoutSeconds := settings.TimeoutMinutes * 60
+	secondsElapsed := 0
+	stableSince := -1 // seconds elapsed when temperature came within tolerance, or -1 if it isn't
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0.0, 0.0, err
+		}
+		temperature, err := service.driverFor(ctx).GetCameraTemperature()
+		if err != nil {
+			fmt.Println("TheSkyServiceInstance/WaitForTargetTemperature error from GetCameraTemperature:", err)
+			return 0.0, 0.0, err
+		}
+		coolerPower, err := service.driverFor(ctx).GetCoolerPower()
+		if err != nil {
+			fmt.Println("TheSkyServiceInstance/WaitForTargetTemperature error from GetCoolerPower:", err)
+			return temperature, 0.0, err
+		}
+
+		secondsStable := 0
+		if math.Abs(temperature-settings.TargetTemperature) <= settings.Tolerance {
+			if stableSince < 0 {
+				stableSince = secondsElapsed
+			}
+			secondsStable = secondsElapsed - stableSince
+		} else {
+			stableSince = -1
+		}
+		if service.verbosity >= 4 {
+			fmt.Printf("  Temperature %g, cooler %g%%, stable for %d seconds\n", temperature, coolerPower, secondsStable)
+		}
+		if settings.Progress != nil {
+			settings.Progress(CoolingProgress{
+				Temperature:    temperature,
+				CoolerPower:    coolerPower,
+				SecondsElapsed: secondsElapsed,
+				SecondsStable:  secondsStable,
+			})
+		}
+
+		if stableSince >= 0 && secondsStable >= settings.SettleSeconds {
+			return temperature, coolerPower, nil
+		}
+		if secondsElapsed >= timeoutSeconds {
+			return temperature, coolerPower, coolingTimeoutError(settings, temperature, coolerPower)
+		}
+		if err := service.delayContext(ctx, settings.PollingIntervalSeconds); err != nil {
+			return temperature, coolerPower, err
+		}
+		secondsElapsed += settings.PollingIntervalSeconds
+	}
+}
+
+// GetCoolerStatus returns the camera's temperature, set point, regulation state and cooler power
+func (service *TheSkyServiceInstance) GetCoolerStatus() (CoolerStatus, error) {
+	return service.GetCoolerStatusContext(context.Background())
+}
+
+func (service *TheSkyServiceInstance) GetCoolerStatusContext(ctx context.Context) (CoolerStatus, error) {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Println("TheSkyServiceInstance/GetCoolerStatus()")
+	}
+	if !service.isOpen {
+		return CoolerStatus{}, errors.New("TheSkyServiceInstance/GetCoolerStatus: Connection not open")
+	}
+	if err := ctx.Err(); err != nil {
+		return CoolerStatus{}, err
+	}
+	status, err := service.driverFor(ctx).GetCoolerStatus()
+	if err != nil {
+		fmt.Println("TheSkyServiceInstance/GetCoolerStatus error from driver:", err)
+		return CoolerStatus{}, err
+	}
+	return status, nil
+}
+
+// WarmUpSettings describes how WarmUpAndStopCooling should ramp the set point up
+type WarmUpSettings struct {
+	FinalTemperature    float64 // Raise the set point to this (roughly ambient) before switching off
+	StepDegrees         float64 // How much to raise the set point at each step
+	StepIntervalSeconds int     // How long to wait at each step
+	Progress            func(progress WarmUpProgress)
+}
+
+// WarmUpProgress is reported to the progress callback after each step of the warm-up
+type WarmUpProgress struct {
+	SetPoint    float64
+	Temperature float64
+	Step        int // Starting at 1
+	TotalSteps  int
+}
+
+// WarmUpAndStopCooling warms the camera gradually before switching off its cooler, to avoid
+// thermally shocking the sensor.  Starting from the current set point (or the current
+// temperature, if that is warmer) it raises the set point StepDegrees at a time, waiting
+// StepIntervalSeconds at each step, until it reaches FinalTemperature; then it turns
+// regulation off.  If the context is cancelled part-way, regulation is left on at the
+// current set point - we don't want a cancelled warm-up to do the very thing it's avoiding.
+func (service *TheSkyServiceInstance) WarmUpAndStopCooling(settings WarmUpSettings) error {
+	return service.WarmUpAndStopCoolingContext(context.Background(), settings)
+}
+
+func (service *TheSkyServiceInstance) WarmUpAndStopCoolingContext(ctx context.Context, settings WarmUpSettings) error {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Printf("TheSkyServiceInstance/WarmUpAndStopCooling(to %g by %g every %d seconds)\n",
+			settings.FinalTemperature, settings.StepDegrees, settings.StepIntervalSeconds)
+	}
+	if !service.isOpen {
+		return errors.New("TheSkyServiceInstance/WarmUpAndStopCooling: Connection not open")
+	}
+	if settings.StepDegrees <= 0 {
+		return errors.New("TheSkyServiceInstance/WarmUpAndStopCooling: step must be greater than 0 degrees")
+	}
+	if settings.StepIntervalSeconds < 1 {
+		return errors.New("TheSkyServiceInstance/WarmUpAndStopCooling: step interval must be at least 1 second")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	status, err := service.driverFor(ctx).GetCoolerStatus()
+	if err != nil {
+		fmt.Println("TheSkyServiceInstance/WarmUpAndStopCooling error from GetCoolerStatus:", err)
+		return err
+	}
+
+	if status.Regulating {
+		setPoint := math.Max(status.SetPoint, status.Temperature)
+		totalSteps := int(math.Ceil((settings.FinalTemperature - setPoint) / settings.StepDegrees))
+		for step := 1; step <= totalSteps; step++ {
+			setPoint = math.Min(setPoint+settings.StepDegrees, settings.FinalTemperature)
+			if err := service.driverFor(ctx).SetCoolerSetPoint(setPoint); err != nil {
+				fmt.Println("TheSkyServiceInstance/WarmUpAndStopCooling error from SetCoolerSetPoint:", err)
+				return err
+			}
+			if err := service.delayContext(ctx, settings.StepIntervalSeconds); err != nil {
+				return err
+			}
+			if settings.Progress != nil {
+				temperature, err := service.driverFor(ctx).GetCameraTemperature()
+				if err != nil {
+					fmt.Println("TheSkyServiceInstance/WarmUpAndStopCooling error from GetCameraTemperature:", err)
+					return err
+				}
+				settings.Progress(WarmUpProgress{
+					SetPoint:    setPoint,
+					Temperature: temperature,
+					Step:        step,
+					TotalSteps:  totalSteps,
+				})
+			}
+		}
+	}
+
+	if err := service.driverFor(ctx).StopCooling(); err != nil {
+		fmt.Println("TheSkyServiceInstance/WarmUpAndStopCooling error from StopCooling:", err)
+		return err
+	}
+	return nil
+}
+
+// coolingTimeoutError describes why cooling failed, as best we can tell
+func coolingTimeoutError(settings CoolingWaitSettings, temperature float64, coolerPower float64) error {
+	reason := "temperature has not stabilized"
+	if coolerPower >= saturatedCoolerPower && temperature > settings.TargetTemperature+settings.Tolerance {
+		reason = "cooler is at full power; ambient temperature may be too warm for this target"
+	}
+	return fmt.Errorf("%w: %.2f after %d minutes (target %.2f +/- %.2f, cooler power %.0f%%): %s",
+		ErrCoolingTimeout, temperature, settings.TimeoutMinutes, settings.TargetTemperature,
+		settings.Tolerance, coolerPower, reason)
+}
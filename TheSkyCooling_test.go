@@ -0,0 +1,274 @@
+package goTheSkyX
+
+import (
+	"context"
+	"github.com/RMcDOttawa/goMockableDelay"
+	"github.com/RMcDOttawa/goTheSkyX/fakeTheSkyX"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// setUpConnectedMockService creates a service with mock driver and delay service, and connects it
+func setUpConnectedMockService(ctrl *gomock.Controller) (TheSkyService, *MockTheSkyDriver, *goMockableDelay.MockDelayService) {
+	mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
+	service := NewTheSkyService(mockDelayService, false, 0, true)
+	// Plug mock driver into service
+	mockDriver := NewMockTheSkyDriver(ctrl)
+	service.SetDriver(mockDriver)
+	mockDriver.EXPECT().Connect("localhost", 3040).Return(nil)
+	mockDriver.EXPECT().ConnectCamera().Return(nil)
+	_ = service.Connect("localhost", 3040)
+	return service, mockDriver, mockDelayService
+}
+
+func TestWaitForTargetTemperature(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Temperature comes down to target, wobbles within tolerance, and is accepted once it
+	// has been within tolerance for the settle time
+	t.Run("temperature settles at target", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+
+		gomock.InOrder(
+			mockDriver.EXPECT().GetCameraTemperature().Return(-5.0, nil),
+			mockDriver.EXPECT().GetCameraTemperature().Return(-9.8, nil),
+			mockDriver.EXPECT().GetCameraTemperature().Return(-10.1, nil),
+			mockDriver.EXPECT().GetCameraTemperature().Return(-9.9, nil),
+		)
+		mockDriver.EXPECT().GetCoolerPower().Return(60.0, nil).Times(4)
+		mockDelayService.EXPECT().DelayDuration(2).Return(2, nil).Times(3)
+
+		var progressReports []CoolingProgress
+		temperature, power, err := service.WaitForTargetTemperature(CoolingWaitSettings{
+			TargetTemperature:      -10.0,
+			Tolerance:              0.5,
+			SettleSeconds:          4,
+			PollingIntervalSeconds: 2,
+			TimeoutMinutes:         10,
+			Progress:               func(progress CoolingProgress) { progressReports = append(progressReports, progress) },
+		})
+		require.Nil(t, err, "WaitForTargetTemperature failed")
+		require.Equal(t, -9.9, temperature)
+		require.Equal(t, 60.0, power)
+		require.Len(t, progressReports, 4)
+		require.Equal(t, 4, progressReports[3].SecondsStable)
+		require.Equal(t, 6, progressReports[3].SecondsElapsed)
+	})
+
+	// Temperature drifting out of tolerance restarts the settle period
+	t.Run("settle period restarts after excursion", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+
+		gomock.InOrder(
+			mockDriver.EXPECT().GetCameraTemperature().Return(-10.0, nil),
+			mockDriver.EXPECT().GetCameraTemperature().Return(-8.0, nil),
+			mockDriver.EXPECT().GetCameraTemperature().Return(-10.0, nil),
+			mockDriver.EXPECT().GetCameraTemperature().Return(-10.0, nil),
+		)
+		mockDriver.EXPECT().GetCoolerPower().Return(60.0, nil).Times(4)
+		mockDelayService.EXPECT().DelayDuration(5).Return(5, nil).Times(3)
+
+		_, _, err := service.WaitForTargetTemperature(CoolingWaitSettings{
+			TargetTemperature:      -10.0,
+			Tolerance:              0.5,
+			SettleSeconds:          5,
+			PollingIntervalSeconds: 5,
+			TimeoutMinutes:         10,
+		})
+		require.Nil(t, err, "WaitForTargetTemperature failed")
+	})
+
+	// On a warm night the cooler runs flat out without reaching target, and we time out
+	t.Run("times out with saturated cooler", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+
+		mockDriver.EXPECT().GetCameraTemperature().Return(-2.0, nil).Times(3)
+		mockDriver.EXPECT().GetCoolerPower().Return(100.0, nil).Times(3)
+		mockDelayService.EXPECT().DelayDuration(30).Return(30, nil).Times(2)
+
+		temperature, power, err := service.WaitForTargetTemperature(CoolingWaitSettings{
+			TargetTemperature:      -20.0,
+			Tolerance:              0.5,
+			SettleSeconds:          60,
+			PollingIntervalSeconds: 30,
+			TimeoutMinutes:         1,
+		})
+		require.ErrorIs(t, err, ErrCoolingTimeout)
+		require.ErrorContains(t, err, "full power")
+		require.Equal(t, -2.0, temperature)
+		require.Equal(t, 100.0, power)
+	})
+
+	// Against the fake server, with real delays, the simulated camera cools to target
+	t.Run("fake camera cools to target", func(t *testing.T) {
+		fake := fakeTheSkyX.NewFakeTheSkyServer(false, 0)
+		require.Nil(t, fake.Start(), "Unable to start fake server")
+		defer fake.Close()
+		service := NewTheSkyService(goMockableDelay.NewDelayService(false, 0), false, 0, true)
+		require.Nil(t, service.Connect("localhost", fake.Port()))
+		defer service.Close()
+
+		require.Nil(t, service.StartCooling(-10.0))
+		temperature, power, err := service.WaitForTargetTemperature(CoolingWaitSettings{
+			TargetTemperature:      -10.0,
+			Tolerance:              0.5,
+			SettleSeconds:          1,
+			PollingIntervalSeconds: 1,
+			TimeoutMinutes:         1,
+		})
+		require.Nil(t, err, "Fake camera did not reach target")
+		require.InDelta(t, -10.0, temperature, 0.5)
+		require.Greater(t, power, 0.0)
+		require.Less(t, power, 100.0)
+	})
+}
+
+func TestCoolerStatus(t *testing.T) {
+
+	t.Run("status before cooling starts", func(t *testing.T) {
+		fake, driver := startFakeForDriver(t)
+		defer fake.Close()
+		defer driver.Close()
+
+		status, err := driver.GetCoolerStatus()
+		require.Nil(t, err, "Unable to get cooler status")
+		require.False(t, status.Regulating)
+		require.Equal(t, 20.0, status.Temperature)
+		require.False(t, status.Saturated())
+	})
+
+	t.Run("saturated cooler on a warm night", func(t *testing.T) {
+		fake := fakeTheSkyX.NewFakeTheSkyServer(false, 0)
+		fake.SetAmbientTemperature(30.0)
+		fake.SetMaxCoolingDelta(25.0)
+		require.Nil(t, fake.Start(), "Unable to start fake server")
+		defer fake.Close()
+		driver := NewTheSkyDriver(false, 0)
+		require.Nil(t, driver.Connect("localhost", fake.Port()))
+		defer driver.Close()
+		require.Nil(t, driver.ConnectCamera())
+
+		require.Nil(t, driver.StartCooling(-10.0))
+		status, err := driver.GetCoolerStatus()
+		require.Nil(t, err, "Unable to get cooler status")
+		require.True(t, status.Regulating)
+		require.Equal(t, -10.0, status.SetPoint)
+		require.Equal(t, 100.0, status.CoolerPowerPercent)
+		require.True(t, status.Saturated())
+	})
+
+	t.Run("parse numeric regulation flag", func(t *testing.T) {
+		status, err := parseCoolerStatus("-9.5\t-10\t1\t62.5")
+		require.Nil(t, err)
+		require.Equal(t, CoolerStatus{Temperature: -9.5, SetPoint: -10.0, Regulating: true, CoolerPowerPercent: 62.5}, status)
+	})
+
+	t.Run("parse malformed status", func(t *testing.T) {
+		var malformed *MalformedReplyError
+		_, err := parseCoolerStatus("-9.5\t-10\t62.5")
+		require.ErrorAs(t, err, &malformed)
+		_, err = parseCoolerStatus("-9.5\t-10\tmaybe\t62.5")
+		require.ErrorAs(t, err, &malformed)
+	})
+
+	t.Run("service passes status through", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		service, mockDriver, _ := setUpConnectedMockService(ctrl)
+
+		expected := CoolerStatus{Temperature: -2.0, SetPoint: -20.0, Regulating: true, CoolerPowerPercent: 100.0}
+		mockDriver.EXPECT().GetCoolerStatus().Return(expected, nil)
+		status, err := service.GetCoolerStatus()
+		require.Nil(t, err)
+		require.Equal(t, expected, status)
+		require.True(t, status.Saturated())
+	})
+}
+
+func TestWarmUpAndStopCooling(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("ramps set point up in steps then stops cooling", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+
+		mockDriver.EXPECT().GetCoolerStatus().Return(CoolerStatus{Temperature: -20.0, SetPoint: -20.0, Regulating: true, CoolerPowerPercent: 50.0}, nil)
+		gomock.InOrder(
+			mockDriver.EXPECT().SetCoolerSetPoint(-15.0).Return(nil),
+			mockDriver.EXPECT().SetCoolerSetPoint(-10.0).Return(nil),
+			mockDriver.EXPECT().SetCoolerSetPoint(-5.0).Return(nil),
+			mockDriver.EXPECT().SetCoolerSetPoint(0.0).Return(nil),
+			mockDriver.EXPECT().StopCooling().Return(nil),
+		)
+		mockDriver.EXPECT().GetCameraTemperature().Return(-12.0, nil).Times(4)
+		mockDelayService.EXPECT().DelayDuration(60).Return(60, nil).Times(4)
+
+		var progressReports []WarmUpProgress
+		err := service.WarmUpAndStopCooling(WarmUpSettings{
+			FinalTemperature:    0.0,
+			StepDegrees:         5.0,
+			StepIntervalSeconds: 60,
+			Progress:            func(progress WarmUpProgress) { progressReports = append(progressReports, progress) },
+		})
+		require.Nil(t, err, "WarmUpAndStopCooling failed")
+		require.Len(t, progressReports, 4)
+		require.Equal(t, WarmUpProgress{SetPoint: 0.0, Temperature: -12.0, Step: 4, TotalSteps: 4}, progressReports[3])
+	})
+
+	t.Run("last step is shortened to land on final temperature", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+
+		mockDriver.EXPECT().GetCoolerStatus().Return(CoolerStatus{Temperature: -7.0, SetPoint: -7.0, Regulating: true, CoolerPowerPercent: 50.0}, nil)
+		gomock.InOrder(
+			mockDriver.EXPECT().SetCoolerSetPoint(-2.0).Return(nil),
+			mockDriver.EXPECT().SetCoolerSetPoint(0.0).Return(nil),
+			mockDriver.EXPECT().StopCooling().Return(nil),
+		)
+		mockDelayService.EXPECT().DelayDuration(10).Return(10, nil).Times(2)
+
+		err := service.WarmUpAndStopCooling(WarmUpSettings{FinalTemperature: 0.0, StepDegrees: 5.0, StepIntervalSeconds: 10})
+		require.Nil(t, err, "WarmUpAndStopCooling failed")
+	})
+
+	t.Run("cooler already off just stops", func(t *testing.T) {
+		service, mockDriver, _ := setUpConnectedMockService(ctrl)
+
+		mockDriver.EXPECT().GetCoolerStatus().Return(CoolerStatus{Temperature: 15.0, SetPoint: -10.0, Regulating: false}, nil)
+		mockDriver.EXPECT().StopCooling().Return(nil)
+
+		err := service.WarmUpAndStopCooling(WarmUpSettings{FinalTemperature: 10.0, StepDegrees: 5.0, StepIntervalSeconds: 10})
+		require.Nil(t, err, "WarmUpAndStopCooling failed")
+	})
+
+	t.Run("cancelled warm-up leaves regulation on", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mockDriver.EXPECT().GetCoolerStatus().Return(CoolerStatus{Temperature: -20.0, SetPoint: -20.0, Regulating: true, CoolerPowerPercent: 50.0}, nil)
+		mockDriver.EXPECT().SetCoolerSetPoint(-15.0).Return(nil)
+		mockDriver.EXPECT().SetCoolerSetPoint(-10.0).Return(nil)
+		mockDriver.EXPECT().GetCameraTemperature().Return(-16.0, nil)
+		mockDelayService.EXPECT().DelayDuration(60).Return(60, nil)
+		// No StopCooling expected
+
+		err := service.WarmUpAndStopCoolingContext(ctx, WarmUpSettings{
+			FinalTemperature:    0.0,
+			StepDegrees:         5.0,
+			StepIntervalSeconds: 60,
+			Progress:            func(progress WarmUpProgress) { cancel() },
+		})
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("invalid step settings", func(t *testing.T) {
+		service, _, _ := setUpConnectedMockService(ctrl)
+
+		err := service.WarmUpAndStopCooling(WarmUpSettings{FinalTemperature: 0.0, StepDegrees: 0.0, StepIntervalSeconds: 10})
+		require.NotNil(t, err, "Expected error for zero step")
+		err = service.WarmUpAndStopCooling(WarmUpSettings{FinalTemperature: 0.0, StepDegrees: 5.0, StepIntervalSeconds: 0})
+		require.NotNil(t, err, "Expected error for zero interval")
+	})
+}
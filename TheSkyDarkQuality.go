@@ -0,0 +1,149 @@
+package goTheSkyX
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+//	Quality gate for dark frames.  A dark frame that has caught light (a leak round the
+//	camera's nosepiece, a lit room) or a burst of cosmic rays looks like any other until it
+//	spoils a master dark.  With SetDarkQualityCheck, each dark frame is measured with
+//	ImageStats as soon as it is captured and compared with what this camera's darks should
+//	look like:
+//
+//		mean        within MeanToleranceADU of BiasLevelADU + DarkCurrentADU * exposure
+//		spread      standard deviation at most MaxStdDev
+//		hot pixels  fraction of pixels more than HotPixelADU above the median at most
+//		            MaxHotPixelFraction
+//
+//	A zero threshold skips that test.  A frame that fails is taken again, up to Reshoots
+//	times; if the last attempt fails too the capture returns a DarkQualityError.  Frames are
+//	only saved once they have passed: while checking, TheSkyX's AutoSave is turned off, and a
+//	frame that passes is saved by the service, with SetSaveSettings or, if there are none, to
+//	the AutoSave directory with the default file name template.
+//
+//	The thresholds depend on the camera, its temperature and the binning; take a few darks you
+//	know are good and measure them with ImageStats to choose them.  Bias frames taken as
+//	minimum-length darks (see CameraCapabilities) are checked too.
+
+// DarkQualityCheck sets what a usable dark frame from this camera looks like
+type DarkQualityCheck struct {
+	BiasLevelADU        float64 // Mean of a bias frame
+	DarkCurrentADU      float64 // Mean added per second of exposure, at the cooling temperature
+	MeanToleranceADU    float64 // Furthest the mean may be from the expected mean; 0 not to check
+	MaxStdDev           float64 // 0 not to check
+	HotPixelADU         int64   // A pixel more than this above the median is hot
+	MaxHotPixelFraction float64 // Of the pixels measured that may be hot; 0 not to check
+	CentralFraction     float64 // Of the width and height measured; 0 for the whole frame
+	Reshoots            int     // Times a rejected frame is taken again before giving up
+}
+
+// DarkQualityError is returned when a dark frame, and any re-shoots, fail the quality check
+type DarkQualityError struct {
+	Stats        ImageStats // Of the last frame taken
+	ExpectedMean float64
+	Problems     []string
+	Attempts     int
+}
+
+// ErrDarkFrameRejected matches any DarkQualityError with errors.Is
+var ErrDarkFrameRejected = &DarkQualityError{}
+
+func (e *DarkQualityError) Error() string {
+	return fmt.Sprintf("dark frame rejected after %d attempts: %s", e.Attempts, strings.Join(e.Problems, "; "))
+}
+
+// Is makes errors.Is match any DarkQualityError, whatever the measurements
+func (e *DarkQualityError) Is(target error) bool {
+	_, ok := target.(*DarkQualityError)
+	return ok
+}
+
+func (check DarkQualityCheck) isZero() bool {
+	return check == DarkQualityCheck{}
+}
+
+func (check DarkQualityCheck) validate() error {
+	if check.BiasLevelADU < 0 || check.DarkCurrentADU < 0 || check.MeanToleranceADU < 0 || check.MaxStdDev < 0 {
+		return errors.New("levels and thresholds must not be negative")
+	}
+	if check.MaxHotPixelFraction < 0 || check.MaxHotPixelFraction > 1 {
+		return fmt.Errorf("hot pixel fraction %g is not between 0 and 1", check.MaxHotPixelFraction)
+	}
+	if check.HotPixelADU < 0 {
+		return fmt.Errorf("hot pixel level %d must not be negative", check.HotPixelADU)
+	}
+	if check.MaxHotPixelFraction > 0 && check.HotPixelADU == 0 {
+		return errors.New("checking the hot pixel fraction needs a hot pixel level (HotPixelADU)")
+	}
+	if check.CentralFraction < 0 || check.CentralFraction > 1 {
+		return fmt.Errorf("central fraction %g is not between 0 and 1", check.CentralFraction)
+	}
+	if check.Reshoots < 0 {
+		return fmt.Errorf("reshoots %d must not be negative", check.Reshoots)
+	}
+	return nil
+}
+
+// SetDarkQualityCheck turns on checking of each dark frame as it is captured.  Passing the
+// zero value turns it off.
+func (service *TheSkyServiceInstance) SetDarkQualityCheck(check DarkQualityCheck) error {
+	if err := check.validate(); err != nil {
+		return fmt.Errorf("TheSkyServiceInstance/SetDarkQualityCheck: %w", err)
+	}
+	if check.isZero() {
+		service.darkQualityCheck = nil
+		return nil
+	}
+	service.darkQualityCheck = &check
+	return nil
+}
+
+// darkFrameProblems measures the dark frame just captured and returns what is wrong with it,
+// or nil if it passes (or there is no quality check)
+func (service *TheSkyServiceInstance) darkFrameProblems(ctx context.Context, exposure float64, attempt int) (*DarkQualityError, error) {
+	check := service.darkQualityCheck
+	if check == nil {
+		return nil, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	centralFraction := check.CentralFraction
+	if centralFraction == 0.0 {
+		centralFraction = 1.0
+	}
+	hotPixelADU := int64(0)
+	if check.MaxHotPixelFraction > 0 {
+		hotPixelADU = check.HotPixelADU
+	}
+	stats, err := service.driverFor(ctx).GetImageStats(centralFraction, hotPixelADU)
+	if err != nil {
+		fmt.Println("TheSkyServiceInstance error from driver measuring dark frame:", err)
+		return nil, err
+	}
+	expectedMean := check.BiasLevelADU + check.DarkCurrentADU*exposure
+	var problems []string
+	if check.MeanToleranceADU > 0 && math.Abs(stats.Mean-expectedMean) > check.MeanToleranceADU {
+		problems = append(problems, fmt.Sprintf("mean %.1f ADU, expected %.1f ± %g (light leak?)",
+			stats.Mean, expectedMean, check.MeanToleranceADU))
+	}
+	if check.MaxStdDev > 0 && stats.StdDev > check.MaxStdDev {
+		problems = append(problems, fmt.Sprintf("standard deviation %.1f ADU, limit %g", stats.StdDev, check.MaxStdDev))
+	}
+	if check.MaxHotPixelFraction > 0 && stats.HotPixelFraction() > check.MaxHotPixelFraction {
+		problems = append(problems, fmt.Sprintf("%d hot pixels over %d ADU above the median, %.4f%% of frame, limit %.4f%% (cosmic rays?)",
+			stats.HotPixels, check.HotPixelADU, stats.HotPixelFraction()*100.0, check.MaxHotPixelFraction*100.0))
+	}
+	if service.verbosity >= 4 || service.debug {
+		fmt.Printf("TheSkyServiceInstance: dark frame mean %.1f, std dev %.1f, %d hot, problems %v\n",
+			stats.Mean, stats.StdDev, stats.HotPixels, problems)
+	}
+	if len(problems) == 0 {
+		return nil, nil
+	}
+	return &DarkQualityError{Stats: stats, ExpectedMean: expectedMean, Problems: problems, Attempts: attempt}, nil
+}
@@ -0,0 +1,153 @@
+package goTheSkyX
+
+import (
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDarkQualityCheck(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The fake camera's darks: bias 1000 ADU plus 2 ADU per second
+	fakeCameraCheck := DarkQualityCheck{
+		BiasLevelADU:        1000.0,
+		DarkCurrentADU:      2.0,
+		MeanToleranceADU:    20.0,
+		MaxStdDev:           100.0,
+		HotPixelADU:         1000,
+		MaxHotPixelFraction: 0.001,
+	}
+	goodStats := ImageStats{CentralFraction: 1.0, PixelCount: 1000, Mean: 1010.0, Median: 1010, StdDev: 15.0, Min: 950, Max: 1100}
+
+	t.Run("good dark is saved", func(t *testing.T) {
+		fake, service := startFakeForSaving(t, ctrl)
+		defer fake.Close()
+		defer service.Close()
+		fake.SetSensor(64, 48, 3.8)
+		require.Nil(t, service.SetSaveSettings(SaveSettings{Directory: "/darks"}))
+		require.Nil(t, service.SetDarkQualityCheck(fakeCameraCheck))
+
+		require.Nil(t, service.CaptureDarkFrame(1, 0.5, 0.0))
+		require.Len(t, fake.SavedImages(), 1)
+	})
+
+	t.Run("light leak rejected after re-shoots and not saved", func(t *testing.T) {
+		fake, service := startFakeForSaving(t, ctrl)
+		defer fake.Close()
+		defer service.Close()
+		fake.SetSensor(64, 48, 3.8)
+		fake.SetHotSpot(0.3, 500.0)
+		require.Nil(t, service.SetSaveSettings(SaveSettings{Directory: "/darks"}))
+		check := fakeCameraCheck
+		check.Reshoots = 2
+		require.Nil(t, service.SetDarkQualityCheck(check))
+
+		err := service.CaptureDarkFrame(1, 0.5, 0.0)
+		require.ErrorIs(t, err, ErrDarkFrameRejected)
+		var rejection *DarkQualityError
+		require.ErrorAs(t, err, &rejection)
+		require.Equal(t, 3, rejection.Attempts)
+		require.Equal(t, 1001.0, rejection.ExpectedMean)
+		require.Greater(t, rejection.Stats.Mean, 1021.0)
+		require.Contains(t, rejection.Problems[0], "light leak")
+		require.Empty(t, fake.SavedImages(), "Rejected frames should not be saved")
+	})
+
+	t.Run("hot pixels well below saturation are caught", func(t *testing.T) {
+		fake, service := startFakeForSaving(t, ctrl)
+		defer fake.Close()
+		defer service.Close()
+		fake.SetSensor(64, 48, 3.8)
+		// A small patch of pixels 3000 ADU above the rest, far from saturating
+		fake.SetHotSpot(0.05, 3000.0)
+		check := fakeCameraCheck
+		check.MeanToleranceADU = 0
+		check.MaxStdDev = 0
+		require.Nil(t, service.SetDarkQualityCheck(check))
+
+		err := service.CaptureDarkFrame(1, 0.5, 0.0)
+		var rejection *DarkQualityError
+		require.ErrorAs(t, err, &rejection)
+		require.Greater(t, rejection.Stats.HotPixels, 0)
+		require.Equal(t, 0, rejection.Stats.SaturatedPixels, "Hot pixels should be counted though not saturated")
+		require.Contains(t, rejection.Problems[0], "hot pixels")
+	})
+
+	t.Run("AutoSave doesn't keep rejected frames", func(t *testing.T) {
+		fake, service := startFakeForSaving(t, ctrl)
+		defer fake.Close()
+		defer service.Close()
+		fake.SetSensor(64, 48, 3.8)
+		require.Nil(t, service.SetDarkQualityCheck(fakeCameraCheck))
+
+		require.Nil(t, service.CaptureDarkFrame(1, 0.5, 0.0))
+		fake.SetHotSpot(0.3, 500.0)
+		require.ErrorIs(t, service.CaptureDarkFrame(1, 0.5, 0.0), ErrDarkFrameRejected)
+		require.Equal(t, 0, fake.SavedImageCount(), "AutoSave should be off while checking")
+		saved := fake.SavedImages()
+		require.Len(t, saved, 1, "Only the good frame should be saved")
+		require.Contains(t, saved[0].Path, "Camera AutoSave/dark_0.5s_1x1_0001.fit")
+	})
+
+	t.Run("re-shoot replaces a frame hit by cosmic rays", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		check := fakeCameraCheck
+		check.Reshoots = 1
+		check.CentralFraction = 0.5
+		require.Nil(t, service.SetDarkQualityCheck(check))
+		cosmicRays := goodStats
+		cosmicRays.HotPixels = 50
+		cosmicRays.Max = 30000
+
+		mockDriver.EXPECT().StartDarkFrameCapture(1, 5.0, 0.0).Return(nil).Times(2)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil).Times(2)
+		mockDriver.EXPECT().IsCaptureDone().Return(true, nil).Times(2)
+		gomock.InOrder(
+			mockDriver.EXPECT().SetAutoSave(false),
+			mockDriver.EXPECT().GetImageStats(0.5, int64(1000)).Return(cosmicRays, nil),
+			mockDriver.EXPECT().GetImageStats(0.5, int64(1000)).Return(goodStats, nil),
+			mockDriver.EXPECT().SaveImage("", "dark_5s_1x1_0001.fit", nil).Return("/autosave/dark_5s_1x1_0001.fit", nil),
+			mockDriver.EXPECT().SetAutoSave(true),
+		)
+		require.Nil(t, service.CaptureDarkFrame(1, 5.0, 0.0))
+	})
+
+	t.Run("noisy frame flagged without re-shoot", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		require.Nil(t, service.SetDarkQualityCheck(fakeCameraCheck))
+		noisy := goodStats
+		noisy.StdDev = 250.0
+
+		mockDriver.EXPECT().SetAutoSave(false)
+		mockDriver.EXPECT().StartDarkFrameCapture(1, 5.0, 0.0).Return(nil)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil)
+		mockDriver.EXPECT().IsCaptureDone().Return(true, nil)
+		mockDriver.EXPECT().GetImageStats(1.0, int64(1000)).Return(noisy, nil)
+		mockDriver.EXPECT().SetAutoSave(true)
+		err := service.CaptureDarkFrame(1, 5.0, 0.0)
+		require.ErrorIs(t, err, ErrDarkFrameRejected)
+		require.Contains(t, err.Error(), "standard deviation 250.0")
+	})
+
+	t.Run("zero check turns checking off", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		require.Nil(t, service.SetDarkQualityCheck(fakeCameraCheck))
+		require.Nil(t, service.SetDarkQualityCheck(DarkQualityCheck{}))
+
+		mockDriver.EXPECT().StartDarkFrameCapture(1, 5.0, 0.0).Return(nil)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil)
+		mockDriver.EXPECT().IsCaptureDone().Return(true, nil)
+		require.Nil(t, service.CaptureDarkFrame(1, 5.0, 0.0), "No statistics should be asked for")
+	})
+
+	t.Run("invalid checks are refused", func(t *testing.T) {
+		service := NewTheSkyService(nil, false, 0, false)
+		require.NotNil(t, service.SetDarkQualityCheck(DarkQualityCheck{MeanToleranceADU: -1}))
+		require.NotNil(t, service.SetDarkQualityCheck(DarkQualityCheck{MaxHotPixelFraction: 2, HotPixelADU: 1000}))
+		require.NotNil(t, service.SetDarkQualityCheck(DarkQualityCheck{MaxHotPixelFraction: 0.01}), "Fraction needs a hot pixel level")
+		require.NotNil(t, service.SetDarkQualityCheck(DarkQualityCheck{CentralFraction: 1.5}))
+		require.NotNil(t, service.SetDarkQualityCheck(DarkQualityCheck{Reshoots: -1}))
+	})
+}
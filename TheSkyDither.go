@@ -0,0 +1,168 @@
+package goTheSkyX
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+)
+
+//	Dithering between flat frames.  Moving the mount a little between flats means dust on the
+//	panel, or a star in a twilight sky, lands on different pixels in each frame and averages
+//	out when the flats are stacked.  Each flat is offset by a random amount, within a circle
+//	around the position the run started at, by jogging the mount north/south and east/west.
+//	At the end of the run the mount slews back to where it started.
+//	The starting position is recorded as altitude and azimuth, which only stays put if the mount
+//	isn't tracking.  So if it is tracking, tracking is switched off for the run and back on at
+//	the end, once the mount has returned to the starting altitude and azimuth.  (The sky will
+//	have moved on in the meantime; that's fine for flats.)
+
+// FlatDither describes how to dither between flat frames.  The zero value means no dithering.
+type FlatDither struct {
+	RadiusArcminutes float64 // Largest offset from the starting position; 0 for no dithering
+	SettleSeconds    int     // Wait after each move before taking the next flat
+	TimeoutMinutes   int     // Give up if a move takes longer than this; 0 for the default
+	Seed             uint64  // Offsets are random, but the same seed gives the same offsets
+}
+
+// ErrDitherFailed is returned (wrapped) when the mount can't be moved to dither
+var ErrDitherFailed = errors.New("unable to dither mount")
+
+const defaultDitherTimeoutMinutes = 2
+
+// ditherPollingSeconds is how often we check whether a dither move has finished
+const ditherPollingSeconds = 1
+
+// minimumJogArcminutes is the smallest move worth asking the mount to make
+const minimumJogArcminutes = 0.001
+
+// flatDitherer keeps track of a dithered run: where it started, how far the mount is offset
+// from there, and whether the next flat is the first (which is taken without moving)
+type flatDitherer struct {
+	settings     FlatDither
+	rng          *rand.Rand
+	start        TelescopePosition // Altitude and azimuth; start.Tracking is whether to restore tracking
+	offsetNorth  float64 // Arcminutes
+	offsetEast   float64 // Arcminutes
+	flatsStarted int
+}
+
+func (settings FlatDither) enabled() bool {
+	return settings.RadiusArcminutes > 0
+}
+
+func (settings FlatDither) validate() error {
+	if settings.RadiusArcminutes < 0 {
+		return fmt.Errorf("dither radius %g must not be negative", settings.RadiusArcminutes)
+	}
+	if settings.SettleSeconds < 0 {
+		return fmt.Errorf("dither settle time %d must not be negative", settings.SettleSeconds)
+	}
+	return nil
+}
+
+// randomDitherOffset picks a point uniformly distributed within a circle of the given radius,
+// returning its north and east components
+func randomDitherOffset(rng *rand.Rand, radius float64) (float64, float64) {
+	distance := radius * math.Sqrt(rng.Float64())
+	angle := 2.0 * math.Pi * rng.Float64()
+	return distance * math.Cos(angle), distance * math.Sin(angle)
+}
+
+// startDithering records the mount's position at the start of a dithered run, and stops it
+// tracking so that position doesn't move
+func (service *TheSkyServiceInstance) startDithering(ctx context.Context, settings FlatDither) (*flatDitherer, error) {
+	start, err := service.GetTelescopePositionContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading starting position: %w", ErrDitherFailed, err)
+	}
+	if start.Parked {
+		return nil, fmt.Errorf("%w: mount is parked", ErrDitherFailed)
+	}
+	if start.Tracking {
+		if err := service.SetTrackingContext(ctx, false); err != nil {
+			return nil, fmt.Errorf("%w: stopping tracking: %w", ErrDitherFailed, err)
+		}
+	}
+	return &flatDitherer{
+		settings: settings,
+		rng:      rand.New(rand.NewPCG(settings.Seed, settings.Seed)),
+		start:    start,
+	}, nil
+}
+
+// ditherBeforeFlat moves the mount to a new random offset before each flat except the first,
+// then waits for it to settle.  The operation names the caller in log and error messages.
+func (service *TheSkyServiceInstance) ditherBeforeFlat(ctx context.Context, operation string, ditherer *flatDitherer) error {
+	ditherer.flatsStarted++
+	if ditherer.flatsStarted == 1 {
+		return nil
+	}
+	north, east := randomDitherOffset(ditherer.rng, ditherer.settings.RadiusArcminutes)
+	if service.verbosity >= 4 || service.debug {
+		fmt.Printf("TheSkyServiceInstance: dithering to %.2f' north, %.2f' east\n", north, east)
+	}
+	if err := service.jogMount(ctx, operation, north-ditherer.offsetNorth, JogNorth, JogSouth, ditherer.settings.TimeoutMinutes); err != nil {
+		return err
+	}
+	ditherer.offsetNorth = north
+	if err := service.jogMount(ctx, operation, east-ditherer.offsetEast, JogEast, JogWest, ditherer.settings.TimeoutMinutes); err != nil {
+		return err
+	}
+	ditherer.offsetEast = east
+	if ditherer.settings.SettleSeconds > 0 {
+		if err := service.delayContext(ctx, ditherer.settings.SettleSeconds); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jogMount jogs the mount the given number of arcminutes, in the positive direction if it is
+// positive and the negative direction if not, and waits for the move to finish
+func (service *TheSkyServiceInstance) jogMount(ctx context.Context, operation string, arcminutes float64, positive string, negative string, timeoutMinutes int) error {
+	direction := positive
+	if arcminutes < 0 {
+		direction = negative
+		arcminutes = -arcminutes
+	}
+	if arcminutes < minimumJogArcminutes {
+		return nil
+	}
+	if err := service.driverFor(ctx).StartJog(arcminutes, direction); err != nil {
+		fmt.Printf("TheSkyServiceInstance/%s error from driver jogging mount: %v\n", operation, err)
+		return fmt.Errorf("%w: %w", ErrDitherFailed, err)
+	}
+	err := service.waitForSlew(ctx, operation, ditherPollingSeconds, valueOrDefault(timeoutMinutes, defaultDitherTimeoutMinutes))
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("%w: %w", ErrDitherFailed, err)
+	}
+	return err
+}
+
+// finishDithering slews the mount back to the altitude and azimuth where the run started, then
+// turns tracking back on if it was on at the start
+func (service *TheSkyServiceInstance) finishDithering(ctx context.Context, ditherer *flatDitherer) error {
+	if ditherer.offsetNorth != 0 || ditherer.offsetEast != 0 {
+		if service.verbosity >= 4 || service.debug {
+			fmt.Println("TheSkyServiceInstance: dithering finished, returning to starting position")
+		}
+		err := service.SlewToAltAzContext(ctx, ditherer.start.Altitude, ditherer.start.Azimuth, ditherPollingSeconds,
+			valueOrDefault(ditherer.settings.TimeoutMinutes, defaultDitherTimeoutMinutes))
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("%w: returning to starting position: %w", ErrDitherFailed, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if ditherer.start.Tracking {
+		err := service.SetTrackingContext(ctx, true)
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("%w: restarting tracking: %w", ErrDitherFailed, err)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package goTheSkyX
+
+import (
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func TestDitherOffsets(t *testing.T) {
+
+	t.Run("offsets stay within radius", func(t *testing.T) {
+		rng := rand.New(rand.NewPCG(7, 7))
+		for i := 0; i < 1000; i++ {
+			north, east := randomDitherOffset(rng, 3.0)
+			require.LessOrEqual(t, math.Hypot(north, east), 3.0)
+		}
+	})
+
+	t.Run("same seed gives same offsets", func(t *testing.T) {
+		first := rand.New(rand.NewPCG(7, 7))
+		second := rand.New(rand.NewPCG(7, 7))
+		for i := 0; i < 10; i++ {
+			north1, east1 := randomDitherOffset(first, 3.0)
+			north2, east2 := randomDitherOffset(second, 3.0)
+			require.Equal(t, north1, north2)
+			require.Equal(t, east1, east2)
+		}
+	})
+
+	t.Run("jog moves the fake mount", func(t *testing.T) {
+		fake, driver, advanceClock := startFakeForMount(t)
+		defer fake.Close()
+		defer driver.Close()
+
+		require.Nil(t, driver.Unpark())
+		require.Nil(t, driver.StartJog(30.0, JogNorth))
+		advanceClock(time.Second)
+		require.Nil(t, driver.StartJog(60.0, JogWest))
+		advanceClock(time.Second)
+		position, err := driver.GetTelescopePosition()
+		require.Nil(t, err)
+		require.InDelta(t, 45.5, position.Altitude, 1.0e-6)
+		require.InDelta(t, -1.0, position.Azimuth, 1.0e-6)
+		require.NotNil(t, driver.StartJog(1.0, "Up"), "Invalid direction should be rejected")
+	})
+}
+
+func TestCaptureFlatSetsDithered(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := TelescopePosition{Altitude: 20.0, Azimuth: 90.0}
+	settings := FlatSetSettings{
+		FilterSlots: []int{1, 2}, Binning: 1, Count: 3, TargetADU: 25000, Tolerance: 0.05,
+		MinExposure: 0.1, MaxExposure: 60.0,
+		Dither: FlatDither{RadiusArcminutes: 2.0, SettleSeconds: 3, Seed: 11},
+	}
+
+	t.Run("dithers between flats and returns to start", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		service.SetSimulateFlatCapture(false)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil).AnyTimes()
+		mockDriver.EXPECT().FilterNames().Return([]string{"Red", "Green"}, nil)
+		captures := setUpSimulatedFlatPanel(mockDriver, 1, 1000.0, 2000.0)
+		mockDriver.EXPECT().GetTelescopePosition().Return(start, nil)
+		mockDriver.EXPECT().IsSlewComplete().Return(true, nil).AnyTimes()
+
+		north, east := 0.0, 0.0
+		jogs := 0
+		mockDriver.EXPECT().StartJog(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(arcminutes float64, direction string) error {
+				jogs++
+				require.Greater(t, arcminutes, 0.0)
+				switch direction {
+				case JogNorth:
+					north += arcminutes
+				case JogSouth:
+					north -= arcminutes
+				case JogEast:
+					east += arcminutes
+				case JogWest:
+					east -= arcminutes
+				}
+				require.LessOrEqual(t, math.Hypot(north, east), 2.0+1.0e-9, "Offset outside dither radius")
+				return nil
+			}).AnyTimes()
+		mockDriver.EXPECT().StartSlewToAltAz(20.0, 90.0).Return(nil)
+
+		results, err := service.CaptureFlatSets(settings)
+		require.Nil(t, err, "CaptureFlatSets failed")
+		require.Len(t, results, 2)
+		for _, result := range results {
+			require.Nil(t, result.Err)
+			require.Len(t, result.ADUs, 3)
+		}
+		// Test exposures finding the exposure aren't saved, so aren't dithered
+		savedFlats := 6
+		require.Greater(t, *captures, savedFlats)
+		require.Equal(t, 2*(savedFlats-1), jogs, "Should jog north/south and east/west before every flat but the first")
+	})
+
+	t.Run("tracking is off for the run and back on at the end", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		service.SetSimulateFlatCapture(false)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil).AnyTimes()
+		mockDriver.EXPECT().FilterNames().Return([]string{"Red", "Green"}, nil)
+		setUpSimulatedFlatPanel(mockDriver, 1, 1000.0, 2000.0)
+		tracking := start
+		tracking.Tracking = true
+		mockDriver.EXPECT().GetTelescopePosition().Return(tracking, nil)
+		mockDriver.EXPECT().IsSlewComplete().Return(true, nil).AnyTimes()
+		mockDriver.EXPECT().StartJog(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		gomock.InOrder(
+			mockDriver.EXPECT().SetTracking(false).Return(nil),
+			mockDriver.EXPECT().StartSlewToAltAz(20.0, 90.0).Return(nil),
+			mockDriver.EXPECT().SetTracking(true).Return(nil),
+		)
+
+		_, err := service.CaptureFlatSets(settings)
+		require.Nil(t, err, "CaptureFlatSets failed")
+	})
+
+	t.Run("parked mount can't dither", func(t *testing.T) {
+		service, mockDriver, _ := setUpConnectedMockService(ctrl)
+		mockDriver.EXPECT().FilterNames().Return([]string{"Red", "Green"}, nil)
+		mockDriver.EXPECT().GetTelescopePosition().Return(TelescopePosition{Parked: true}, nil)
+
+		_, err := service.CaptureFlatSets(settings)
+		require.ErrorIs(t, err, ErrDitherFailed)
+	})
+
+	t.Run("mount failure stops the run", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		service.SetSimulateFlatCapture(false)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil).AnyTimes()
+		mockDriver.EXPECT().FilterNames().Return([]string{"Red", "Green"}, nil)
+		setUpSimulatedFlatPanel(mockDriver, 1, 1000.0, 2000.0)
+		mockDriver.EXPECT().GetTelescopePosition().Return(start, nil)
+		mockDriver.EXPECT().StartJog(gomock.Any(), gomock.Any()).Return(errors.New("mount not responding"))
+
+		results, err := service.CaptureFlatSets(settings)
+		require.ErrorIs(t, err, ErrDitherFailed)
+		require.Len(t, results, 1, "Second filter should not be attempted")
+		require.Len(t, results[0].ADUs, 1, "First flat is taken before any dither")
+	})
+
+	t.Run("negative radius is rejected", func(t *testing.T) {
+		service, _, _ := setUpConnectedMockService(ctrl)
+		invalid := settings
+		invalid.Dither.RadiusArcminutes = -1.0
+		_, err := service.CaptureFlatSets(invalid)
+		require.NotNil(t, err)
+	})
+}
@@ -1,13 +1,17 @@
 package goTheSkyX
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"net"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 )
 
 // TheSkyDriver is the low-level interface to the TheSkyX application's TCP server, running
@@ -20,48 +24,117 @@ type TheSkyDriver interface {
 	Close() error
 	SetDebug(debug bool)
 	SetVerbosity(verbosity int)
+	SetMaxReplySize(bytes int)
+	SetReplyTimeout(timeout time.Duration)
+	SetAutoSave(on bool)
+	SetSubframe(subframe Subframe)
 	// Camera
 	ConnectCamera() error
 	StartCooling(temp float64) error
 	GetCameraTemperature() (float64, error)
+	GetCoolerPower() (float64, error)
+	GetCoolerStatus() (CoolerStatus, error)
+	SetCoolerSetPoint(temperature float64) error
 	StopCooling() error
+	GetCameraCapabilities() (CameraCapabilities, error)
 	// Frame Capture
 	MeasureDownloadTime(binning int) (float64, error)
+	MeasureDownloadTimeContext(ctx context.Context, binning int) (float64, error)
 	StartDarkFrameCapture(binning int, seconds float64, downloadTime float64) error
 	StartFlatFrameCapture(binning int, seconds float64, filterSlot int, downloadTime float64, saveImage bool) error
 	IsCaptureDone() (bool, error)
 	StartBiasFrameCapture(binning int, downloadTime float64) error
 	GetADUValue() (int64, error)
+	GetImageStats(centralFraction float64, hotPixelADU int64) (ImageStats, error)
+	AbortExposure() error
+	SaveImage(directory string, fileName string, keywords []FITSKeyword) (string, error)
+	GetCapturedImageInfo() (CaptureResult, error)
 	// Filters
 	FilterWheelIsConnected() (bool, error)
 	FilterWheelConnect() error
 	FilterWheelDisconnect() error
 	FilterNames() ([]string, error)
+	SetFilterSlot(filterSlot int) error
+	GetFilterSlot() (int, error)
+	// Mount
+	ConnectTelescope() error
+	StartSlewToAltAz(altitude float64, azimuth float64) error
+	IsSlewComplete() (bool, error)
+	AbortSlew() error
+	StartJog(arcminutes float64, direction string) error
+	StartPark() error
+	Unpark() error
+	SetTracking(on bool) error
+	GetTelescopePosition() (TelescopePosition, error)
 }
 
 type TheSkyDriverInstance struct {
-	isOpen          bool
-	server          string
-	port            int
-	cameraConnected bool
-	debug           bool
-	verbosity       int
+	*driverState
+	ctx context.Context // Commands are sent with this, if set; see withContext
+}
+
+// driverState is the connection and settings, shared by a driver and the copies of it made
+// by withContext
+type driverState struct {
+	isOpen               bool
+	server               string
+	port                 int
+	conn                 net.Conn
+	mutex                sync.Mutex
+	cameraConnected      bool
+	filterWheelConnected bool
+	telescopeConnected   bool
+	debug                bool
+	verbosity            int
+	maxReplySize         int
+	replyTimeout         time.Duration
+	autoSave             bool     // Whether captures are saved by TheSkyX's AutoSave
+	subframe             Subframe // For flats and download time measurements; darks and bias frames are full frame
 }
 
 const FilterSlotNoFilter = -1
 
+// FilterSlotMoving is returned by GetFilterSlot while the filter wheel is still moving
+const FilterSlotMoving = 0
+
+// maxTheSkyBuffer is the size of each socket read; a reply may take several reads
 const maxTheSkyBuffer = 4096
 
+// ErrServerUnreachable is returned (wrapped) when the TheSkyX server cannot be reached,
+// either when first connecting or when a broken connection cannot be re-established
+var ErrServerUnreachable = errors.New("TheSkyX server unreachable")
+
+const dialTimeout = 10 * time.Second
+const reconnectAttempts = 4
+const reconnectInitialBackoff = 500 * time.Millisecond // doubled after each failed attempt
+
 // NewTheSkyDriver is the constructor for a working instance of the interface
 func NewTheSkyDriver(
 	debug bool, verbosity int) TheSkyDriver {
-	driver := &TheSkyDriverInstance{
-		debug:     debug,
-		verbosity: verbosity,
-	}
+	driver := &TheSkyDriverInstance{driverState: &driverState{
+		debug:        debug,
+		verbosity:    verbosity,
+		maxReplySize: defaultMaxReplySize,
+		replyTimeout: defaultReplyTimeout,
+		autoSave:     true,
+	}}
 	return driver
 }
 
+// withContext returns a driver sharing this one's connection and settings, whose commands
+// stop waiting for the server when the context is cancelled
+func (driver *TheSkyDriverInstance) withContext(ctx context.Context) TheSkyDriver {
+	return &TheSkyDriverInstance{driverState: driver.driverState, ctx: ctx}
+}
+
+// commandContext is the context commands are sent with
+func (driver *TheSkyDriverInstance) commandContext() context.Context {
+	if driver.ctx == nil {
+		return context.Background()
+	}
+	return driver.ctx
+}
+
 func (driver *TheSkyDriverInstance) SetDebug(debug bool) {
 	driver.debug = debug
 }
@@ -70,20 +143,66 @@ func (driver *TheSkyDriverInstance) SetVerbosity(verbosity int) {
 	driver.verbosity = verbosity
 }
 
-// Connect opens connection to the server and camera.
+// SetMaxReplySize sets the largest reply, in bytes, we will accept from the server
+func (driver *TheSkyDriverInstance) SetMaxReplySize(bytes int) {
+	driver.maxReplySize = bytes
+}
+
+// SetReplyTimeout sets how long we will wait for a complete reply to any one command.
+// Zero means wait indefinitely.
+func (driver *TheSkyDriverInstance) SetReplyTimeout(timeout time.Duration) {
+	driver.replyTimeout = timeout
+}
+
+// SetAutoSave sets whether dark, bias and flat captures are saved by TheSkyX's AutoSave, to its
+// AutoSave directory with its own file names.  Turn it off when saving with SaveImage instead.
+func (driver *TheSkyDriverInstance) SetAutoSave(on bool) {
+	driver.autoSave = on
+}
+
+// SetSubframe sets the part of the sensor read out by flat captures and download time
+// measurements; the zero value reads the full sensor.  Dark and bias captures always read the
+// full sensor.
+func (driver *TheSkyDriverInstance) SetSubframe(subframe Subframe) {
+	driver.subframe = subframe
+}
+
+// writeSubframe adds the commands setting the camera's subframe to a script.  TheSkyX keeps the
+// subframe between scripts, so it is set, or switched off, every time.
+func writeSubframe(message *strings.Builder, subframe Subframe) {
+	if subframe.IsFullFrame() {
+		message.WriteString("ccdsoftCamera.Subframe=false;\n")
+		return
+	}
+	message.WriteString("ccdsoftCamera.Subframe=true;\n")
+	message.WriteString(fmt.Sprintf("ccdsoftCamera.SubframeLeft=%d;\n", subframe.Left))
+	message.WriteString(fmt.Sprintf("ccdsoftCamera.SubframeTop=%d;\n", subframe.Top))
+	message.WriteString(fmt.Sprintf("ccdsoftCamera.SubframeRight=%d;\n", subframe.Right))
+	message.WriteString(fmt.Sprintf("ccdsoftCamera.SubframeBottom=%d;\n", subframe.Bottom))
+}
+
+// Connect opens the socket connection to the server.
 //
-//	In fact, all we do is remember the server coordinates. The actual open of the
-//	socket is deferred until we have a command to send
+//	The connection is held open and used for all subsequent commands, rather than opening a
+//	socket for each command. If the server is not listening, an error wrapping
+//	ErrServerUnreachable is returned.
 func (driver *TheSkyDriverInstance) Connect(server string, port int) error {
 	if driver.verbosity >= 4 || driver.debug {
 		fmt.Printf("TheSkyDriverInstance/Connect(%s,%d) entered\n", server, port)
 	}
+	driver.mutex.Lock()
+	defer driver.mutex.Unlock()
 	if driver.isOpen {
 		fmt.Printf("TheSkyDriverInstance/Connect(%s,%d): Already connected\n", server, port)
 		return nil // already open, nothing to do
 	}
 	driver.server = server
 	driver.port = port
+	conn, err := driver.dial(driver.commandContext())
+	if err != nil {
+		return fmt.Errorf("TheSkyDriverInstance/Connect: %w at %s: %v", ErrServerUnreachable, driver.address(), err)
+	}
+	driver.conn = conn
 	driver.isOpen = true
 	if driver.verbosity >= 5 || driver.debug {
 		fmt.Printf("TheSkyDriverInstance/Connect(%s,%d) successful\n", server, port)
@@ -96,11 +215,23 @@ func (driver *TheSkyDriverInstance) Close() error {
 	if driver.verbosity >= 4 || driver.debug {
 		fmt.Printf("TheSkyDriverInstance/Close() entered\n")
 	}
+	driver.mutex.Lock()
+	defer driver.mutex.Unlock()
 	if !driver.isOpen {
 		fmt.Println("TheSkyDriverInstance/Close(): Not open")
 		return nil
 	}
 	driver.isOpen = false
+	driver.cameraConnected = false
+	driver.filterWheelConnected = false
+	driver.telescopeConnected = false
+	if driver.conn != nil {
+		err := driver.conn.Close()
+		driver.conn = nil
+		if err != nil {
+			return err
+		}
+	}
 	if driver.verbosity >= 5 || driver.debug {
 		fmt.Printf("TheSkyDriverInstance/Close() successful\n")
 	}
@@ -148,6 +279,24 @@ func (driver *TheSkyDriverInstance) StartCooling(temperature float64) error {
 	return nil
 }
 
+// SetCoolerSetPoint changes the target temperature without switching regulation off and on
+// again (as StartCooling does), so the cooler keeps working while the set point moves
+func (driver *TheSkyDriverInstance) SetCoolerSetPoint(temperature float64) error {
+	if driver.verbosity >= 4 || driver.debug {
+		fmt.Printf("TheSkyDriverInstance/SetCoolerSetPoint(%g)  \n", temperature)
+	}
+	if !driver.cameraConnected {
+		return errors.New("TheSkyDriverInstance/SetCoolerSetPoint: Camera not connected")
+	}
+
+	command := fmt.Sprintf("ccdsoftCamera.TemperatureSetPoint=%.2f;\n", temperature)
+	if err := driver.sendCommandIgnoreReply(command); err != nil {
+		fmt.Println("SetCoolerSetPoint error from driver:", err)
+		return err
+	}
+	return nil
+}
+
 func (driver *TheSkyDriverInstance) StopCooling() error {
 	var commands strings.Builder
 	commands.WriteString("ccdsoftCamera.RegulateTemperature=false;\n")
@@ -183,6 +332,138 @@ func (driver *TheSkyDriverInstance) GetCameraTemperature() (float64, error) {
 	return numberResult, nil
 }
 
+// GetCoolerPower asks TheSkyX what percentage of its power the camera's cooler is using
+func (driver *TheSkyDriverInstance) GetCoolerPower() (float64, error) {
+	if driver.verbosity >= 4 || driver.debug {
+		fmt.Println("GetCoolerPower()")
+	}
+	if !driver.cameraConnected {
+		return 0.0, errors.New("TheSkyDriverInstance/GetCoolerPower: Camera not connected")
+	}
+	var commands strings.Builder
+	commands.WriteString("var power=ccdsoftCamera.ThermoElectricCoolerPower;\n")
+	commands.WriteString("var Out;\n")
+	commands.WriteString("Out=power + \"\\n\";\n")
+
+	numberResult, err := driver.sendCommandFloatReply(commands.String())
+	if err != nil {
+		fmt.Println("GetCoolerPower error from driver:", err)
+		return -1.0, err
+	}
+	return numberResult, nil
+}
+
+// GetCoolerStatus asks TheSkyX for the camera's temperature, set point, regulation state and
+// cooler power, all in one packet.  The four values come back tab-separated.
+func (driver *TheSkyDriverInstance) GetCoolerStatus() (CoolerStatus, error) {
+	if driver.verbosity >= 4 || driver.debug {
+		fmt.Println("GetCoolerStatus()")
+	}
+	if !driver.cameraConnected {
+		return CoolerStatus{}, errors.New("TheSkyDriverInstance/GetCoolerStatus: Camera not connected")
+	}
+	var commands strings.Builder
+	commands.WriteString("var temp=ccdsoftCamera.Temperature;\n")
+	commands.WriteString("var setPoint=ccdsoftCamera.TemperatureSetPoint;\n")
+	commands.WriteString("var regulating=ccdsoftCamera.RegulateTemperature;\n")
+	commands.WriteString("var power=ccdsoftCamera.ThermoElectricCoolerPower;\n")
+	commands.WriteString("var Out;\n")
+	commands.WriteString("Out=temp + \"\\t\" + setPoint + \"\\t\" + regulating + \"\\t\" + power + \"\\n\";\n")
+
+	responseBlob, err := driver.sendCommandStringReply(commands.String())
+	if err != nil {
+		fmt.Println("GetCoolerStatus error from driver:", err)
+		return CoolerStatus{}, err
+	}
+	return parseCoolerStatus(responseBlob)
+}
+
+// parseCoolerStatus interprets the tab-separated reply from GetCoolerStatus.  TheSkyX reports
+// RegulateTemperature as true/false, but some camera drivers give 1/0, so we accept either.
+func parseCoolerStatus(reply string) (CoolerStatus, error) {
+	parts := strings.Split(reply, "\t")
+	if len(parts) != 4 {
+		return CoolerStatus{}, &MalformedReplyError{Reason: "expected 4 cooler status values", Reply: reply}
+	}
+	var numbers [3]float64
+	for i, part := range []string{parts[0], parts[1], parts[3]} {
+		number, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return CoolerStatus{}, &MalformedReplyError{Reason: "cooler status value is not a number", Reply: reply}
+		}
+		numbers[i] = number
+	}
+	var regulating bool
+	switch strings.ToLower(strings.TrimSpace(parts[2])) {
+	case "true", "1":
+		regulating = true
+	case "false", "0":
+		regulating = false
+	default:
+		return CoolerStatus{}, &MalformedReplyError{Reason: "regulation state is not a boolean", Reply: reply}
+	}
+	return CoolerStatus{
+		Temperature:        numbers[0],
+		SetPoint:           numbers[1],
+		Regulating:         regulating,
+		CoolerPowerPercent: numbers[2],
+	}, nil
+}
+
+// GetCameraCapabilities asks TheSkyX for the camera's sensor size and pixel size.  Each is
+// read in its own packet, so a property the camera's driver doesn't report can't spoil the
+// others; it is left at zero, meaning unknown.  Whether bias frames can be taken isn't a
+// property at all, so all frame types are assumed until the camera refuses one.
+func (driver *TheSkyDriverInstance) GetCameraCapabilities() (CameraCapabilities, error) {
+	if driver.verbosity >= 4 || driver.debug {
+		fmt.Println("GetCameraCapabilities()")
+	}
+	if !driver.cameraConnected {
+		return CameraCapabilities{}, errors.New("TheSkyDriverInstance/GetCameraCapabilities: Camera not connected")
+	}
+	var values [3]float64
+	for i, property := range []string{"WidthInPixels", "HeightInPixels", "PixelSize1x1"} {
+		value, err := driver.getCameraProperty(property)
+		var theSkyXError *TheSkyXError
+		var malformedReply *MalformedReplyError
+		if errors.As(err, &theSkyXError) || errors.As(err, &malformedReply) {
+			if driver.verbosity >= 4 || driver.debug {
+				fmt.Printf("GetCameraCapabilities: camera doesn't report %s: %v\n", property, err)
+			}
+			continue
+		}
+		if err != nil {
+			fmt.Println("GetCameraCapabilities error from driver:", err)
+			return CameraCapabilities{}, err
+		}
+		values[i] = value
+	}
+	return CameraCapabilities{
+		WidthPixels:      int(values[0]),
+		HeightPixels:     int(values[1]),
+		PixelSizeMicrons: values[2],
+		FrameTypes:       []FrameType{DarkFrame, BiasFrame, FlatFrame},
+	}, nil
+}
+
+// getCameraProperty reads one numeric ccdsoftCamera property
+func (driver *TheSkyDriverInstance) getCameraProperty(property string) (float64, error) {
+	var commands strings.Builder
+	commands.WriteString(fmt.Sprintf("var value=ccdsoftCamera.%s;\n", property))
+	commands.WriteString("var Out;\n")
+	commands.WriteString("Out=value + \"\\n\";\n")
+
+	responseBlob, err := driver.sendCommandStringReply(commands.String())
+	if err != nil {
+		return 0.0, err
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(responseBlob), 64)
+	if err != nil {
+		return 0.0, &MalformedReplyError{Reason: property + " is not a number", Reply: responseBlob}
+	}
+	return value, nil
+}
+
 func (driver *TheSkyDriverInstance) GetADUValue() (int64, error) {
 	if driver.verbosity >= 4 || driver.debug {
 		fmt.Println("GetADUValue()")
@@ -204,6 +485,128 @@ func (driver *TheSkyDriverInstance) GetADUValue() (int64, error) {
 	return int64(math.Round(numberResult)), nil
 }
 
+// GetImageStats measures the most recent image, over the central fraction of its width and
+// height (1 for the whole image).  If hotPixelADU is more than 0, pixels more than that above
+// the median are counted as hot; the median is only known once every pixel has been seen, so
+// they are counted from the histogram afterwards.  The statistics are computed by the script, in TheSkyX, so
+// only the results come back, tab-separated.  Here is the javascript, explained:
+//
+//	ccdsoftCameraImage.AttachToActive();
+//	var width=ccdsoftCameraImage.WidthInPixels;     // Of the image, so already binned
+//	var height=ccdsoftCameraImage.HeightInPixels;
+//	var left=Math.floor(width*margin);              // margin is (1 - centralFraction) / 2
+//	var top=Math.floor(height*margin);
+//	var right=Math.max(width-left, left+1);         // At least one pixel
+//	var bottom=Math.max(height-top, top+1);
+//	var histogram=[];                               // Pixel count for each ADU, for the median
+//	for (var v=0; v<65536; v++) { histogram[v]=0; }
+//	...
+//	for (var y=top; y<bottom; y++) {
+//	  var row=ccdsoftCameraImage.scanLine(y);       // One row of pixel values
+//	  for (var x=left; x<right; x++) {
+//	    var value=Math.round(row[x]);
+//	    histogram[value]++;                         // And the count, sum, sum of squares,
+//	    ...                                         // minimum, maximum and saturated count
+//	  }
+//	}
+//	for (v=0; v<65536; v++) {                       // The median is the first ADU at which
+//	  cumulative+=histogram[v];                     // half the pixels have been counted
+//	  if (cumulative*2<count) { median=v+1; }
+//	}
+//	for (v=median+hotPixelADU+1; v<=maximum; v++) { // Pixels far above the median are hot
+//	  hot+=histogram[v];
+//	}
+func (driver *TheSkyDriverInstance) GetImageStats(centralFraction float64, hotPixelADU int64) (ImageStats, error) {
+	if driver.verbosity >= 4 || driver.debug {
+		fmt.Println("TheSkyDriverInstance/GetImageStats ", centralFraction)
+	}
+	if !driver.cameraConnected {
+		return ImageStats{}, errors.New("TheSkyDriverInstance/GetImageStats: Camera not connected")
+	}
+	margin := (1.0 - centralFraction) / 2.0
+	var commands strings.Builder
+	commands.WriteString("ccdsoftCameraImage.AttachToActive();\n")
+	commands.WriteString("var width=ccdsoftCameraImage.WidthInPixels;\n")
+	commands.WriteString("var height=ccdsoftCameraImage.HeightInPixels;\n")
+	commands.WriteString(fmt.Sprintf("var left=Math.floor(width*%.6f);\n", margin))
+	commands.WriteString(fmt.Sprintf("var top=Math.floor(height*%.6f);\n", margin))
+	commands.WriteString("var right=Math.max(width-left, left+1);\n")
+	commands.WriteString("var bottom=Math.max(height-top, top+1);\n")
+	commands.WriteString(fmt.Sprintf("var histogram=[];\nfor (var v=0; v<%d; v++) { histogram[v]=0; }\n", maxADU+1))
+	commands.WriteString(fmt.Sprintf("var count=0;\nvar sum=0;\nvar sumSquares=0;\nvar minimum=%d;\nvar maximum=0;\nvar saturated=0;\n", maxADU))
+	commands.WriteString("for (var y=top; y<bottom; y++) {\n")
+	commands.WriteString("  var row=ccdsoftCameraImage.scanLine(y);\n")
+	commands.WriteString("  for (var x=left; x<right; x++) {\n")
+	commands.WriteString(fmt.Sprintf("    var value=Math.max(0, Math.min(%d, Math.round(row[x])));\n", maxADU))
+	commands.WriteString("    histogram[value]++;\n")
+	commands.WriteString("    count++;\n")
+	commands.WriteString("    sum+=value;\n")
+	commands.WriteString("    sumSquares+=value*value;\n")
+	commands.WriteString("    minimum=Math.min(minimum, value);\n")
+	commands.WriteString("    maximum=Math.max(maximum, value);\n")
+	commands.WriteString(fmt.Sprintf("    if (value>=%d) { saturated++; }\n", saturatedADU))
+	commands.WriteString("  }\n")
+	commands.WriteString("}\n")
+	commands.WriteString("var median=0;\nvar cumulative=0;\n")
+	commands.WriteString(fmt.Sprintf("for (v=0; v<%d; v++) {\n", maxADU+1))
+	commands.WriteString("  cumulative+=histogram[v];\n")
+	commands.WriteString("  if (cumulative*2<count) { median=v+1; }\n")
+	commands.WriteString("}\n")
+	commands.WriteString("var hot=0;\n")
+	if hotPixelADU > 0 {
+		commands.WriteString(fmt.Sprintf("for (v=median+%d; v<=maximum; v++) {\n", hotPixelADU+1))
+		commands.WriteString("  hot+=histogram[v];\n")
+		commands.WriteString("}\n")
+	}
+	commands.WriteString("var mean=sum/count;\n")
+	commands.WriteString("var stdDev=Math.sqrt(Math.max(0, sumSquares/count-mean*mean));\n")
+	commands.WriteString("var Out;\n")
+	commands.WriteString("Out=count + \"\\t\" + mean + \"\\t\" + median + \"\\t\" + stdDev + \"\\t\" + minimum + \"\\t\" + maximum + \"\\t\" + saturated + \"\\t\" + hot + \"\\n\";\n")
+
+	responseBlob, err := driver.sendCommandStringReply(commands.String())
+	if err != nil {
+		fmt.Println("GetImageStats error from driver:", err)
+		return ImageStats{}, err
+	}
+	stats, err := parseImageStats(responseBlob)
+	if err != nil {
+		return ImageStats{}, err
+	}
+	stats.CentralFraction = centralFraction
+	stats.HotPixelADU = hotPixelADU
+	return stats, nil
+}
+
+// parseImageStats interprets the tab-separated reply from GetImageStats: pixel count, mean,
+// median, standard deviation, minimum, maximum, saturated pixel count and hot pixel count
+func parseImageStats(reply string) (ImageStats, error) {
+	parts := strings.Split(reply, "\t")
+	if len(parts) != 8 {
+		return ImageStats{}, &MalformedReplyError{Reason: "expected 8 image statistics", Reply: reply}
+	}
+	var numbers [8]float64
+	for i, part := range parts {
+		number, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return ImageStats{}, &MalformedReplyError{Reason: "image statistic is not a number", Reply: reply}
+		}
+		numbers[i] = number
+	}
+	if numbers[0] < 1 {
+		return ImageStats{}, &MalformedReplyError{Reason: "no pixels measured", Reply: reply}
+	}
+	return ImageStats{
+		PixelCount:      int(numbers[0]),
+		Mean:            numbers[1],
+		Median:          int64(numbers[2]),
+		StdDev:          numbers[3],
+		Min:             int64(numbers[4]),
+		Max:             int64(numbers[5]),
+		SaturatedPixels: int(numbers[6]),
+		HotPixels:       int(numbers[7]),
+	}, nil
+}
+
 // MeasureDownloadTime measures the time needed to download an image from the camera to the TheSkyX application
 // We do this because the download time is often significant, especially on older cameras, and because TheSkyX
 // does not provide a notification that download is complete. By knowing the download time, we can initiate an exposure
@@ -227,6 +630,7 @@ func (driver *TheSkyDriverInstance) GetADUValue() (int64, error) {
 //	 ccdsoftCamera.AutoSaveOn=false;				// Don't save the image to disk
 //	 ccdsoftCamera.BinX=1;							// Set the binning level
 //	 ccdsoftCamera.BinY=1;							// Set the binning level
+//	 ccdsoftCamera.Subframe=false;					// Full frame, or the subframe set with SetSubframe
 //	 ccdsoftCamera.ExposureTime=0.1;				// Set the exposure time
 //
 //	 // Record the time before the image
@@ -247,6 +651,13 @@ func (driver *TheSkyDriverInstance) GetADUValue() (int64, error) {
 const shortExposureLength = 0.1
 
 func (driver *TheSkyDriverInstance) MeasureDownloadTime(binning int) (float64, error) {
+	return driver.MeasureDownloadTimeContext(driver.commandContext(), binning)
+}
+
+// MeasureDownloadTimeContext is MeasureDownloadTime, but gives up waiting for the server if the
+// context is cancelled.  The measurement is a single synchronous command, so it is the one driver
+// call that can keep us waiting on the server for a significant time.
+func (driver *TheSkyDriverInstance) MeasureDownloadTimeContext(ctx context.Context, binning int) (float64, error) {
 	if driver.verbosity >= 4 || driver.debug {
 		fmt.Println("TheSkyDriverInstance/MeasureDownloadTime ", binning)
 	}
@@ -263,6 +674,7 @@ func (driver *TheSkyDriverInstance) MeasureDownloadTime(binning int) (float64, e
 	message.WriteString("ccdsoftCamera.AutoSaveOn=false;\n")
 	message.WriteString("ccdsoftCamera.BinX=1;\n")
 	message.WriteString("ccdsoftCamera.BinY=1;\n")
+	writeSubframe(&message, driver.subframe)
 	message.WriteString(fmt.Sprintf("ccdsoftCamera.ExposureTime=%.2f;\n", shortExposureLength))
 	message.WriteString("sky6Utils.ComputeUniversalTime();\n")
 	message.WriteString("var timeBefore=sky6Utils.dOut0;\n")
@@ -273,12 +685,15 @@ func (driver *TheSkyDriverInstance) MeasureDownloadTime(binning int) (float64, e
 	message.WriteString("out = timeBefore + \",\" + timeAfter + \"\\n\";\n")
 	//fmt.Println("Command to send:\n", message.String())
 
-	responseString, err := driver.sendCommandStringReply(message.String())
+	responseString, err := driver.sendCommandStringReplyContext(ctx, message.String())
 	if err != nil {
 		fmt.Println("MeasureDownloadTime error from driver:", err)
 		return -1.0, err
 	}
 	responseParts := strings.Split(responseString, ",")
+	if len(responseParts) != 2 {
+		return -1.0, &MalformedReplyError{Reason: "expected two times", Reply: responseString}
+	}
 
 	timeBefore, err := strconv.ParseFloat(responseParts[0], 64)
 	if err != nil {
@@ -308,14 +723,15 @@ func (driver *TheSkyDriverInstance) StartDarkFrameCapture(binning int, seconds f
 		return errors.New("TheSkyDriverInstance/StartDarkFrameCapture: Camera not connected")
 	}
 	var message strings.Builder
-	message.WriteString("ccdsoftCamera.Autoguider=false;\n")  // Use main camera not autoguider
-	message.WriteString("ccdsoftCamera.Asynchronous=true;\n") // Async (don't wait)
-	message.WriteString("ccdsoftCamera.Frame=3;\n")           // Dark frame
-	message.WriteString("ccdsoftCamera.ImageReduction=0;\n")  // No image reduction
-	message.WriteString("ccdsoftCamera.ToNewWindow=false;\n") // Don't open a new window
-	message.WriteString("ccdsoftCamera.AutoSaveOn=true;\n")   // Save the image to configured location
+	message.WriteString("ccdsoftCamera.Autoguider=false;\n")                                                // Use main camera not autoguider
+	message.WriteString("ccdsoftCamera.Asynchronous=true;\n")                                               // Async (don't wait)
+	message.WriteString("ccdsoftCamera.Frame=3;\n")                                                         // Dark frame
+	message.WriteString("ccdsoftCamera.ImageReduction=0;\n")                                                // No image reduction
+	message.WriteString("ccdsoftCamera.ToNewWindow=false;\n")                                               // Don't open a new window
+	message.WriteString(fmt.Sprintf("ccdsoftCamera.AutoSaveOn=%s;\n", makeJavascriptBool(driver.autoSave))) // Save the image to configured location?
 	message.WriteString(fmt.Sprintf("ccdsoftCamera.BinX=%d;\n", binning))
 	message.WriteString(fmt.Sprintf("ccdsoftCamera.BinY=%d;\n", binning))
+	writeSubframe(&message, Subframe{}) // Darks are always full frame
 	message.WriteString(fmt.Sprintf("ccdsoftCamera.ExposureTime=%.2f;\n", seconds))
 	message.WriteString("var cameraResult = ccdsoftCamera.TakeImage();\n")
 	message.WriteString("var Out;\n")
@@ -385,6 +801,10 @@ func (driver *TheSkyDriverInstance) sendCommandFloatReply(command string) (float
 // sendCommandStringReply is an internal method that sends the given command string to the server.
 // This is used for commands where an arbitrary string reply is to be read and processed by the caller
 func (driver *TheSkyDriverInstance) sendCommandStringReply(command string) (string, error) {
+	return driver.sendCommandStringReplyContext(driver.commandContext(), command)
+}
+
+func (driver *TheSkyDriverInstance) sendCommandStringReplyContext(ctx context.Context, command string) (string, error) {
 	if driver.verbosity >= 6 || driver.debug {
 		fmt.Println("TheSkyDriverInstance/sendCommandStringReply: ", command)
 	}
@@ -395,7 +815,7 @@ func (driver *TheSkyDriverInstance) sendCommandStringReply(command string) (stri
 	message.WriteString(command)
 	message.WriteString("/* Socket End Packet */\n")
 
-	responseString, err := driver.sendCommand(message.String())
+	responseString, err := driver.sendCommandContext(ctx, message.String())
 	trimmedResponse := strings.TrimSpace(responseString)
 	if err != nil {
 		fmt.Println("sendCommandNoReply error from driver:", err)
@@ -407,60 +827,167 @@ func (driver *TheSkyDriverInstance) sendCommandStringReply(command string) (stri
 
 // sendCommand is an internal method that sends the given command packet to the server and
 // returns whatever reply is received.
+//
+//	The packet is sent on the connection opened by Connect. If that connection turns out to be
+//	broken (server restarted, network reset), we re-establish it and send the packet again.
 func (driver *TheSkyDriverInstance) sendCommand(command string) (string, error) {
+	return driver.sendCommandContext(driver.commandContext(), command)
+}
+
+// sendCommandContext is sendCommand, but stops waiting for the reply if the context is cancelled
+// or its deadline passes.  In that case, or if the reply is bad in some way, the rest of the reply
+// may still arrive later, so the connection can't be trusted for the next command; we drop it and
+// the next command reconnects.
+func (driver *TheSkyDriverInstance) sendCommandContext(ctx context.Context, command string) (string, error) {
 	//fmt.Println("TheSkyDriverInstance/sendCommand:", command)
 	//	This function must be mutex-locked in case of parallel activities
-	var mutex sync.Mutex
-	mutex.Lock()
-	defer mutex.Unlock()
+	driver.mutex.Lock()
+	defer driver.mutex.Unlock()
 
-	if driver.verbosity >= 6 || driver.debug {
-		fmt.Printf("TheSkyDriverInstance/sendCommand() opening socket(%s,%d)\n", driver.server, driver.port)
+	if !driver.isOpen {
+		return "", errors.New("TheSkyDriverInstance/sendCommand: Connection not open")
 	}
-	conn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", driver.server, driver.port))
-	if err != nil {
-		fmt.Println("Error opening socket:", err)
+	if err := ctx.Err(); err != nil {
 		return "", err
 	}
-	defer func(conn net.Conn) {
-		if driver.verbosity > 4 || driver.debug {
-			fmt.Println("Closing socket")
+	if driver.conn == nil {
+		// An earlier reconnect failed; try again now
+		if err := driver.reconnect(ctx); err != nil {
+			return "", err
 		}
-		_ = conn.Close()
-	}(conn)
-
-	numWritten, err := conn.Write([]byte(command))
+	}
+	response, err := driver.exchangePacket(ctx, command)
+	if err != nil && ctx.Err() == nil && isBrokenConnection(err) {
+		if driver.verbosity >= 4 || driver.debug {
+			fmt.Println("TheSkyDriverInstance/sendCommand: connection lost, reconnecting:", err)
+		}
+		if err := driver.reconnect(ctx); err != nil {
+			return "", err
+		}
+		response, err = driver.exchangePacket(ctx, command)
+	}
 	if err != nil {
+		_ = driver.conn.Close()
+		driver.conn = nil
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
 		fmt.Println("sendCommand error from driver:", err)
 		return "", err
 	}
-	if numWritten != len(command) {
-		fmt.Println("sendCommand wrong number of bytes from driver")
-		return "", errors.New("sendCommand wrong number of bytes from driver")
+	if driver.verbosity >= 5 || driver.debug {
+		fmt.Println("TheSkyDriverInstance/sendCommand() received response:", response)
 	}
 
-	responseBuffer := make([]byte, maxTheSkyBuffer)
-	numRead, err := conn.Read(responseBuffer)
+	//	Response will be of the form <data if any> | error line
+	responseText, errorLine, err := parseReply(response)
 	if err != nil {
+		_ = driver.conn.Close()
+		driver.conn = nil
 		fmt.Println("sendCommand error from driver:", err)
 		return "", err
 	}
-	if driver.verbosity >= 5 || driver.debug {
-		fmt.Println("TheSkyDriverInstance/sendCommand() received response:", string(responseBuffer[:numRead]))
+	return responseText, parseErrorLine(errorLine)
+}
+
+// exchangePacket writes one command packet to the open connection and reads the reply.
+// The socket deadline is the earlier of the context's deadline and the reply timeout, and
+// cancelling the context interrupts the read.
+func (driver *TheSkyDriverInstance) exchangePacket(ctx context.Context, command string) (string, error) {
+	conn := driver.conn
+	deadline, _ := ctx.Deadline() // zero time, meaning no deadline, if the context has none
+	replyDeadline := time.Time{}
+	if driver.replyTimeout > 0 {
+		replyDeadline = time.Now().Add(driver.replyTimeout)
+		if deadline.IsZero() || replyDeadline.Before(deadline) {
+			deadline = replyDeadline
+		}
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return "", err
 	}
+	stopInterrupt := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stopInterrupt()
 
-	//	Response will be of the form <data if any> | error line
-	responseParts := strings.Split(string(responseBuffer[:numRead]), "|")
-	responseText := responseParts[0]
-	errorLine := strings.ToLower(responseParts[1])
+	numWritten, err := conn.Write([]byte(command))
+	if err != nil {
+		return "", err
+	}
+	if numWritten != len(command) {
+		fmt.Println("sendCommand wrong number of bytes from driver")
+		return "", errors.New("sendCommand wrong number of bytes from driver")
+	}
 
-	if errorLine == "" {
-		return responseText, nil
+	response, err := readReply(conn, driver.maxReplySize)
+	var netError net.Error
+	if err != nil && ctx.Err() == nil && errors.As(err, &netError) && netError.Timeout() {
+		// The socket can time out at the context's deadline a moment before the context
+		// itself notices; that is the context's deadline, not a slow server
+		if deadline != replyDeadline {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("%w after %v", ErrReplyTimeout, driver.replyTimeout)
 	}
-	if strings.HasPrefix(errorLine, "no error.") {
-		return responseText, nil
+	return response, err
+}
+
+// reconnect closes the current connection, if any, and opens a new one, retrying with
+// increasing delays until the context is cancelled.  Called with the mutex held.
+func (driver *TheSkyDriverInstance) reconnect(ctx context.Context) error {
+	if driver.conn != nil {
+		_ = driver.conn.Close()
+		driver.conn = nil
+	}
+	backoff := reconnectInitialBackoff
+	var err error
+	for attempt := 1; attempt <= reconnectAttempts; attempt++ {
+		var conn net.Conn
+		conn, err = driver.dial(ctx)
+		if err == nil {
+			if driver.verbosity >= 4 || driver.debug {
+				fmt.Printf("TheSkyDriverInstance/reconnect: reconnected on attempt %d\n", attempt)
+			}
+			driver.conn = conn
+			return nil
+		}
+		if driver.verbosity >= 4 || driver.debug {
+			fmt.Printf("TheSkyDriverInstance/reconnect: attempt %d failed (%v), waiting %v\n", attempt, err, backoff)
+		}
+		if attempt < reconnectAttempts {
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			backoff *= 2
+		}
 	}
-	return responseText, errors.New("TheSkyX error: " + errorLine)
+	return fmt.Errorf("TheSkyDriverInstance/reconnect: %w at %s: %v", ErrServerUnreachable, driver.address(), err)
+}
+
+func (driver *TheSkyDriverInstance) dial(ctx context.Context) (net.Conn, error) {
+	if driver.verbosity >= 6 || driver.debug {
+		fmt.Printf("TheSkyDriverInstance/dial() opening socket(%s,%d)\n", driver.server, driver.port)
+	}
+	dialer := net.Dialer{Timeout: dialTimeout}
+	return dialer.DialContext(ctx, "tcp", driver.address())
+}
+
+func (driver *TheSkyDriverInstance) address() string {
+	return net.JoinHostPort(driver.server, strconv.Itoa(driver.port))
+}
+
+// isBrokenConnection tells whether an error from the socket means the connection has been
+// lost (so reconnecting is worthwhile), as opposed to some other failure
+func isBrokenConnection(err error) bool {
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED)
 }
 
 // IsCaptureDone polls the server to see if the camera is done with its current activity
@@ -487,6 +1014,26 @@ func (driver *TheSkyDriverInstance) IsCaptureDone() (bool, error) {
 	return responseString == "1", nil
 }
 
+// AbortExposure asks the camera to stop the exposure in progress, if any
+func (driver *TheSkyDriverInstance) AbortExposure() error {
+	if driver.verbosity >= 4 || driver.debug {
+		fmt.Println("TheSkyDriverInstance/AbortExposure()")
+	}
+	if !driver.cameraConnected {
+		return errors.New("TheSkyDriverInstance/AbortExposure: Camera not connected")
+	}
+	var message strings.Builder
+	message.WriteString("ccdsoftCamera.Abort();\n")
+	message.WriteString("var Out;\n")
+	message.WriteString("Out=0;\n")
+
+	if err := driver.sendCommandIgnoreReply(message.String()); err != nil {
+		fmt.Println("AbortExposure error from driver:", err)
+		return err
+	}
+	return nil
+}
+
 func (driver *TheSkyDriverInstance) StartBiasFrameCapture(binning int, downloadTime float64) error {
 	if driver.verbosity >= 4 || driver.debug {
 		fmt.Println("TheSkyDriverInstance/StartBiasFrameCapture ", binning, downloadTime)
@@ -495,14 +1042,15 @@ func (driver *TheSkyDriverInstance) StartBiasFrameCapture(binning int, downloadT
 		return errors.New("TheSkyDriverInstance/StartBiasFrameCapture: Camera not connected")
 	}
 	var message strings.Builder
-	message.WriteString("ccdsoftCamera.Autoguider=false;\n")  // Use main camera not autoguider
-	message.WriteString("ccdsoftCamera.Asynchronous=true;\n") // Async (don't wait)
-	message.WriteString("ccdsoftCamera.Frame=2;\n")           // Bias frame
-	message.WriteString("ccdsoftCamera.ImageReduction=0;\n")  // No image reduction
-	message.WriteString("ccdsoftCamera.ToNewWindow=false;\n") // Don't open a new window
-	message.WriteString("ccdsoftCamera.AutoSaveOn=true;\n")   // Save the image to configured location
+	message.WriteString("ccdsoftCamera.Autoguider=false;\n")                                                // Use main camera not autoguider
+	message.WriteString("ccdsoftCamera.Asynchronous=true;\n")                                               // Async (don't wait)
+	message.WriteString("ccdsoftCamera.Frame=2;\n")                                                         // Bias frame
+	message.WriteString("ccdsoftCamera.ImageReduction=0;\n")                                                // No image reduction
+	message.WriteString("ccdsoftCamera.ToNewWindow=false;\n")                                               // Don't open a new window
+	message.WriteString(fmt.Sprintf("ccdsoftCamera.AutoSaveOn=%s;\n", makeJavascriptBool(driver.autoSave))) // Save the image to configured location?
 	message.WriteString(fmt.Sprintf("ccdsoftCamera.BinX=%d;\n", binning))
 	message.WriteString(fmt.Sprintf("ccdsoftCamera.BinY=%d;\n", binning))
+	writeSubframe(&message, Subframe{}) // Bias frames are always full frame
 	message.WriteString("var cameraResult = ccdsoftCamera.TakeImage();\n")
 	message.WriteString("var Out;\n")
 	message.WriteString("Out=cameraResult+\"\\n\";\n")
@@ -529,14 +1077,15 @@ func (driver *TheSkyDriverInstance) StartFlatFrameCapture(binning int, seconds f
 		// Note: filter slot is zero-based so we subtract one
 		message.WriteString(fmt.Sprintf("ccdsoftCamera.FilterIndexZeroBased=%d;\n", filterSlot-1))
 	}
-	message.WriteString("ccdsoftCamera.Autoguider=false;\n")                                          // Use main camera not autoguider
-	message.WriteString("ccdsoftCamera.Asynchronous=true;\n")                                         // Async (don't wait)
-	message.WriteString("ccdsoftCamera.Frame=4;\n")                                                   // Flat frame
-	message.WriteString("ccdsoftCamera.ImageReduction=0;\n")                                          // No image reduction
-	message.WriteString("ccdsoftCamera.ToNewWindow=false;\n")                                         // Don't open a new window
-	message.WriteString(fmt.Sprintf("ccdsoftCamera.AutoSaveOn=%s;\n", makeJavascriptBool(saveImage))) // Save the image?
+	message.WriteString("ccdsoftCamera.Autoguider=false;\n")                                                             // Use main camera not autoguider
+	message.WriteString("ccdsoftCamera.Asynchronous=true;\n")                                                            // Async (don't wait)
+	message.WriteString("ccdsoftCamera.Frame=4;\n")                                                                      // Flat frame
+	message.WriteString("ccdsoftCamera.ImageReduction=0;\n")                                                             // No image reduction
+	message.WriteString("ccdsoftCamera.ToNewWindow=false;\n")                                                            // Don't open a new window
+	message.WriteString(fmt.Sprintf("ccdsoftCamera.AutoSaveOn=%s;\n", makeJavascriptBool(saveImage && driver.autoSave))) // Save the image?
 	message.WriteString(fmt.Sprintf("ccdsoftCamera.BinX=%d;\n", binning))
 	message.WriteString(fmt.Sprintf("ccdsoftCamera.BinY=%d;\n", binning))
+	writeSubframe(&message, driver.subframe)
 	message.WriteString(fmt.Sprintf("ccdsoftCamera.ExposureTime=%.2f;\n", seconds))
 	message.WriteString("var cameraResult = ccdsoftCamera.TakeImage();\n")
 	message.WriteString("var Out;\n")
@@ -547,9 +1096,95 @@ func (driver *TheSkyDriverInstance) StartFlatFrameCapture(binning int, seconds f
 		fmt.Println("StartFlatFrameCapture error from driver on starting capture:", err)
 		return err
 	}
+	if filterSlot != FilterSlotNoFilter {
+		driver.filterWheelConnected = true
+	}
 	return nil
 }
 
+// SaveImage saves the image just captured as a FITS file with the given name, in the given
+// directory or, if that is "", TheSkyX's AutoSave directory, after adding the given FITS
+// keywords to its header.  It returns the path the file was saved to.
+func (driver *TheSkyDriverInstance) SaveImage(directory string, fileName string, keywords []FITSKeyword) (string, error) {
+	if driver.verbosity >= 4 || driver.debug {
+		fmt.Println("TheSkyDriverInstance/SaveImage ", directory, fileName)
+	}
+	if !driver.cameraConnected {
+		return "", errors.New("TheSkyDriverInstance/SaveImage: Camera not connected")
+	}
+	var message strings.Builder
+	message.WriteString("ccdsoftCameraImage.AttachToActive();\n")
+	for _, keyword := range keywords {
+		message.WriteString(fmt.Sprintf("ccdsoftCameraImage.setFITSKeyword(%s, %s);\n",
+			makeJavascriptString(keyword.Name), makeJavascriptString(keyword.Value)))
+	}
+	if directory == "" {
+		message.WriteString("var directory=ccdsoftCamera.AutoSavePath;\n")
+	} else {
+		message.WriteString(fmt.Sprintf("var directory=%s;\n", makeJavascriptString(directory)))
+	}
+	message.WriteString(fmt.Sprintf("var path=directory + \"/\" + %s;\n", makeJavascriptString(fileName)))
+	message.WriteString("ccdsoftCameraImage.Path=path;\n")
+	message.WriteString("ccdsoftCameraImage.Save();\n")
+	message.WriteString("var Out;\n")
+	message.WriteString("Out=path+\"\\n\";\n")
+
+	path, err := driver.sendCommandStringReply(message.String())
+	if err != nil {
+		fmt.Println("SaveImage error from driver:", err)
+		return "", err
+	}
+	return path, nil
+}
+
+// GetCapturedImageInfo asks TheSkyX where the image just captured was saved, and when and at
+// what temperature it was taken, from its FITS header.  Only those three fields of the result
+// are filled in.  The values come back tab-separated.
+func (driver *TheSkyDriverInstance) GetCapturedImageInfo() (CaptureResult, error) {
+	if driver.verbosity >= 4 || driver.debug {
+		fmt.Println("TheSkyDriverInstance/GetCapturedImageInfo()")
+	}
+	if !driver.cameraConnected {
+		return CaptureResult{}, errors.New("TheSkyDriverInstance/GetCapturedImageInfo: Camera not connected")
+	}
+	var message strings.Builder
+	message.WriteString("ccdsoftCameraImage.AttachToActive();\n")
+	message.WriteString("var path=ccdsoftCameraImage.Path;\n")
+	message.WriteString("var start=ccdsoftCameraImage.FITSKeyword(\"DATE-OBS\");\n")
+	message.WriteString("var temp=ccdsoftCameraImage.FITSKeyword(\"CCD-TEMP\");\n")
+	message.WriteString("var Out;\n")
+	message.WriteString("Out=start + \"\\t\" + path + \"\\t\" + temp + \"\\n\";\n")
+
+	responseBlob, err := driver.sendCommandStringReply(message.String())
+	if err != nil {
+		fmt.Println("GetCapturedImageInfo error from driver:", err)
+		return CaptureResult{}, err
+	}
+	return parseCapturedImageInfo(responseBlob)
+}
+
+// parseCapturedImageInfo interprets the tab-separated reply from GetCapturedImageInfo.  The path
+// is in the middle so that, if it is empty, trimming the reply doesn't lose it.
+func parseCapturedImageInfo(reply string) (CaptureResult, error) {
+	parts := strings.Split(reply, "\t")
+	if len(parts) != 3 {
+		return CaptureResult{}, &MalformedReplyError{Reason: "expected 3 captured image values", Reply: reply}
+	}
+	startTime, err := parseDateObs(strings.Trim(strings.TrimSpace(parts[0]), "'"))
+	if err != nil {
+		return CaptureResult{}, &MalformedReplyError{Reason: err.Error(), Reply: reply}
+	}
+	temperature, err := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+	if err != nil {
+		return CaptureResult{}, &MalformedReplyError{Reason: "CCD-TEMP is not a number", Reply: reply}
+	}
+	return CaptureResult{
+		Path:        strings.TrimSpace(parts[1]),
+		StartTime:   startTime,
+		Temperature: temperature,
+	}, nil
+}
+
 func makeJavascriptBool(b bool) string {
 	if b {
 		return "true"
@@ -557,6 +1192,15 @@ func makeJavascriptBool(b bool) string {
 	return "false"
 }
 
+// makeJavascriptString quotes a string for use in a script, escaping backslashes (as in
+// Windows paths) and quotes
+func makeJavascriptString(text string) string {
+	escaped := strings.ReplaceAll(text, "\\", "\\\\")
+	escaped = strings.ReplaceAll(escaped, "\"", "\\\"")
+	escaped = strings.ReplaceAll(escaped, "\n", "\\n")
+	return "\"" + escaped + "\""
+}
+
 func (driver *TheSkyDriverInstance) sendCommandIntReply(s string) (int, error) {
 	response, err := driver.sendCommandStringReply(s)
 	if err != nil {
@@ -570,6 +1214,8 @@ func (driver *TheSkyDriverInstance) sendCommandIntReply(s string) (int, error) {
 	return result, nil
 }
 
+// FilterWheelIsConnected asks TheSkyX whether the filter wheel is connected.  The wheel may
+// have been connected by another session, so we record the answer rather than trusting our own.
 func (driver *TheSkyDriverInstance) FilterWheelIsConnected() (bool, error) {
 	//fmt.Println("FilterWheelIsConnected")
 	var message strings.Builder
@@ -587,7 +1233,8 @@ func (driver *TheSkyDriverInstance) FilterWheelIsConnected() (bool, error) {
 
 	//fmt.Println("HasFilterWheel response:", responseCode)
 
-	return responseCode == 1, nil
+	driver.filterWheelConnected = responseCode == 1
+	return driver.filterWheelConnected, nil
 }
 
 func (driver *TheSkyDriverInstance) FilterWheelConnect() error {
@@ -604,15 +1251,45 @@ func (driver *TheSkyDriverInstance) FilterWheelConnect() error {
 		return err
 	}
 	if responseCode != 0 {
-		return errors.New(fmt.Sprintf("FilterWheelConnect: response code %d", responseCode))
+		return &TheSkyXError{Code: responseCode, Message: "FilterWheelConnect failed"}
 	}
 
+	driver.filterWheelConnected = true
 	return nil
 }
 
+// ensureFilterWheelConnected connects the filter wheel unless we know it to be connected
+// already, so setting or reading the slot fails (with ErrCannotColorGrab if there is no
+// wheel) rather than acting on a wheel that isn't there
+func (driver *TheSkyDriverInstance) ensureFilterWheelConnected() error {
+	if driver.filterWheelConnected {
+		return nil
+	}
+	return driver.FilterWheelConnect()
+}
+
+// FilterWheelDisconnect releases the filter wheel.  It is sent even if we don't think the wheel
+// is connected, since it may have been connected by an earlier session.
 func (driver *TheSkyDriverInstance) FilterWheelDisconnect() error {
-	fmt.Println("FilterWheelDisconnect STUB")
-	return errors.New("FilterWheelDisconnect not implemented yet")
+	if driver.verbosity >= 4 || driver.debug {
+		fmt.Println("TheSkyDriverInstance/FilterWheelDisconnect()")
+	}
+	var message strings.Builder
+	message.WriteString("result = ccdsoftCamera.filterWheelDisconnect();\n")
+	message.WriteString("var out;\n")
+	message.WriteString("out = result + \"\\n\";\n")
+
+	responseCode, err := driver.sendCommandIntReply(message.String())
+	if err != nil {
+		fmt.Println("FilterWheelDisconnect error from driver:", err)
+		return err
+	}
+	if responseCode != 0 {
+		return &TheSkyXError{Code: responseCode, Message: "FilterWheelDisconnect failed"}
+	}
+
+	driver.filterWheelConnected = false
+	return nil
 }
 
 //	Retrieve a list of filter names from the camera
@@ -656,6 +1333,299 @@ func (driver *TheSkyDriverInstance) FilterNames() ([]string, error) {
 	return filterNames, nil
 }
 
+// SetFilterSlot connects the filter wheel if need be and starts it moving to the given
+// (one-based) slot
+func (driver *TheSkyDriverInstance) SetFilterSlot(filterSlot int) error {
+	if driver.verbosity >= 4 || driver.debug {
+		fmt.Println("TheSkyDriverInstance/SetFilterSlot ", filterSlot)
+	}
+	if err := driver.ensureFilterWheelConnected(); err != nil {
+		fmt.Println("SetFilterSlot error from driver connecting filter wheel:", err)
+		return err
+	}
+	var message strings.Builder
+	// Note: filter slot is zero-based so we subtract one
+	message.WriteString(fmt.Sprintf("ccdsoftCamera.FilterIndexZeroBased=%d;\n", filterSlot-1))
+	message.WriteString("var Out;\n")
+	message.WriteString("Out=0;\n")
+
+	if err := driver.sendCommandIgnoreReply(message.String()); err != nil {
+		fmt.Println("SetFilterSlot error from driver:", err)
+		return err
+	}
+	return nil
+}
+
+// GetFilterSlot returns the (one-based) slot the filter wheel is at.  Some wheels return from
+// the slot assignment before they have stopped, reporting index -1 until they arrive; we
+// return that as FilterSlotMoving.
+func (driver *TheSkyDriverInstance) GetFilterSlot() (int, error) {
+	if driver.verbosity >= 5 || driver.debug {
+		fmt.Println("TheSkyDriverInstance/GetFilterSlot()")
+	}
+	if err := driver.ensureFilterWheelConnected(); err != nil {
+		fmt.Println("GetFilterSlot error from driver connecting filter wheel:", err)
+		return 0, err
+	}
+	var message strings.Builder
+	message.WriteString("var index = ccdsoftCamera.FilterIndexZeroBased;\n")
+	message.WriteString("var Out;\n")
+	message.WriteString("Out=index+\"\\n\";\n")
+
+	index, err := driver.sendCommandIntReply(message.String())
+	if err != nil {
+		fmt.Println("GetFilterSlot error from driver:", err)
+		return 0, err
+	}
+	if index < 0 {
+		return FilterSlotMoving, nil
+	}
+	return index + 1, nil
+}
+
+// ConnectTelescope asks TheSkyX to connect to the mount
+func (driver *TheSkyDriverInstance) ConnectTelescope() error {
+	if driver.verbosity >= 4 || driver.debug {
+		fmt.Println("TheSkyDriverInstance/ConnectTelescope()")
+	}
+	var commands strings.Builder
+	commands.WriteString("sky6RASCOMTele.Connect();\n")
+	commands.WriteString("var Out;\n")
+	commands.WriteString("Out=0;\n")
+
+	if err := driver.sendCommandIgnoreReply(commands.String()); err != nil {
+		fmt.Println("ConnectTelescope error from driver:", err)
+		return err
+	}
+	driver.telescopeConnected = true
+	return nil
+}
+
+// StartSlewToAltAz starts the mount slewing to the given altitude and azimuth, in degrees, and
+// returns without waiting for the slew to finish.  Use IsSlewComplete to find out when it has.
+func (driver *TheSkyDriverInstance) StartSlewToAltAz(altitude float64, azimuth float64) error {
+	if driver.verbosity >= 4 || driver.debug {
+		fmt.Println("TheSkyDriverInstance/StartSlewToAltAz ", altitude, azimuth)
+	}
+	if !driver.telescopeConnected {
+		return errors.New("TheSkyDriverInstance/StartSlewToAltAz: Telescope not connected")
+	}
+	var message strings.Builder
+	message.WriteString("sky6RASCOMTele.Asynchronous=true;\n") // Async (don't wait)
+	message.WriteString(fmt.Sprintf("sky6RASCOMTele.SlewToAzAlt(%.4f, %.4f, \"\");\n", azimuth, altitude))
+	message.WriteString("var Out;\n")
+	message.WriteString("Out=0;\n")
+
+	if err := driver.sendCommandIgnoreReply(message.String()); err != nil {
+		fmt.Println("StartSlewToAltAz error from driver:", err)
+		return err
+	}
+	return nil
+}
+
+// IsSlewComplete polls the server to see if the mount has finished its slew (or park)
+func (driver *TheSkyDriverInstance) IsSlewComplete() (bool, error) {
+	if driver.verbosity >= 5 || driver.debug {
+		fmt.Println("TheSkyDriverInstance/IsSlewComplete()")
+	}
+	if !driver.telescopeConnected {
+		return false, errors.New("TheSkyDriverInstance/IsSlewComplete: Telescope not connected")
+	}
+	var message strings.Builder
+	message.WriteString("var complete = sky6RASCOMTele.IsSlewComplete;\n")
+	message.WriteString("var Out;\n")
+	message.WriteString("Out=complete+\"\\n\";\n")
+
+	responseCode, err := driver.sendCommandIntReply(message.String())
+	if err != nil {
+		fmt.Println("IsSlewComplete error from driver:", err)
+		return false, err
+	}
+	return responseCode != 0, nil
+}
+
+// AbortSlew stops the mount's slew in progress, if any
+func (driver *TheSkyDriverInstance) AbortSlew() error {
+	if driver.verbosity >= 4 || driver.debug {
+		fmt.Println("TheSkyDriverInstance/AbortSlew()")
+	}
+	if !driver.telescopeConnected {
+		return errors.New("TheSkyDriverInstance/AbortSlew: Telescope not connected")
+	}
+	var message strings.Builder
+	message.WriteString("sky6RASCOMTele.Abort();\n")
+	message.WriteString("var Out;\n")
+	message.WriteString("Out=0;\n")
+
+	if err := driver.sendCommandIgnoreReply(message.String()); err != nil {
+		fmt.Println("AbortSlew error from driver:", err)
+		return err
+	}
+	return nil
+}
+
+// Directions the mount can be jogged in, as sky6RASCOMTele.Jog expects them
+const (
+	JogNorth = "N"
+	JogSouth = "S"
+	JogEast  = "E"
+	JogWest  = "W"
+)
+
+// StartJog starts the mount moving the given number of arcminutes in the given direction
+// (JogNorth etc.) and returns without waiting.  Use IsSlewComplete to find out when it stops.
+func (driver *TheSkyDriverInstance) StartJog(arcminutes float64, direction string) error {
+	if driver.verbosity >= 4 || driver.debug {
+		fmt.Println("TheSkyDriverInstance/StartJog ", arcminutes, direction)
+	}
+	if !driver.telescopeConnected {
+		return errors.New("TheSkyDriverInstance/StartJog: Telescope not connected")
+	}
+	switch direction {
+	case JogNorth, JogSouth, JogEast, JogWest:
+	default:
+		return fmt.Errorf("TheSkyDriverInstance/StartJog: invalid direction %q", direction)
+	}
+	var message strings.Builder
+	message.WriteString("sky6RASCOMTele.Asynchronous=true;\n") // Async (don't wait)
+	message.WriteString(fmt.Sprintf("sky6RASCOMTele.Jog(%.4f, \"%s\");\n", arcminutes, direction))
+	message.WriteString("var Out;\n")
+	message.WriteString("Out=0;\n")
+
+	if err := driver.sendCommandIgnoreReply(message.String()); err != nil {
+		fmt.Println("StartJog error from driver:", err)
+		return err
+	}
+	return nil
+}
+
+// StartPark starts the mount moving to its park position, without waiting for it to get there.
+// The mount stays connected once parked.
+func (driver *TheSkyDriverInstance) StartPark() error {
+	if driver.verbosity >= 4 || driver.debug {
+		fmt.Println("TheSkyDriverInstance/StartPark()")
+	}
+	if !driver.telescopeConnected {
+		return errors.New("TheSkyDriverInstance/StartPark: Telescope not connected")
+	}
+	var message strings.Builder
+	message.WriteString("sky6RASCOMTele.Asynchronous=true;\n") // Async (don't wait)
+	message.WriteString("sky6RASCOMTele.ParkAndDoNotDisconnect();\n")
+	message.WriteString("var Out;\n")
+	message.WriteString("Out=0;\n")
+
+	if err := driver.sendCommandIgnoreReply(message.String()); err != nil {
+		fmt.Println("StartPark error from driver:", err)
+		return err
+	}
+	return nil
+}
+
+// Unpark releases the mount from its park position so it can slew
+func (driver *TheSkyDriverInstance) Unpark() error {
+	if driver.verbosity >= 4 || driver.debug {
+		fmt.Println("TheSkyDriverInstance/Unpark()")
+	}
+	if !driver.telescopeConnected {
+		return errors.New("TheSkyDriverInstance/Unpark: Telescope not connected")
+	}
+	var message strings.Builder
+	message.WriteString("sky6RASCOMTele.Unpark();\n")
+	message.WriteString("var Out;\n")
+	message.WriteString("Out=0;\n")
+
+	if err := driver.sendCommandIgnoreReply(message.String()); err != nil {
+		fmt.Println("Unpark error from driver:", err)
+		return err
+	}
+	return nil
+}
+
+// SetTracking switches sidereal tracking on or off
+func (driver *TheSkyDriverInstance) SetTracking(on bool) error {
+	if driver.verbosity >= 4 || driver.debug {
+		fmt.Println("TheSkyDriverInstance/SetTracking ", on)
+	}
+	if !driver.telescopeConnected {
+		return errors.New("TheSkyDriverInstance/SetTracking: Telescope not connected")
+	}
+	trackingFlag := 0
+	if on {
+		trackingFlag = 1
+	}
+	var message strings.Builder
+	// Arguments are on/off, ignore the rates (i.e. track at sidereal rate), RA rate, Dec rate
+	message.WriteString(fmt.Sprintf("sky6RASCOMTele.SetTracking(%d, 1, 0, 0);\n", trackingFlag))
+	message.WriteString("var Out;\n")
+	message.WriteString("Out=0;\n")
+
+	if err := driver.sendCommandIgnoreReply(message.String()); err != nil {
+		fmt.Println("SetTracking error from driver:", err)
+		return err
+	}
+	return nil
+}
+
+// GetTelescopePosition asks TheSkyX for the mount's altitude, azimuth, tracking and parked
+// state, all in one packet.  The four values come back tab-separated.
+func (driver *TheSkyDriverInstance) GetTelescopePosition() (TelescopePosition, error) {
+	if driver.verbosity >= 5 || driver.debug {
+		fmt.Println("TheSkyDriverInstance/GetTelescopePosition()")
+	}
+	if !driver.telescopeConnected {
+		return TelescopePosition{}, errors.New("TheSkyDriverInstance/GetTelescopePosition: Telescope not connected")
+	}
+	var message strings.Builder
+	message.WriteString("sky6RASCOMTele.GetAzAlt();\n")
+	message.WriteString("var az = sky6RASCOMTele.dAz;\n")
+	message.WriteString("var alt = sky6RASCOMTele.dAlt;\n")
+	message.WriteString("var tracking = sky6RASCOMTele.IsTracking;\n")
+	message.WriteString("var parked = sky6RASCOMTele.IsParked();\n")
+	message.WriteString("var Out;\n")
+	message.WriteString("Out=alt + \"\\t\" + az + \"\\t\" + tracking + \"\\t\" + parked + \"\\n\";\n")
+
+	responseBlob, err := driver.sendCommandStringReply(message.String())
+	if err != nil {
+		fmt.Println("GetTelescopePosition error from driver:", err)
+		return TelescopePosition{}, err
+	}
+	return parseTelescopePosition(responseBlob)
+}
+
+// parseTelescopePosition interprets the tab-separated reply from GetTelescopePosition.  The
+// flags may come back as true/false or 1/0, depending on the mount driver.
+func parseTelescopePosition(reply string) (TelescopePosition, error) {
+	parts := strings.Split(reply, "\t")
+	if len(parts) != 4 {
+		return TelescopePosition{}, &MalformedReplyError{Reason: "expected 4 telescope position values", Reply: reply}
+	}
+	var numbers [2]float64
+	for i, part := range parts[:2] {
+		number, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return TelescopePosition{}, &MalformedReplyError{Reason: "telescope position is not a number", Reply: reply}
+		}
+		numbers[i] = number
+	}
+	var flags [2]bool
+	for i, part := range parts[2:] {
+		switch strings.ToLower(strings.TrimSpace(part)) {
+		case "true", "1":
+			flags[i] = true
+		case "false", "0":
+			flags[i] = false
+		default:
+			return TelescopePosition{}, &MalformedReplyError{Reason: "telescope state is not a boolean", Reply: reply}
+		}
+	}
+	return TelescopePosition{
+		Altitude: numbers[0],
+		Azimuth:  numbers[1],
+		Tracking: flags[0],
+		Parked:   flags[1],
+	}, nil
+}
+
 //func (driver *TheSkyDriverInstance) xxxxx(args) error {
 //}
 
@@ -5,7 +5,9 @@
 package goTheSkyX
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -33,6 +35,34 @@ func (m *MockTheSkyDriver) EXPECT() *MockTheSkyDriverMockRecorder {
 	return m.recorder
 }
 
+// AbortExposure mocks base method.
+func (m *MockTheSkyDriver) AbortExposure() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AbortExposure")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AbortExposure indicates an expected call of AbortExposure.
+func (mr *MockTheSkyDriverMockRecorder) AbortExposure() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AbortExposure", reflect.TypeOf((*MockTheSkyDriver)(nil).AbortExposure))
+}
+
+// AbortSlew mocks base method.
+func (m *MockTheSkyDriver) AbortSlew() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AbortSlew")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AbortSlew indicates an expected call of AbortSlew.
+func (mr *MockTheSkyDriverMockRecorder) AbortSlew() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AbortSlew", reflect.TypeOf((*MockTheSkyDriver)(nil).AbortSlew))
+}
+
 // Close mocks base method.
 func (m *MockTheSkyDriver) Close() error {
 	m.ctrl.T.Helper()
@@ -75,6 +105,20 @@ func (mr *MockTheSkyDriverMockRecorder) ConnectCamera() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectCamera", reflect.TypeOf((*MockTheSkyDriver)(nil).ConnectCamera))
 }
 
+// ConnectTelescope mocks base method.
+func (m *MockTheSkyDriver) ConnectTelescope() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConnectTelescope")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConnectTelescope indicates an expected call of ConnectTelescope.
+func (mr *MockTheSkyDriverMockRecorder) ConnectTelescope() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectTelescope", reflect.TypeOf((*MockTheSkyDriver)(nil).ConnectTelescope))
+}
+
 // FilterNames mocks base method.
 func (m *MockTheSkyDriver) FilterNames() ([]string, error) {
 	m.ctrl.T.Helper()
@@ -148,6 +192,21 @@ func (mr *MockTheSkyDriverMockRecorder) GetADUValue() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetADUValue", reflect.TypeOf((*MockTheSkyDriver)(nil).GetADUValue))
 }
 
+// GetCameraCapabilities mocks base method.
+func (m *MockTheSkyDriver) GetCameraCapabilities() (CameraCapabilities, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCameraCapabilities")
+	ret0, _ := ret[0].(CameraCapabilities)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCameraCapabilities indicates an expected call of GetCameraCapabilities.
+func (mr *MockTheSkyDriverMockRecorder) GetCameraCapabilities() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCameraCapabilities", reflect.TypeOf((*MockTheSkyDriver)(nil).GetCameraCapabilities))
+}
+
 // GetCameraTemperature mocks base method.
 func (m *MockTheSkyDriver) GetCameraTemperature() (float64, error) {
 	m.ctrl.T.Helper()
@@ -163,6 +222,96 @@ func (mr *MockTheSkyDriverMockRecorder) GetCameraTemperature() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCameraTemperature", reflect.TypeOf((*MockTheSkyDriver)(nil).GetCameraTemperature))
 }
 
+// GetCapturedImageInfo mocks base method.
+func (m *MockTheSkyDriver) GetCapturedImageInfo() (CaptureResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCapturedImageInfo")
+	ret0, _ := ret[0].(CaptureResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCapturedImageInfo indicates an expected call of GetCapturedImageInfo.
+func (mr *MockTheSkyDriverMockRecorder) GetCapturedImageInfo() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCapturedImageInfo", reflect.TypeOf((*MockTheSkyDriver)(nil).GetCapturedImageInfo))
+}
+
+// GetCoolerPower mocks base method.
+func (m *MockTheSkyDriver) GetCoolerPower() (float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCoolerPower")
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCoolerPower indicates an expected call of GetCoolerPower.
+func (mr *MockTheSkyDriverMockRecorder) GetCoolerPower() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCoolerPower", reflect.TypeOf((*MockTheSkyDriver)(nil).GetCoolerPower))
+}
+
+// GetCoolerStatus mocks base method.
+func (m *MockTheSkyDriver) GetCoolerStatus() (CoolerStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCoolerStatus")
+	ret0, _ := ret[0].(CoolerStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCoolerStatus indicates an expected call of GetCoolerStatus.
+func (mr *MockTheSkyDriverMockRecorder) GetCoolerStatus() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCoolerStatus", reflect.TypeOf((*MockTheSkyDriver)(nil).GetCoolerStatus))
+}
+
+// GetFilterSlot mocks base method.
+func (m *MockTheSkyDriver) GetFilterSlot() (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFilterSlot")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFilterSlot indicates an expected call of GetFilterSlot.
+func (mr *MockTheSkyDriverMockRecorder) GetFilterSlot() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFilterSlot", reflect.TypeOf((*MockTheSkyDriver)(nil).GetFilterSlot))
+}
+
+// GetImageStats mocks base method.
+func (m *MockTheSkyDriver) GetImageStats(arg0 float64, arg1 int64) (ImageStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImageStats", arg0, arg1)
+	ret0, _ := ret[0].(ImageStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImageStats indicates an expected call of GetImageStats.
+func (mr *MockTheSkyDriverMockRecorder) GetImageStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageStats", reflect.TypeOf((*MockTheSkyDriver)(nil).GetImageStats), arg0, arg1)
+}
+
+// GetTelescopePosition mocks base method.
+func (m *MockTheSkyDriver) GetTelescopePosition() (TelescopePosition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTelescopePosition")
+	ret0, _ := ret[0].(TelescopePosition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTelescopePosition indicates an expected call of GetTelescopePosition.
+func (mr *MockTheSkyDriverMockRecorder) GetTelescopePosition() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTelescopePosition", reflect.TypeOf((*MockTheSkyDriver)(nil).GetTelescopePosition))
+}
+
 // IsCaptureDone mocks base method.
 func (m *MockTheSkyDriver) IsCaptureDone() (bool, error) {
 	m.ctrl.T.Helper()
@@ -178,6 +327,21 @@ func (mr *MockTheSkyDriverMockRecorder) IsCaptureDone() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsCaptureDone", reflect.TypeOf((*MockTheSkyDriver)(nil).IsCaptureDone))
 }
 
+// IsSlewComplete mocks base method.
+func (m *MockTheSkyDriver) IsSlewComplete() (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsSlewComplete")
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsSlewComplete indicates an expected call of IsSlewComplete.
+func (mr *MockTheSkyDriverMockRecorder) IsSlewComplete() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsSlewComplete", reflect.TypeOf((*MockTheSkyDriver)(nil).IsSlewComplete))
+}
+
 // MeasureDownloadTime mocks base method.
 func (m *MockTheSkyDriver) MeasureDownloadTime(arg0 int) (float64, error) {
 	m.ctrl.T.Helper()
@@ -193,6 +357,62 @@ func (mr *MockTheSkyDriverMockRecorder) MeasureDownloadTime(arg0 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MeasureDownloadTime", reflect.TypeOf((*MockTheSkyDriver)(nil).MeasureDownloadTime), arg0)
 }
 
+// MeasureDownloadTimeContext mocks base method.
+func (m *MockTheSkyDriver) MeasureDownloadTimeContext(arg0 context.Context, arg1 int) (float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MeasureDownloadTimeContext", arg0, arg1)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MeasureDownloadTimeContext indicates an expected call of MeasureDownloadTimeContext.
+func (mr *MockTheSkyDriverMockRecorder) MeasureDownloadTimeContext(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MeasureDownloadTimeContext", reflect.TypeOf((*MockTheSkyDriver)(nil).MeasureDownloadTimeContext), arg0, arg1)
+}
+
+// SaveImage mocks base method.
+func (m *MockTheSkyDriver) SaveImage(arg0, arg1 string, arg2 []FITSKeyword) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveImage", arg0, arg1, arg2)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveImage indicates an expected call of SaveImage.
+func (mr *MockTheSkyDriverMockRecorder) SaveImage(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveImage", reflect.TypeOf((*MockTheSkyDriver)(nil).SaveImage), arg0, arg1, arg2)
+}
+
+// SetAutoSave mocks base method.
+func (m *MockTheSkyDriver) SetAutoSave(arg0 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetAutoSave", arg0)
+}
+
+// SetAutoSave indicates an expected call of SetAutoSave.
+func (mr *MockTheSkyDriverMockRecorder) SetAutoSave(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAutoSave", reflect.TypeOf((*MockTheSkyDriver)(nil).SetAutoSave), arg0)
+}
+
+// SetCoolerSetPoint mocks base method.
+func (m *MockTheSkyDriver) SetCoolerSetPoint(arg0 float64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCoolerSetPoint", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetCoolerSetPoint indicates an expected call of SetCoolerSetPoint.
+func (mr *MockTheSkyDriverMockRecorder) SetCoolerSetPoint(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCoolerSetPoint", reflect.TypeOf((*MockTheSkyDriver)(nil).SetCoolerSetPoint), arg0)
+}
+
 // SetDebug mocks base method.
 func (m *MockTheSkyDriver) SetDebug(arg0 bool) {
 	m.ctrl.T.Helper()
@@ -205,6 +425,70 @@ func (mr *MockTheSkyDriverMockRecorder) SetDebug(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDebug", reflect.TypeOf((*MockTheSkyDriver)(nil).SetDebug), arg0)
 }
 
+// SetFilterSlot mocks base method.
+func (m *MockTheSkyDriver) SetFilterSlot(arg0 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetFilterSlot", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetFilterSlot indicates an expected call of SetFilterSlot.
+func (mr *MockTheSkyDriverMockRecorder) SetFilterSlot(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFilterSlot", reflect.TypeOf((*MockTheSkyDriver)(nil).SetFilterSlot), arg0)
+}
+
+// SetMaxReplySize mocks base method.
+func (m *MockTheSkyDriver) SetMaxReplySize(arg0 int) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxReplySize", arg0)
+}
+
+// SetMaxReplySize indicates an expected call of SetMaxReplySize.
+func (mr *MockTheSkyDriverMockRecorder) SetMaxReplySize(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxReplySize", reflect.TypeOf((*MockTheSkyDriver)(nil).SetMaxReplySize), arg0)
+}
+
+// SetReplyTimeout mocks base method.
+func (m *MockTheSkyDriver) SetReplyTimeout(arg0 time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReplyTimeout", arg0)
+}
+
+// SetReplyTimeout indicates an expected call of SetReplyTimeout.
+func (mr *MockTheSkyDriverMockRecorder) SetReplyTimeout(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReplyTimeout", reflect.TypeOf((*MockTheSkyDriver)(nil).SetReplyTimeout), arg0)
+}
+
+// SetSubframe mocks base method.
+func (m *MockTheSkyDriver) SetSubframe(arg0 Subframe) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSubframe", arg0)
+}
+
+// SetSubframe indicates an expected call of SetSubframe.
+func (mr *MockTheSkyDriverMockRecorder) SetSubframe(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSubframe", reflect.TypeOf((*MockTheSkyDriver)(nil).SetSubframe), arg0)
+}
+
+// SetTracking mocks base method.
+func (m *MockTheSkyDriver) SetTracking(arg0 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTracking", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTracking indicates an expected call of SetTracking.
+func (mr *MockTheSkyDriverMockRecorder) SetTracking(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTracking", reflect.TypeOf((*MockTheSkyDriver)(nil).SetTracking), arg0)
+}
+
 // SetVerbosity mocks base method.
 func (m *MockTheSkyDriver) SetVerbosity(arg0 int) {
 	m.ctrl.T.Helper()
@@ -273,6 +557,48 @@ func (mr *MockTheSkyDriverMockRecorder) StartFlatFrameCapture(arg0, arg1, arg2,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartFlatFrameCapture", reflect.TypeOf((*MockTheSkyDriver)(nil).StartFlatFrameCapture), arg0, arg1, arg2, arg3, arg4)
 }
 
+// StartJog mocks base method.
+func (m *MockTheSkyDriver) StartJog(arg0 float64, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartJog", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StartJog indicates an expected call of StartJog.
+func (mr *MockTheSkyDriverMockRecorder) StartJog(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartJog", reflect.TypeOf((*MockTheSkyDriver)(nil).StartJog), arg0, arg1)
+}
+
+// StartPark mocks base method.
+func (m *MockTheSkyDriver) StartPark() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartPark")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StartPark indicates an expected call of StartPark.
+func (mr *MockTheSkyDriverMockRecorder) StartPark() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartPark", reflect.TypeOf((*MockTheSkyDriver)(nil).StartPark))
+}
+
+// StartSlewToAltAz mocks base method.
+func (m *MockTheSkyDriver) StartSlewToAltAz(arg0, arg1 float64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartSlewToAltAz", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StartSlewToAltAz indicates an expected call of StartSlewToAltAz.
+func (mr *MockTheSkyDriverMockRecorder) StartSlewToAltAz(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartSlewToAltAz", reflect.TypeOf((*MockTheSkyDriver)(nil).StartSlewToAltAz), arg0, arg1)
+}
+
 // StopCooling mocks base method.
 func (m *MockTheSkyDriver) StopCooling() error {
 	m.ctrl.T.Helper()
@@ -286,3 +612,17 @@ func (mr *MockTheSkyDriverMockRecorder) StopCooling() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopCooling", reflect.TypeOf((*MockTheSkyDriver)(nil).StopCooling))
 }
+
+// Unpark mocks base method.
+func (m *MockTheSkyDriver) Unpark() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unpark")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unpark indicates an expected call of Unpark.
+func (mr *MockTheSkyDriverMockRecorder) Unpark() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unpark", reflect.TypeOf((*MockTheSkyDriver)(nil).Unpark))
+}
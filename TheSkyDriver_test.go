@@ -0,0 +1,203 @@
+package goTheSkyX
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/RMcDOttawa/goTheSkyX/fakeTheSkyX"
+	"github.com/stretchr/testify/require"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tests of the low-level driver, run against the in-process fake TheSkyX server
+
+// startFakeForDriver starts a fake server and a driver connected to it, with the camera connected
+func startFakeForDriver(t *testing.T) (*fakeTheSkyX.FakeTheSkyServer, TheSkyDriver) {
+	fake := fakeTheSkyX.NewFakeTheSkyServer(false, 0)
+	require.Nil(t, fake.Start(), "Unable to start fake server")
+	driver := NewTheSkyDriver(false, 0)
+	require.Nil(t, driver.Connect("localhost", fake.Port()), "Unable to connect driver")
+	require.Nil(t, driver.ConnectCamera(), "Unable to connect camera")
+	return fake, driver
+}
+
+func TestDriverConnection(t *testing.T) {
+
+	t.Run("commands share one connection", func(t *testing.T) {
+		fake, driver := startFakeForDriver(t)
+		defer fake.Close()
+		defer driver.Close()
+
+		for i := 0; i < 5; i++ {
+			_, err := driver.GetCameraTemperature()
+			require.Nil(t, err, "Unable to get temperature")
+		}
+		require.Equal(t, 1, fake.ConnectionCount(), "Expected a single persistent connection")
+		require.Equal(t, 6, fake.PacketCount())
+	})
+
+	t.Run("reconnects after connection is dropped", func(t *testing.T) {
+		fake, driver := startFakeForDriver(t)
+		defer fake.Close()
+		defer driver.Close()
+
+		_, err := driver.GetCameraTemperature()
+		require.Nil(t, err, "Unable to get temperature")
+		fake.DropConnections()
+		temperature, err := driver.GetCameraTemperature()
+		require.Nil(t, err, "Driver did not recover from dropped connection")
+		require.Equal(t, 20.0, temperature)
+		require.Equal(t, 2, fake.ConnectionCount())
+	})
+
+	t.Run("connect reports unreachable server", func(t *testing.T) {
+		// Find a port with nothing listening on it
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.Nil(t, err)
+		port := listener.Addr().(*net.TCPAddr).Port
+		_ = listener.Close()
+
+		driver := NewTheSkyDriver(false, 0)
+		err = driver.Connect("127.0.0.1", port)
+		require.NotNil(t, err, "Connect should have failed")
+		require.True(t, errors.Is(err, ErrServerUnreachable), "Expected ErrServerUnreachable, got %v", err)
+	})
+
+	t.Run("commands fail after close", func(t *testing.T) {
+		fake, driver := startFakeForDriver(t)
+		defer fake.Close()
+
+		require.Nil(t, driver.Close())
+		_, err := driver.GetCameraTemperature()
+		require.NotNil(t, err, "Expected error using closed driver")
+	})
+}
+
+func TestDriverContext(t *testing.T) {
+
+	t.Run("deadline interrupts a slow download measurement", func(t *testing.T) {
+		fake := fakeTheSkyX.NewFakeTheSkyServer(false, 0)
+		fake.SetDownloadTime(3.0)
+		require.Nil(t, fake.Start(), "Unable to start fake server")
+		defer fake.Close()
+		driver := NewTheSkyDriver(false, 0)
+		require.Nil(t, driver.Connect("localhost", fake.Port()))
+		defer driver.Close()
+		require.Nil(t, driver.ConnectCamera())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		started := time.Now()
+		_, err := driver.MeasureDownloadTimeContext(ctx, 1)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Less(t, time.Since(started), 2*time.Second, "Deadline did not interrupt the wait")
+
+		// The driver recovers with a fresh connection for the next command
+		_, err = driver.GetCameraTemperature()
+		require.Nil(t, err, "Driver did not recover after cancelled command")
+	})
+}
+
+func TestDriverReplies(t *testing.T) {
+
+	t.Run("reply split across several segments", func(t *testing.T) {
+		fake := fakeTheSkyX.NewFakeTheSkyServer(false, 0)
+		fake.SetReplyChunking(3, 2*time.Millisecond)
+		require.Nil(t, fake.Start(), "Unable to start fake server")
+		defer fake.Close()
+		driver := NewTheSkyDriver(false, 0)
+		require.Nil(t, driver.Connect("localhost", fake.Port()))
+		defer driver.Close()
+		require.Nil(t, driver.ConnectCamera())
+
+		temperature, err := driver.GetCameraTemperature()
+		require.Nil(t, err, "Unable to read split reply")
+		require.Equal(t, 20.0, temperature)
+		names, err := driver.FilterNames()
+		require.Nil(t, err, "Unable to read split reply")
+		require.Equal(t, "Red", names[0])
+	})
+
+	t.Run("reply larger than one buffer", func(t *testing.T) {
+		fake := fakeTheSkyX.NewFakeTheSkyServer(false, 0)
+		var manyNames []string
+		for i := 0; i < 300; i++ {
+			manyNames = append(manyNames, fmt.Sprintf("Filter number %03d with a long name", i))
+		}
+		fake.SetFilterNames(manyNames)
+		require.Nil(t, fake.Start(), "Unable to start fake server")
+		defer fake.Close()
+		driver := NewTheSkyDriver(false, 0)
+		require.Nil(t, driver.Connect("localhost", fake.Port()))
+		defer driver.Close()
+
+		names, err := driver.FilterNames()
+		require.Nil(t, err, "Unable to read large reply")
+		require.Equal(t, manyNames, names)
+	})
+
+	t.Run("reply exceeding maximum size", func(t *testing.T) {
+		fake, driver := startFakeForDriver(t)
+		defer fake.Close()
+		defer driver.Close()
+		driver.SetMaxReplySize(40)
+
+		_, err := driver.FilterNames()
+		require.ErrorIs(t, err, ErrReplyTooLarge)
+		// Small replies still work afterwards
+		_, err = driver.GetCameraTemperature()
+		require.Nil(t, err, "Driver did not recover after oversized reply")
+	})
+
+	t.Run("reply without terminator times out", func(t *testing.T) {
+		fake, driver := startFakeForDriver(t)
+		defer fake.Close()
+		defer driver.Close()
+		driver.SetReplyTimeout(200 * time.Millisecond)
+		fake.SetReplyFilter(func(reply string) string {
+			return strings.Split(reply, "|")[0]
+		})
+
+		_, err := driver.GetCameraTemperature()
+		require.ErrorIs(t, err, ErrReplyTimeout)
+	})
+
+	t.Run("connection dropped after a malformed reply", func(t *testing.T) {
+		fake, driver := startFakeForDriver(t)
+		defer fake.Close()
+		defer driver.Close()
+		// The first reply arrives with a stale reply in front of it; later ones are good
+		replies := 0
+		fake.SetReplyFilter(func(reply string) string {
+			replies++
+			if replies == 1 {
+				return "-99\n|No error. Error = 0." + reply
+			}
+			return reply
+		})
+
+		_, err := driver.GetCameraTemperature()
+		var malformed *MalformedReplyError
+		require.ErrorAs(t, err, &malformed)
+		temperature, err := driver.GetCameraTemperature()
+		require.Nil(t, err, "Driver did not recover after malformed reply")
+		require.Equal(t, 20.0, temperature)
+		require.Equal(t, 2, fake.ConnectionCount(), "Connection should have been replaced")
+	})
+
+	t.Run("malformed download time reply", func(t *testing.T) {
+		fake, driver := startFakeForDriver(t)
+		defer fake.Close()
+		defer driver.Close()
+		fake.SetReplyFilter(func(reply string) string {
+			return "12.5\n|No error. Error = 0."
+		})
+
+		_, err := driver.MeasureDownloadTime(1)
+		var malformed *MalformedReplyError
+		require.ErrorAs(t, err, &malformed)
+	})
+}
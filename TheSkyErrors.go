@@ -0,0 +1,117 @@
+package goTheSkyX
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//	Errors reported by TheSkyX itself.  Every reply ends with an error line such as
+//		"TypeError: Process aborted. Error = 212."
+//	and the number is one of the codes in TheSkyX's sberrorx.h (see "Scripting TheSky notes.txt").
+//	We parse that into a TheSkyXError so callers can tell the failures apart, e.g.
+//		if errors.Is(err, ErrCannotColorGrab) { ... no filter wheel ... }
+//	Only some codes are given names: the general device and communication codes (200 to 219)
+//	and the others this library checks for.  The rest, including most camera and filter wheel
+//	codes, are reported by number alone.
+
+// TheSkyXError is an error reported by the TheSkyX server, with its numeric code
+type TheSkyXError struct {
+	Code    int
+	Message string
+}
+
+// Sentinel errors for the codes this library needs to distinguish.  Compare with errors.Is,
+// which matches any TheSkyXError with the same code, whatever its message.
+var (
+	ErrCommNoLink        = &TheSkyXError{Code: 200}
+	ErrCommandFailed     = &TheSkyXError{Code: 206}
+	ErrAbortedProcess    = &TheSkyXError{Code: 212}
+	ErrNoLink            = &TheSkyXError{Code: 215}
+	ErrDeviceParked      = &TheSkyXError{Code: 216}
+	ErrCommandInProgress = &TheSkyXError{Code: 219}
+	ErrCannotColorGrab   = &TheSkyXError{Code: 1166}
+)
+
+// theSkyXErrorNames gives the sberrorx.h names for the general device and communication codes,
+// and the others this library checks for.  It is not the whole header.
+var theSkyXErrorNames = map[int]string{
+	0:    "ERR_NOERROR",
+	200:  "ERR_COMMNOLINK",
+	201:  "ERR_COMMOPENING",
+	202:  "ERR_COMMSETTINGS",
+	203:  "ERR_NORESPONSE",
+	205:  "ERR_MEMORY",
+	206:  "ERR_CMDFAILED",
+	207:  "ERR_DATAOUT",
+	208:  "ERR_TXTIMEOUT",
+	209:  "ERR_RXTIMEOUT",
+	210:  "ERR_POSTMESSAGE",
+	211:  "ERR_POINTER",
+	212:  "ERR_ABORTEDPROCESS",
+	213:  "ERR_AUTOTERMINATE",
+	214:  "ERR_INTERNETSETTINGS",
+	215:  "ERR_NOLINK",
+	216:  "ERR_DEVICEPARKED",
+	217:  "ERR_DRIVERNOTFOUND",
+	218:  "ERR_LIMITSEXCEEDED",
+	219:  "ERR_COMMANDINPROGRESS",
+	1166: "ERR_CANNOT_COLORGRAB",
+}
+
+// unknownErrorCode is used when a reply's error line has no "Error = N" code we can parse
+const unknownErrorCode = -1
+
+// errorLinePattern picks the message and code out of a reply's error line
+var errorLinePattern = regexp.MustCompile(`^(?s)(.*?)\s*Error = (-?\d+)\.?\s*$`)
+
+func (e *TheSkyXError) Error() string {
+	description := fmt.Sprintf("TheSkyX error %d", e.Code)
+	if name := e.Name(); name != "" {
+		description += " (" + name + ")"
+	}
+	if e.Message == "" {
+		return description
+	}
+	return description + ": " + e.Message
+}
+
+// Is makes errors.Is match on the error code alone
+func (e *TheSkyXError) Is(target error) bool {
+	targetError, ok := target.(*TheSkyXError)
+	return ok && targetError.Code == e.Code
+}
+
+// Name returns the sberrorx.h name of the error code if it is one of the codes we name (see
+// above), or "" if not
+func (e *TheSkyXError) Name() string {
+	if e.Code == unknownErrorCode {
+		return "no error code given"
+	}
+	return theSkyXErrorNames[e.Code]
+}
+
+// parseErrorLine interprets the error line from a reply.  It returns nil if the line reports
+// no error, otherwise a *TheSkyXError.
+func parseErrorLine(errorLine string) error {
+	errorLine = strings.TrimSpace(errorLine)
+	if errorLine == "" {
+		return nil
+	}
+	match := errorLinePattern.FindStringSubmatch(errorLine)
+	if match == nil {
+		if strings.HasPrefix(strings.ToLower(errorLine), "no error.") {
+			return nil
+		}
+		return &TheSkyXError{Code: unknownErrorCode, Message: errorLine}
+	}
+	code, err := strconv.Atoi(match[2])
+	if err != nil {
+		return &TheSkyXError{Code: unknownErrorCode, Message: errorLine}
+	}
+	if code == 0 {
+		return nil
+	}
+	return &TheSkyXError{Code: code, Message: match[1]}
+}
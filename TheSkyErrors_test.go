@@ -0,0 +1,80 @@
+package goTheSkyX
+
+import (
+	"errors"
+	"github.com/RMcDOttawa/goTheSkyX/fakeTheSkyX"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestErrorLineParsing(t *testing.T) {
+	t.Run("no error", func(t *testing.T) {
+		require.Nil(t, parseErrorLine("No error. Error = 0."))
+		require.Nil(t, parseErrorLine(""))
+	})
+
+	t.Run("known error code", func(t *testing.T) {
+		err := parseErrorLine("TypeError: Process aborted. Error = 212.")
+		require.True(t, errors.Is(err, ErrAbortedProcess), "Expected ErrAbortedProcess, got %v", err)
+		require.False(t, errors.Is(err, ErrNoLink))
+		var theSkyXError *TheSkyXError
+		require.True(t, errors.As(err, &theSkyXError))
+		require.Equal(t, 212, theSkyXError.Code)
+		require.Equal(t, "TypeError: Process aborted.", theSkyXError.Message)
+		require.Equal(t, "ERR_ABORTEDPROCESS", theSkyXError.Name())
+	})
+
+	t.Run("error code without a name", func(t *testing.T) {
+		err := parseErrorLine("Something odd. Error = 4242.")
+		var theSkyXError *TheSkyXError
+		require.True(t, errors.As(err, &theSkyXError))
+		require.Equal(t, 4242, theSkyXError.Code)
+		require.Equal(t, "", theSkyXError.Name())
+		require.Equal(t, "TheSkyX error 4242: Something odd.", err.Error())
+	})
+
+	t.Run("error line without a code", func(t *testing.T) {
+		err := parseErrorLine("Something went wrong")
+		var theSkyXError *TheSkyXError
+		require.True(t, errors.As(err, &theSkyXError))
+		require.Equal(t, unknownErrorCode, theSkyXError.Code)
+		require.Equal(t, "no error code given", theSkyXError.Name())
+	})
+}
+
+func TestDriverErrorCodes(t *testing.T) {
+	t.Run("no filter wheel reported as ERR_CANNOT_COLORGRAB", func(t *testing.T) {
+		fake := fakeTheSkyX.NewFakeTheSkyServer(false, 0)
+		fake.SetHasFilterWheel(false)
+		require.Nil(t, fake.Start(), "Unable to start fake server")
+		defer fake.Close()
+		driver := NewTheSkyDriver(false, 0)
+		require.Nil(t, driver.Connect("localhost", fake.Port()))
+		defer driver.Close()
+
+		err := driver.FilterWheelConnect()
+		require.ErrorIs(t, err, ErrCannotColorGrab)
+	})
+
+	t.Run("every code the fake reports has a name", func(t *testing.T) {
+		fake := fakeTheSkyX.NewFakeTheSkyServer(false, 0)
+		require.Nil(t, fake.Start(), "Unable to start fake server")
+		defer fake.Close()
+		driver := NewTheSkyDriver(false, 0)
+		require.Nil(t, driver.Connect("localhost", fake.Port()))
+		defer driver.Close()
+
+		require.Nil(t, driver.ConnectCamera())
+		_, err := driver.GetCapturedImageInfo()
+		require.ErrorIs(t, err, ErrCommandFailed, "No image yet")
+		require.Nil(t, driver.ConnectTelescope())
+		require.Nil(t, driver.StartPark())
+		err = driver.StartSlewToAltAz(45.0, 180.0)
+		require.ErrorIs(t, err, ErrDeviceParked)
+
+		for _, code := range fakeTheSkyX.ErrorCodes() {
+			theSkyXError := &TheSkyXError{Code: code}
+			require.NotEqual(t, "", theSkyXError.Name(), "Fake reports code %d", code)
+		}
+	})
+}
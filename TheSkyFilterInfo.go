@@ -0,0 +1,243 @@
+package goTheSkyX
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"regexp"
+	"strings"
+)
+
+//	Filter metadata.  Each rig names its filters differently ("Ha", "H-alpha 7nm", "Hydrogen"),
+//	so as well as the name we infer the filter's band, and a plan can ask for a filter by band
+//	and have it resolved to the right slot on whichever rig it runs on.  Anything the name
+//	doesn't tell us (or gets wrong) can be given in a local override file:
+//
+//		filters:
+//		  - slot: 5             # identify the filter by slot ...
+//		    band: Ha
+//		    focusOffset: -120   # focuser steps relative to the reference filter
+//		    flatExposure: 12.5  # seconds
+//		  - name: Clear         # ... or by its name in TheSkyX
+//		    band: L
+//		    aliases: [Lum, Luminance]
+
+// FilterBand is the part of the spectrum a filter passes
+type FilterBand string
+
+const (
+	BandUnknown   FilterBand = ""
+	BandLuminance FilterBand = "L"
+	BandRed       FilterBand = "R"
+	BandGreen     FilterBand = "G"
+	BandBlue      FilterBand = "B"
+	BandHAlpha    FilterBand = "Ha"
+	BandOIII      FilterBand = "OIII"
+	BandSII       FilterBand = "SII"
+)
+
+// FilterInfo describes the filter in one slot of the filter wheel
+type FilterInfo struct {
+	Slot                int        // One-based
+	RawName             string     // Name as configured in TheSkyX
+	Name                string     // Trimmed and lower case, as returned by FilterNames
+	Band                FilterBand // Inferred from the name, unless overridden
+	Aliases             []string   // Other names the filter can be asked for by
+	FocusOffset         int        // Focuser steps relative to the reference filter; 0 if not known
+	DefaultFlatExposure float64    // Seconds; 0 if not known
+}
+
+// FilterOverride gives metadata for one filter, identified by Slot or (if Slot is 0) by Name
+type FilterOverride struct {
+	Slot         int      `yaml:"slot"`
+	Name         string   `yaml:"name"`
+	Band         string   `yaml:"band"`
+	Aliases      []string `yaml:"aliases"`
+	FocusOffset  *int     `yaml:"focusOffset"`
+	FlatExposure *float64 `yaml:"flatExposure"`
+}
+
+// FilterOverrides is the contents of a filter override file
+type FilterOverrides []FilterOverride
+
+// filterBandNames maps the names filters are commonly given, with punctuation and spaces
+// removed, to their bands
+var filterBandNames = map[string]FilterBand{
+	"l": BandLuminance, "lum": BandLuminance, "luminance": BandLuminance, "c": BandLuminance, "clear": BandLuminance,
+	"r": BandRed, "red": BandRed,
+	"g": BandGreen, "green": BandGreen,
+	"b": BandBlue, "blue": BandBlue,
+	"h": BandHAlpha, "ha": BandHAlpha, "halpha": BandHAlpha, "hydrogen": BandHAlpha, "hydrogenalpha": BandHAlpha,
+	"o": BandOIII, "o3": BandOIII, "oiii": BandOIII, "oxygen": BandOIII, "oxygeniii": BandOIII,
+	"s": BandSII, "s2": BandSII, "sii": BandSII, "sulfur": BandSII, "sulphur": BandSII, "sulfurii": BandSII, "sulphurii": BandSII,
+}
+
+// nonAlphanumeric and bandwidthSuffix are used to reduce a name like "H-alpha 7nm" to "halpha"
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+var bandwidthSuffix = regexp.MustCompile(`\d+nm$`)
+
+// InferFilterBand guesses a filter's band from its name, returning BandUnknown if it can't
+func InferFilterBand(name string) FilterBand {
+	compact := nonAlphanumeric.ReplaceAllString(strings.ToLower(name), "")
+	compact = bandwidthSuffix.ReplaceAllString(compact, "")
+	return filterBandNames[compact]
+}
+
+// filterInfoFromNames makes a FilterInfo for each named slot.  Unlike FilterNames, blank slots
+// are skipped rather than ending the list, so later slots keep their numbers.
+func filterInfoFromNames(rawNames []string) []FilterInfo {
+	var filters []FilterInfo
+	for i, rawName := range rawNames {
+		name := strings.ToLower(strings.TrimSpace(rawName))
+		if name == "" {
+			continue
+		}
+		filters = append(filters, FilterInfo{
+			Slot:    i + 1,
+			RawName: rawName,
+			Name:    name,
+			Band:    InferFilterBand(name),
+		})
+	}
+	return filters
+}
+
+// applyFilterOverrides updates the filters with the overrides that match them.  Overrides for
+// filters this rig doesn't have are ignored, so one file can serve several rigs.
+func applyFilterOverrides(filters []FilterInfo, overrides FilterOverrides) {
+	for _, override := range overrides {
+		for i := range filters {
+			filter := &filters[i]
+			if override.Slot != 0 && override.Slot != filter.Slot {
+				continue
+			}
+			if override.Slot == 0 && !strings.EqualFold(strings.TrimSpace(override.Name), filter.Name) {
+				continue
+			}
+			if override.Band != "" {
+				filter.Band = InferFilterBand(override.Band)
+			}
+			filter.Aliases = append(filter.Aliases, override.Aliases...)
+			if override.FocusOffset != nil {
+				filter.FocusOffset = *override.FocusOffset
+			}
+			if override.FlatExposure != nil {
+				filter.DefaultFlatExposure = *override.FlatExposure
+			}
+		}
+	}
+}
+
+// resolveFilter finds the filter asked for by name.  It tries the filters' names, then their
+// aliases, then their bands, so "Ha" finds a filter called "H-alpha 7nm".  All matching
+// ignores case.  An unknown name gives an UnknownFilterError.
+func resolveFilter(filters []FilterInfo, filterName string) (FilterInfo, error) {
+	wanted := strings.ToLower(strings.TrimSpace(filterName))
+	for _, filter := range filters {
+		if filter.Name == wanted {
+			return filter, nil
+		}
+	}
+	for _, filter := range filters {
+		for _, alias := range filter.Aliases {
+			if strings.EqualFold(strings.TrimSpace(alias), wanted) {
+				return filter, nil
+			}
+		}
+	}
+	if band := InferFilterBand(wanted); band != BandUnknown {
+		for _, filter := range filters {
+			if filter.Band == band {
+				return filter, nil
+			}
+		}
+	}
+	unknown := &UnknownFilterError{Name: filterName}
+	for _, filter := range filters {
+		unknown.Available = append(unknown.Available, filter.Name)
+	}
+	return FilterInfo{}, unknown
+}
+
+// filterOverrideFile is the layout of a filter override file
+type filterOverrideFile struct {
+	Filters FilterOverrides `yaml:"filters"`
+}
+
+// LoadFilterOverrides reads a YAML or JSON filter override file
+func LoadFilterOverrides(path string) (FilterOverrides, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFilterOverrides(data)
+}
+
+// ParseFilterOverrides interprets the contents of a filter override file
+func ParseFilterOverrides(data []byte) (FilterOverrides, error) {
+	var file filterOverrideFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("filter override file: %w", err)
+	}
+	for i, override := range file.Filters {
+		if (override.Slot == 0) == (strings.TrimSpace(override.Name) == "") {
+			return nil, fmt.Errorf("filter override file: entry %d needs either a slot or a name", i+1)
+		}
+		if override.Slot < 0 {
+			return nil, fmt.Errorf("filter override file: entry %d has invalid slot %d", i+1, override.Slot)
+		}
+		if override.Band != "" && InferFilterBand(override.Band) == BandUnknown {
+			return nil, fmt.Errorf("filter override file: entry %d has unknown band %q", i+1, override.Band)
+		}
+		if override.FlatExposure != nil && *override.FlatExposure <= 0 {
+			return nil, fmt.Errorf("filter override file: entry %d flat exposure must be positive", i+1)
+		}
+	}
+	return file.Filters, nil
+}
+
+// SetFilterOverrides sets the local filter metadata applied by FilterDetails
+func (service *TheSkyServiceInstance) SetFilterOverrides(overrides FilterOverrides) {
+	service.filterOverrides = overrides
+}
+
+// FilterDetails returns a FilterInfo for each named slot in the filter wheel, from TheSkyX's
+// filter names plus any overrides set with SetFilterOverrides
+func (service *TheSkyServiceInstance) FilterDetails() ([]FilterInfo, error) {
+	return service.FilterDetailsContext(context.Background())
+}
+
+func (service *TheSkyServiceInstance) FilterDetailsContext(ctx context.Context) ([]FilterInfo, error) {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Println("TheSkyServiceInstance/FilterDetails ")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	rawNames, err := service.driverFor(ctx).FilterNames()
+	if err != nil {
+		fmt.Println("FilterDetails error from driver retrieving filter names:", err)
+		return nil, err
+	}
+	filters := filterInfoFromNames(rawNames)
+	applyFilterOverrides(filters, service.filterOverrides)
+	return filters, nil
+}
+
+// ResolveFilter finds the filter asked for by name, alias or band (e.g. "Ha"), ignoring case.
+// An unknown name gives an UnknownFilterError.
+func (service *TheSkyServiceInstance) ResolveFilter(filterName string) (FilterInfo, error) {
+	return service.ResolveFilterContext(context.Background(), filterName)
+}
+
+func (service *TheSkyServiceInstance) ResolveFilterContext(ctx context.Context, filterName string) (FilterInfo, error) {
+	filters, err := service.FilterDetailsContext(ctx)
+	if err != nil {
+		return FilterInfo{}, err
+	}
+	return resolveFilter(filters, filterName)
+}
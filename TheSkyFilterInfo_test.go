@@ -0,0 +1,116 @@
+package goTheSkyX
+
+import (
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilterInfo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("infer band from common names", func(t *testing.T) {
+		cases := map[string]FilterBand{
+			"Luminance": BandLuminance, "Clear": BandLuminance, "L": BandLuminance,
+			"Red": BandRed, "g": BandGreen, "BLUE": BandBlue,
+			"Ha": BandHAlpha, "H-alpha 7nm": BandHAlpha, "H_Alpha": BandHAlpha,
+			"OIII": BandOIII, "O3 6.5nm": BandOIII, "SII": BandSII, "Sulphur": BandSII,
+			"Dark": BandUnknown, "": BandUnknown,
+		}
+		for name, band := range cases {
+			require.Equal(t, band, InferFilterBand(name), "Band of %q", name)
+		}
+	})
+
+	t.Run("details keep slot numbers past blank names", func(t *testing.T) {
+		service, mockDriver, _ := setUpConnectedMockService(ctrl)
+		mockDriver.EXPECT().FilterNames().Return([]string{" Lum ", "", "H-alpha 7nm", "", ""}, nil)
+
+		filters, err := service.FilterDetails()
+		require.Nil(t, err, "FilterDetails failed")
+		require.Equal(t, []FilterInfo{
+			{Slot: 1, RawName: " Lum ", Name: "lum", Band: BandLuminance},
+			{Slot: 3, RawName: "H-alpha 7nm", Name: "h-alpha 7nm", Band: BandHAlpha},
+		}, filters)
+	})
+
+	t.Run("overrides by slot and name", func(t *testing.T) {
+		service, mockDriver, _ := setUpConnectedMockService(ctrl)
+		overrides, err := ParseFilterOverrides([]byte(`
+filters:
+  - slot: 2
+    band: Ha
+    focusOffset: -120
+    flatExposure: 12.5
+  - name: clear
+    aliases: [Lum]
+  - slot: 9
+    band: SII
+`))
+		require.Nil(t, err, "Unable to parse overrides")
+		service.SetFilterOverrides(overrides)
+		mockDriver.EXPECT().FilterNames().Return([]string{"Clear", "Narrow 1", "Red"}, nil)
+
+		filters, err := service.FilterDetails()
+		require.Nil(t, err, "FilterDetails failed")
+		require.Len(t, filters, 3)
+		require.Equal(t, []string{"Lum"}, filters[0].Aliases)
+		require.Equal(t, BandHAlpha, filters[1].Band)
+		require.Equal(t, -120, filters[1].FocusOffset)
+		require.Equal(t, 12.5, filters[1].DefaultFlatExposure)
+		require.Equal(t, BandRed, filters[2].Band)
+	})
+
+	t.Run("resolve by name, alias and band", func(t *testing.T) {
+		filters := filterInfoFromNames([]string{"Luminance", "Red", "Ha 3nm", "Ha"})
+		filters[0].Aliases = []string{"Clear"}
+
+		filter, err := resolveFilter(filters, "HA")
+		require.Nil(t, err)
+		require.Equal(t, 4, filter.Slot, "Exact name should beat band match")
+		filter, err = resolveFilter(filters, "clear")
+		require.Nil(t, err)
+		require.Equal(t, 1, filter.Slot)
+		filter, err = resolveFilter(filters, "R")
+		require.Nil(t, err)
+		require.Equal(t, 2, filter.Slot)
+		_, err = resolveFilter(filters, "OIII")
+		require.ErrorIs(t, err, ErrUnknownFilter)
+	})
+
+	t.Run("select filter by band", func(t *testing.T) {
+		service, mockDriver, _ := setUpConnectedMockService(ctrl)
+		mockDriver.EXPECT().FilterNames().Return([]string{"Red", "Green", "Blue", "Hydrogen Alpha"}, nil)
+		mockDriver.EXPECT().SetFilterSlot(4).Return(nil)
+		mockDriver.EXPECT().GetFilterSlot().Return(4, nil)
+
+		slot, err := service.SelectFilterByName("Ha")
+		require.Nil(t, err, "SelectFilterByName failed")
+		require.Equal(t, 4, slot)
+	})
+
+	t.Run("load override file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "filters.json")
+		require.Nil(t, os.WriteFile(path, []byte(`{"filters": [{"name": "Ha", "flatExposure": 30}]}`), 0o644))
+		overrides, err := LoadFilterOverrides(path)
+		require.Nil(t, err, "Unable to load overrides")
+		require.Len(t, overrides, 1)
+		require.Equal(t, 30.0, *overrides[0].FlatExposure)
+	})
+
+	t.Run("reject bad override files", func(t *testing.T) {
+		_, err := ParseFilterOverrides([]byte("filters:\n  - band: Ha\n"))
+		require.ErrorContains(t, err, "entry 1 needs either a slot or a name")
+		_, err = ParseFilterOverrides([]byte("filters:\n  - slot: 1\n    name: Red\n"))
+		require.ErrorContains(t, err, "entry 1 needs either a slot or a name")
+		_, err = ParseFilterOverrides([]byte("filters:\n  - slot: 1\n    band: Infrared\n"))
+		require.ErrorContains(t, err, "unknown band")
+		_, err = ParseFilterOverrides([]byte("filters:\n  - slot: 1\n    offset: 3\n"))
+		require.NotNil(t, err, "Expected unknown key to be rejected")
+		_, err = LoadFilterOverrides(filepath.Join(t.TempDir(), "missing.yaml"))
+		require.NotNil(t, err, "Expected error for missing file")
+	})
+}
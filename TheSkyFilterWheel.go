@@ -0,0 +1,175 @@
+package goTheSkyX
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+//	Selecting filters outside of flat frame capture, e.g. to put an opaque filter in place for
+//	darks.  Filters can be chosen by (one-based) slot or by name; names are resolved as
+//	described in TheSkyFilterInfo.go.
+
+// UnknownFilterError is returned when a filter name is not one of the filter wheel's names
+type UnknownFilterError struct {
+	Name      string
+	Available []string
+}
+
+// ErrUnknownFilter matches any UnknownFilterError with errors.Is
+var ErrUnknownFilter = &UnknownFilterError{}
+
+func (e *UnknownFilterError) Error() string {
+	return fmt.Sprintf("no filter named %q in filter wheel", e.Name)
+}
+
+// Is makes errors.Is match any UnknownFilterError, whatever the name
+func (e *UnknownFilterError) Is(target error) bool {
+	_, ok := target.(*UnknownFilterError)
+	return ok
+}
+
+// ErrFilterWheelTimeout is returned (wrapped) when the filter wheel doesn't stop in time
+var ErrFilterWheelTimeout = errors.New("timed out waiting for filter wheel")
+
+// filterWheelPollingSeconds is how often we check whether the filter wheel has stopped
+const filterWheelPollingSeconds = 1
+
+// defaultFilterWheelTimeoutSeconds is how long SelectFilter waits for the wheel to stop
+const defaultFilterWheelTimeoutSeconds = 60
+
+// SelectFilter moves the filter wheel to the given (one-based) slot and waits for it to stop
+func (service *TheSkyServiceInstance) SelectFilter(filterSlot int) error {
+	return service.SelectFilterContext(context.Background(), filterSlot)
+}
+
+func (service *TheSkyServiceInstance) SelectFilterContext(ctx context.Context, filterSlot int) error {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Printf("TheSkyServiceInstance/SelectFilter(%d)\n", filterSlot)
+	}
+	if !service.isOpen {
+		return errors.New("TheSkyServiceInstance/SelectFilter: Connection not open")
+	}
+	if filterSlot < 1 {
+		return fmt.Errorf("TheSkyServiceInstance/SelectFilter: invalid filter slot %d", filterSlot)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := service.driverFor(ctx).SetFilterSlot(filterSlot); err != nil {
+		fmt.Println("TheSkyServiceInstance/SelectFilter error from driver:", err)
+		return err
+	}
+	arrivedSlot, err := service.WaitForFilterWheelContext(ctx, defaultFilterWheelTimeoutSeconds)
+	if err != nil {
+		return err
+	}
+	if arrivedSlot != filterSlot {
+		return fmt.Errorf("TheSkyServiceInstance/SelectFilter: filter wheel stopped at slot %d, not %d", arrivedSlot, filterSlot)
+	}
+	return nil
+}
+
+// SelectFilterByName moves the filter wheel to the named filter, waits for it to stop, and
+// returns the filter's slot.  The name is resolved as by ResolveFilter, so may be an alias or
+// a band.  An unknown name gives an UnknownFilterError.
+func (service *TheSkyServiceInstance) SelectFilterByName(filterName string) (int, error) {
+	return service.SelectFilterByNameContext(context.Background(), filterName)
+}
+
+func (service *TheSkyServiceInstance) SelectFilterByNameContext(ctx context.Context, filterName string) (int, error) {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Printf("TheSkyServiceInstance/SelectFilterByName(%s)\n", filterName)
+	}
+	if !service.isOpen {
+		return FilterSlotNoFilter, errors.New("TheSkyServiceInstance/SelectFilterByName: Connection not open")
+	}
+	filter, err := service.ResolveFilterContext(ctx, filterName)
+	if err != nil {
+		fmt.Println("TheSkyServiceInstance/SelectFilterByName error resolving filter:", err)
+		return FilterSlotNoFilter, err
+	}
+	if err := service.SelectFilterContext(ctx, filter.Slot); err != nil {
+		return FilterSlotNoFilter, err
+	}
+	return filter.Slot, nil
+}
+
+// CurrentFilterSlot returns the (one-based) slot the filter wheel is at, or FilterSlotMoving
+// if it is still moving
+func (service *TheSkyServiceInstance) CurrentFilterSlot() (int, error) {
+	return service.CurrentFilterSlotContext(context.Background())
+}
+
+func (service *TheSkyServiceInstance) CurrentFilterSlotContext(ctx context.Context) (int, error) {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Println("TheSkyServiceInstance/CurrentFilterSlot ")
+	}
+	if !service.isOpen {
+		return FilterSlotNoFilter, errors.New("TheSkyServiceInstance/CurrentFilterSlot: Connection not open")
+	}
+	if err := ctx.Err(); err != nil {
+		return FilterSlotNoFilter, err
+	}
+	filterSlot, err := service.driverFor(ctx).GetFilterSlot()
+	if err != nil {
+		fmt.Println("TheSkyServiceInstance/CurrentFilterSlot error from driver:", err)
+		return FilterSlotNoFilter, err
+	}
+	return filterSlot, nil
+}
+
+// WaitForFilterWheel waits until the filter wheel has stopped moving, and returns the slot it
+// stopped at.  If it is still moving after the timeout, ErrFilterWheelTimeout is returned.
+func (service *TheSkyServiceInstance) WaitForFilterWheel(timeoutSeconds int) (int, error) {
+	return service.WaitForFilterWheelContext(context.Background(), timeoutSeconds)
+}
+
+func (service *TheSkyServiceInstance) WaitForFilterWheelContext(ctx context.Context, timeoutSeconds int) (int, error) {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Printf("TheSkyServiceInstance/WaitForFilterWheel(%d)\n", timeoutSeconds)
+	}
+	secondsWaitedSoFar := 0
+	for {
+		filterSlot, err := service.CurrentFilterSlotContext(ctx)
+		if err != nil {
+			return FilterSlotNoFilter, err
+		}
+		if filterSlot != FilterSlotMoving {
+			return filterSlot, nil
+		}
+		if secondsWaitedSoFar >= timeoutSeconds {
+			return FilterSlotNoFilter, fmt.Errorf("%w: still moving after %d seconds", ErrFilterWheelTimeout, secondsWaitedSoFar)
+		}
+		if service.verbosity >= 5 {
+			fmt.Printf("  Filter wheel moving, waiting %d seconds to check again\n", filterWheelPollingSeconds)
+		}
+		if err := service.delayContext(ctx, filterWheelPollingSeconds); err != nil {
+			return FilterSlotNoFilter, err
+		}
+		secondsWaitedSoFar += filterWheelPollingSeconds
+	}
+}
+
+// DisconnectFilterWheel releases the filter wheel, whether it was connected by this session or
+// an earlier one, so other software can use it
+func (service *TheSkyServiceInstance) DisconnectFilterWheel() error {
+	return service.DisconnectFilterWheelContext(context.Background())
+}
+
+func (service *TheSkyServiceInstance) DisconnectFilterWheelContext(ctx context.Context) error {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Println("TheSkyServiceInstance/DisconnectFilterWheel ")
+	}
+	if !service.isOpen {
+		return errors.New("TheSkyServiceInstance/DisconnectFilterWheel: Connection not open")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := service.driverFor(ctx).FilterWheelDisconnect(); err != nil {
+		fmt.Println("TheSkyServiceInstance/DisconnectFilterWheel error from driver:", err)
+		return err
+	}
+	return nil
+}
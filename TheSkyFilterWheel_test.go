@@ -0,0 +1,208 @@
+package goTheSkyX
+
+import (
+	"errors"
+	"github.com/RMcDOttawa/goMockableDelay"
+	"github.com/RMcDOttawa/goTheSkyX/fakeTheSkyX"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestFilterWheelDriver(t *testing.T) {
+
+	t.Run("set and read back filter slot", func(t *testing.T) {
+		fake, driver := startFakeForDriver(t)
+		defer fake.Close()
+		defer driver.Close()
+
+		require.Nil(t, driver.SetFilterSlot(3), "Unable to set filter slot")
+		slot, err := driver.GetFilterSlot()
+		require.Nil(t, err, "Unable to get filter slot")
+		require.Equal(t, 3, slot)
+	})
+
+	t.Run("moving wheel reports FilterSlotMoving", func(t *testing.T) {
+		fake := fakeTheSkyX.NewFakeTheSkyServer(false, 0)
+		simulatedTime := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+		fake.SetClock(func() time.Time { return simulatedTime })
+		fake.SetFilterMoveTime(2.0)
+		require.Nil(t, fake.Start(), "Unable to start fake server")
+		defer fake.Close()
+		driver := NewTheSkyDriver(false, 0)
+		require.Nil(t, driver.Connect("localhost", fake.Port()))
+		defer driver.Close()
+
+		require.Nil(t, driver.SetFilterSlot(4))
+		slot, err := driver.GetFilterSlot()
+		require.Nil(t, err)
+		require.Equal(t, FilterSlotMoving, slot)
+		simulatedTime = simulatedTime.Add(6 * time.Second)
+		slot, err = driver.GetFilterSlot()
+		require.Nil(t, err)
+		require.Equal(t, 4, slot)
+	})
+
+	t.Run("disconnect releases wheel", func(t *testing.T) {
+		fake, driver := startFakeForDriver(t)
+		defer fake.Close()
+		defer driver.Close()
+
+		require.Nil(t, driver.FilterWheelConnect())
+		require.True(t, fake.FilterWheelConnected())
+		require.Nil(t, driver.FilterWheelDisconnect(), "Unable to disconnect filter wheel")
+		require.False(t, fake.FilterWheelConnected())
+		connected, err := driver.FilterWheelIsConnected()
+		require.Nil(t, err)
+		require.False(t, connected)
+	})
+
+	t.Run("filter slot calls connect the wheel when it isn't connected", func(t *testing.T) {
+		fake, driver := startFakeForDriver(t)
+		defer fake.Close()
+		defer driver.Close()
+
+		require.False(t, fake.FilterWheelConnected())
+		names, err := driver.FilterNames()
+		require.Nil(t, err, "Unable to get filter names")
+		require.NotEmpty(t, names)
+		require.False(t, fake.FilterWheelConnected(), "Reading filter names should not connect the wheel")
+
+		require.Nil(t, driver.SetFilterSlot(2))
+		require.True(t, fake.FilterWheelConnected())
+		require.Nil(t, driver.FilterWheelDisconnect())
+		_, err = driver.GetFilterSlot()
+		require.Nil(t, err, "Unable to get filter slot")
+		require.True(t, fake.FilterWheelConnected(), "Disconnected wheel should have been reconnected")
+	})
+
+	t.Run("HasFilterWheel leaves wheel as it found it", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		fake, driver := startFakeForDriver(t)
+		defer fake.Close()
+		defer driver.Close()
+		service := NewTheSkyService(goMockableDelay.NewMockDelayService(ctrl), false, 0, true)
+		service.SetDriver(driver)
+
+		hasFilterWheel, err := service.HasFilterWheel()
+		require.Nil(t, err, "Unable to query filter wheel")
+		require.True(t, hasFilterWheel)
+		require.False(t, fake.FilterWheelConnected(), "Probe should have disconnected the wheel")
+
+		require.Nil(t, driver.FilterWheelConnect())
+		hasFilterWheel, err = service.HasFilterWheel()
+		require.Nil(t, err, "Unable to query filter wheel")
+		require.True(t, hasFilterWheel)
+		require.True(t, fake.FilterWheelConnected(), "Probe should have left the wheel connected")
+	})
+
+	t.Run("a later session can release the wheel", func(t *testing.T) {
+		fake, driver := startFakeForDriver(t)
+		defer fake.Close()
+		require.Nil(t, driver.SetFilterSlot(2))
+		require.Nil(t, driver.Close())
+
+		laterDriver := NewTheSkyDriver(false, 0)
+		require.Nil(t, laterDriver.Connect("localhost", fake.Port()))
+		defer laterDriver.Close()
+		require.True(t, fake.FilterWheelConnected())
+		require.Nil(t, laterDriver.FilterWheelDisconnect())
+		require.False(t, fake.FilterWheelConnected())
+	})
+
+	t.Run("no filter wheel", func(t *testing.T) {
+		fake := fakeTheSkyX.NewFakeTheSkyServer(false, 0)
+		fake.SetHasFilterWheel(false)
+		require.Nil(t, fake.Start(), "Unable to start fake server")
+		defer fake.Close()
+		driver := NewTheSkyDriver(false, 0)
+		require.Nil(t, driver.Connect("localhost", fake.Port()))
+		defer driver.Close()
+
+		err := driver.SetFilterSlot(2)
+		require.ErrorIs(t, err, ErrCannotColorGrab)
+		_, err = driver.FilterNames()
+		require.Nil(t, err, "Filter names should be read without a wheel")
+	})
+}
+
+func TestSelectFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("select by slot waits for wheel to stop", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		gomock.InOrder(
+			mockDriver.EXPECT().SetFilterSlot(5).Return(nil),
+			mockDriver.EXPECT().GetFilterSlot().Return(FilterSlotMoving, nil).Times(2),
+			mockDriver.EXPECT().GetFilterSlot().Return(5, nil),
+		)
+		mockDelayService.EXPECT().DelayDuration(filterWheelPollingSeconds).Return(filterWheelPollingSeconds, nil).Times(2)
+
+		require.Nil(t, service.SelectFilter(5), "SelectFilter failed")
+	})
+
+	t.Run("select by name ignores case", func(t *testing.T) {
+		service, mockDriver, _ := setUpConnectedMockService(ctrl)
+		mockDriver.EXPECT().FilterNames().Return([]string{"Red", "Green", "Blue", "Dark", ""}, nil)
+		mockDriver.EXPECT().SetFilterSlot(4).Return(nil)
+		mockDriver.EXPECT().GetFilterSlot().Return(4, nil)
+
+		slot, err := service.SelectFilterByName("DARK")
+		require.Nil(t, err, "SelectFilterByName failed")
+		require.Equal(t, 4, slot)
+	})
+
+	t.Run("unknown name gives typed error", func(t *testing.T) {
+		service, mockDriver, _ := setUpConnectedMockService(ctrl)
+		mockDriver.EXPECT().FilterNames().Return([]string{"Red", "Green", "Blue", ""}, nil)
+
+		_, err := service.SelectFilterByName("Ha")
+		require.ErrorIs(t, err, ErrUnknownFilter)
+		var unknownFilter *UnknownFilterError
+		require.True(t, errors.As(err, &unknownFilter))
+		require.Equal(t, "Ha", unknownFilter.Name)
+		require.Equal(t, []string{"red", "green", "blue"}, unknownFilter.Available)
+	})
+
+	t.Run("wheel that stops in the wrong slot is an error", func(t *testing.T) {
+		service, mockDriver, _ := setUpConnectedMockService(ctrl)
+		mockDriver.EXPECT().SetFilterSlot(2).Return(nil)
+		mockDriver.EXPECT().GetFilterSlot().Return(1, nil)
+
+		err := service.SelectFilter(2)
+		require.ErrorContains(t, err, "stopped at slot 1")
+	})
+
+	t.Run("wheel that never stops times out", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		mockDriver.EXPECT().GetFilterSlot().Return(FilterSlotMoving, nil).Times(4)
+		mockDelayService.EXPECT().DelayDuration(filterWheelPollingSeconds).Return(filterWheelPollingSeconds, nil).Times(3)
+
+		_, err := service.WaitForFilterWheel(3)
+		require.ErrorIs(t, err, ErrFilterWheelTimeout)
+	})
+
+	t.Run("invalid slot is rejected", func(t *testing.T) {
+		service, _, _ := setUpConnectedMockService(ctrl)
+		require.NotNil(t, service.SelectFilter(0), "Expected error for slot 0")
+	})
+
+	t.Run("disconnect filter wheel", func(t *testing.T) {
+		service, mockDriver, _ := setUpConnectedMockService(ctrl)
+		mockDriver.EXPECT().FilterWheelDisconnect().Return(nil)
+
+		require.Nil(t, service.DisconnectFilterWheel(), "DisconnectFilterWheel failed")
+	})
+
+	t.Run("current slot", func(t *testing.T) {
+		service, mockDriver, _ := setUpConnectedMockService(ctrl)
+		mockDriver.EXPECT().GetFilterSlot().Return(2, nil)
+
+		slot, err := service.CurrentFilterSlot()
+		require.Nil(t, err, "CurrentFilterSlot failed")
+		require.Equal(t, 2, slot)
+	})
+}
@@ -0,0 +1,102 @@
+package goTheSkyX
+
+import (
+	"github.com/stretchr/testify/require"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTableFlatSimulationModel(t *testing.T) {
+
+	t.Run("default model uses per-filter coefficients", func(t *testing.T) {
+		model := NewDefaultFlatSimulationModel(1)
+		model.NoiseFraction = 0.0
+		require.Equal(t, int64(math.Round(11678.0*2.0-293.09)), model.SimulateADU(2.0, 2, 2, 1.0))
+		require.Equal(t, int64(math.Round(721.8*10.0+19817.0)), model.SimulateADU(10.0, 3, 7, 1.0), "Expected default coefficients")
+		require.Equal(t, int64(math.Round(11678.0*0.5*2.0-293.09)), model.SimulateADU(2.0, 2, 2, 0.5), "Brightness should scale slope")
+	})
+
+	t.Run("clips at saturation and zero", func(t *testing.T) {
+		model := NewTableFlatSimulationModel(nil, FlatSimulationCoefficients{Slope: 10000.0, Intercept: -500.0}, 1)
+		model.SaturationADU = 60000
+		require.Equal(t, int64(60000), model.SimulateADU(100.0, 1, 1, 1.0))
+		require.Equal(t, int64(0), model.SimulateADU(0.01, 1, 1, 1.0))
+	})
+
+	t.Run("same seed gives same noise", func(t *testing.T) {
+		for _, noise := range []NoiseDistribution{UniformNoise, GaussianNoise} {
+			first := NewDefaultFlatSimulationModel(99)
+			second := NewDefaultFlatSimulationModel(99)
+			first.Noise, second.Noise = noise, noise
+			var firstValues, secondValues []int64
+			for i := 0; i < 10; i++ {
+				firstValues = append(firstValues, first.SimulateADU(2.0, 2, 1, 1.0))
+				secondValues = append(secondValues, second.SimulateADU(2.0, 2, 1, 1.0))
+			}
+			require.Equal(t, firstValues, secondValues)
+			require.NotEqual(t, firstValues[0], firstValues[1], "Expected noise to vary")
+		}
+	})
+
+	t.Run("model built as a literal", func(t *testing.T) {
+		model := &TableFlatSimulationModel{Default: FlatSimulationCoefficients{Slope: 10000.0}, NoiseFraction: 0.1}
+		value := model.SimulateADU(2.0, 1, 1, 1.0)
+		require.InDelta(t, 20000.0, float64(value), 1000.0)
+		require.Equal(t, int64(maxADU), model.SimulateADU(100.0, 1, 1, 1.0), "Unset saturation should be 65535")
+	})
+
+	t.Run("gaussian noise has requested spread", func(t *testing.T) {
+		model := NewTableFlatSimulationModel(nil, FlatSimulationCoefficients{Intercept: 10000.0}, 7)
+		model.Noise = GaussianNoise
+		model.NoiseFraction = 0.02
+		var values []int64
+		for i := 0; i < 2000; i++ {
+			values = append(values, model.SimulateADU(1.0, 1, 1, 1.0))
+		}
+		mean, stdDev := meanAndStdDev(values)
+		require.InDelta(t, 10000.0, mean, 20.0)
+		require.InDelta(t, 200.0, stdDev, 20.0)
+	})
+
+	t.Run("loads model file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "simulation.yaml")
+		require.Nil(t, os.WriteFile(path, []byte(`
+saturationADU: 50000
+noise:
+  distribution: gaussian
+  fraction: 0
+default: {slope: 100, intercept: 1000}
+coefficients:
+  - {binning: 2, filterSlot: 3, slope: 2000, intercept: 500}
+`), 0o644))
+		model, err := LoadFlatSimulationModel(path, 1)
+		require.Nil(t, err, "Load failed")
+		require.Equal(t, GaussianNoise, model.Noise)
+		require.Equal(t, int64(50000), model.SaturationADU)
+		require.Equal(t, int64(4500), model.SimulateADU(2.0, 2, 3, 1.0))
+		require.Equal(t, int64(1200), model.SimulateADU(2.0, 1, 3, 1.0))
+	})
+
+	t.Run("loads JSON model file", func(t *testing.T) {
+		model, err := ParseFlatSimulationModel([]byte(`{"default": {"slope": 100, "intercept": 0}}`), 1)
+		require.Nil(t, err, "Parse failed")
+		require.Equal(t, int64(maxADU), model.SaturationADU)
+		require.Equal(t, UniformNoise, model.Noise)
+		require.Equal(t, int64(300), model.SimulateADU(3.0, 1, 1, 1.0))
+	})
+
+	t.Run("rejects bad model files", func(t *testing.T) {
+		_, err := ParseFlatSimulationModel([]byte("coefficients: []\n"), 1)
+		require.ErrorContains(t, err, "no default coefficients")
+		_, err = ParseFlatSimulationModel([]byte("default: {slope: 1}\nnoise: {distribution: poisson}\n"), 1)
+		require.ErrorContains(t, err, "unknown noise distribution")
+		_, err = ParseFlatSimulationModel([]byte("default: {slope: 1}\ngain: 2\n"), 1)
+		require.NotNil(t, err, "Expected unknown key to be rejected")
+		_, err = ParseFlatSimulationModel([]byte("default: {slope: 1}\nsaturationADU: 70000\n"), 1)
+		require.ErrorContains(t, err, "saturationADU")
+		_, err = LoadFlatSimulationModel(filepath.Join(t.TempDir(), "missing.yaml"), 1)
+		require.NotNil(t, err, "Expected error for missing file")
+	})
+}
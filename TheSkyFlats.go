@@ -0,0 +1,362 @@
+package goTheSkyX
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+//	Flat frame services built on CaptureAndMeasureFlatFrame: finding the exposure that gives
+//	flats of the wanted brightness, and capturing sets of flats through each filter.
+
+// Errors from FindFlatExposure, returned wrapped with details
+var (
+	ErrFlatPanelTooBright     = errors.New("flat light source too bright to reach target ADU")
+	ErrFlatPanelTooDim        = errors.New("flat light source too dim to reach target ADU")
+	ErrFlatSearchNotConverged = errors.New("flat exposure search did not converge")
+)
+
+// FlatExposureSearch describes the flat frames we want an exposure for
+type FlatExposureSearch struct {
+	FilterSlot      int
+	Binning         int
+	TargetADU       int64
+	Tolerance       float64 // Fraction of TargetADU the result may be off by, e.g. 0.05 for 5%
+	MinExposure     float64 // Seconds.  Shorter exposures risk shutter effects in the flats
+	MaxExposure     float64 // Seconds
+	InitialExposure float64 // First exposure tried; 0 for the default
+	MaxAttempts     int     // Give up after this many test exposures; 0 for the default
+	DownloadTime    float64
+	Subframe        Subframe // Part of the sensor read out for test exposures; the zero value is the full frame
+}
+
+// FlatMeasurement is one test exposure taken during the search, or a rejected flat
+type FlatMeasurement struct {
+	Exposure float64
+	ADU      int64
+	Path     string // Where a rejected flat was saved, so it can be removed; "" if it wasn't
+}
+
+// FlatExposureResult is the outcome of a search: the chosen exposure and the ADU it gave, and
+// all the measurements taken to find it
+type FlatExposureResult struct {
+	Exposure float64
+	ADU      int64
+	History  []FlatMeasurement
+}
+
+const defaultFlatInitialExposure = 1.0
+const defaultFlatSearchAttempts = 10
+
+// saturatedADU is the level at which we assume pixels are saturated and the measured ADU no
+// longer tells us how bright the light source is
+const saturatedADU = 65000
+
+// FindFlatExposure searches for the exposure giving flat frames within tolerance of the
+// target ADU.  Each test exposure is captured without saving the image.  ADU is roughly linear
+// in exposure, so after the first measurement we estimate the next exposure from a straight
+// line through the measurements so far.  If the light source is too bright even at the minimum
+// exposure, or too dim even at the maximum, we give up with ErrFlatPanelTooBright or
+// ErrFlatPanelTooDim.  The measurement history is returned even when the search fails.
+func (service *TheSkyServiceInstance) FindFlatExposure(search FlatExposureSearch) (FlatExposureResult, error) {
+	return service.FindFlatExposureContext(context.Background(), search)
+}
+
+func (service *TheSkyServiceInstance) FindFlatExposureContext(ctx context.Context, search FlatExposureSearch) (FlatExposureResult, error) {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Printf("TheSkyServiceInstance/FindFlatExposure(slot %d, binning %d, %d +/- %g)\n",
+			search.FilterSlot, search.Binning, search.TargetADU, search.Tolerance)
+	}
+	if !service.isOpen {
+		return FlatExposureResult{}, errors.New("TheSkyServiceInstance/FindFlatExposure: Connection not open")
+	}
+	if err := search.validate(); err != nil {
+		return FlatExposureResult{}, err
+	}
+	restoreFullFrame, err := service.useSubframe(ctx, search.Subframe, search.Binning)
+	if err != nil {
+		return FlatExposureResult{}, fmt.Errorf("TheSkyServiceInstance/FindFlatExposure: %w", err)
+	}
+	defer restoreFullFrame()
+	maxAttempts := valueOrDefault(search.MaxAttempts, defaultFlatSearchAttempts)
+	lowADU := float64(search.TargetADU) * (1.0 - search.Tolerance)
+	highADU := float64(search.TargetADU) * (1.0 + search.Tolerance)
+
+	var result FlatExposureResult
+	exposure := search.clampExposure(valueOrDefault(search.InitialExposure, defaultFlatInitialExposure))
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		adu, err := service.CaptureAndMeasureFlatFrameContext(ctx, exposure, search.Binning, search.FilterSlot, search.DownloadTime, false)
+		if err != nil {
+			fmt.Println("TheSkyServiceInstance/FindFlatExposure error from CaptureAndMeasureFlatFrame:", err)
+			return result, err
+		}
+		result.History = append(result.History, FlatMeasurement{Exposure: exposure, ADU: adu})
+		result.Exposure = exposure
+		result.ADU = adu
+		if service.verbosity >= 4 {
+			fmt.Printf("  Exposure %g gave %d ADU\n", exposure, adu)
+		}
+
+		if float64(adu) >= lowADU && float64(adu) <= highADU {
+			return result, nil
+		}
+		if exposure <= search.MinExposure && float64(adu) > highADU {
+			return result, fmt.Errorf("%w: %d ADU at minimum exposure %g", ErrFlatPanelTooBright, adu, exposure)
+		}
+		if exposure >= search.MaxExposure && float64(adu) < lowADU {
+			return result, fmt.Errorf("%w: %d ADU at maximum exposure %g", ErrFlatPanelTooDim, adu, exposure)
+		}
+		exposure = search.clampExposure(nextFlatExposure(result.History, search.TargetADU))
+	}
+	return result, fmt.Errorf("%w after %d exposures: last was %g seconds giving %d ADU",
+		ErrFlatSearchNotConverged, maxAttempts, result.Exposure, result.ADU)
+}
+
+func (search FlatExposureSearch) validate() error {
+	if search.TargetADU < 1 || search.TargetADU > maxADU {
+		return fmt.Errorf("TheSkyServiceInstance/FindFlatExposure: target ADU must be between 1 and %d", maxADU)
+	}
+	if search.Tolerance <= 0 || search.Tolerance >= 1 {
+		return errors.New("TheSkyServiceInstance/FindFlatExposure: tolerance must be a fraction between 0 and 1")
+	}
+	if search.MinExposure <= 0 || search.MaxExposure < search.MinExposure {
+		return errors.New("TheSkyServiceInstance/FindFlatExposure: need 0 < minimum exposure <= maximum exposure")
+	}
+	return nil
+}
+
+func (search FlatExposureSearch) clampExposure(exposure float64) float64 {
+	// Round to the millisecond - finer than that is meaningless to the camera
+	rounded := math.Round(exposure*1000.0) / 1000.0
+	return math.Max(search.MinExposure, math.Min(search.MaxExposure, rounded))
+}
+
+// nextFlatExposure estimates the exposure giving the target ADU from the measurements so far.
+// With two usable measurements we fit a line through the latest two; with one we assume ADU
+// is proportional to exposure.  Saturated measurements only tell us to go shorter.
+func nextFlatExposure(history []FlatMeasurement, targetADU int64) float64 {
+	var usable []FlatMeasurement
+	for _, measurement := range history {
+		if measurement.ADU < saturatedADU && measurement.ADU > 0 {
+			usable = append(usable, measurement)
+		}
+	}
+	latest := history[len(history)-1]
+	if len(usable) == 0 {
+		if latest.ADU >= saturatedADU {
+			// Saturated, so exposure * target / maximum is an upper bound on the exposure we
+			// need.  Halve it to be sure of getting below saturation next time.
+			return latest.Exposure * float64(targetADU) / float64(maxADU) / 2.0
+		}
+		return latest.Exposure * 2.0
+	}
+	if len(usable) >= 2 {
+		first := usable[len(usable)-2]
+		second := usable[len(usable)-1]
+		if second.Exposure != first.Exposure {
+			slope := float64(second.ADU-first.ADU) / (second.Exposure - first.Exposure)
+			if slope > 0 {
+				return second.Exposure + float64(targetADU-second.ADU)/slope
+			}
+		}
+	}
+	last := usable[len(usable)-1]
+	return last.Exposure * float64(targetADU) / float64(last.ADU)
+}
+
+// FlatSetSettings describes a set of flat frames to capture through each of several filters
+type FlatSetSettings struct {
+	FilterSlots  []int // One-based; empty for every filter in the wheel
+	Binning      int
+	Count        int // Number of good flats wanted per filter
+	TargetADU    int64
+	Tolerance    float64 // Fraction of TargetADU a flat may be off by
+	MinExposure  float64
+	MaxExposure  float64
+	MaxRejected  int // Give up on a filter after this many rejected flats; 0 for the same as Count
+	DownloadTime float64
+	Dither       FlatDither // Move the mount a little between flats; the zero value doesn't
+
+	// Part of the sensor read out while finding the exposure, and its download time (0 for
+	// DownloadTime).  The flats themselves are full frame.
+	SearchSubframe     Subframe
+	SearchDownloadTime float64
+}
+
+// FlatSetResult is the outcome of capturing the flats for one filter.  If the filter could not
+// be completed (light source too bright, too many rejected frames, ...) Err says why, and the
+// rest of the result shows what was captured before giving up.
+type FlatSetResult struct {
+	FilterSlot int
+	FilterName string
+	Search     FlatExposureResult // Finding the starting exposure
+	Exposure   float64            // Exposure used for the last flat
+	Exposures  []float64          // Exposure of each accepted flat
+	ADUs       []int64            // Average ADU of each accepted flat
+	Rejected   []FlatMeasurement  // Flats that were saved, but were out of tolerance; delete these
+	MeanADU    float64
+	StdDevADU  float64
+	Err        error
+}
+
+// ErrTooManyRejectedFlats is recorded (wrapped) against a filter when the light source drifted
+// so much that too many flats were out of tolerance
+var ErrTooManyRejectedFlats = errors.New("too many flat frames out of tolerance")
+
+// CaptureFlatSets captures a set of saved flat frames through each filter.  For each filter it
+// first finds the right exposure with FindFlatExposure, then captures flats until it has Count
+// within tolerance.  Each flat's ADU is checked; if the light source has drifted and a flat is
+// out of tolerance it is rejected and the exposure adjusted for the next one.  A rejected flat
+// has been saved already, so its path is recorded in Rejected for the caller to delete it
+// before the flats are stacked.  Problems with
+// one filter are recorded in its result and we go on to the next; an error is returned only
+// if the whole run can't continue (e.g. cancelled, or the mount can't be moved to dither).
+// If dithering, the mount is moved before each flat but the first, and slewed back to its
+// starting altitude and azimuth at the end.  A tracking mount has tracking switched off for the
+// run and back on at the end.  If the run is cancelled or stops early, the mount is left where
+// it is, not tracking.
+func (service *TheSkyServiceInstance) CaptureFlatSets(settings FlatSetSettings) ([]FlatSetResult, error) {
+	return service.CaptureFlatSetsContext(context.Background(), settings)
+}
+
+func (service *TheSkyServiceInstance) CaptureFlatSetsContext(ctx context.Context, settings FlatSetSettings) ([]FlatSetResult, error) {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Printf("TheSkyServiceInstance/CaptureFlatSets(slots %v, binning %d, %d each)\n",
+			settings.FilterSlots, settings.Binning, settings.Count)
+	}
+	if !service.isOpen {
+		return nil, errors.New("TheSkyServiceInstance/CaptureFlatSets: Connection not open")
+	}
+	if settings.Count < 1 {
+		return nil, errors.New("TheSkyServiceInstance/CaptureFlatSets: count must be at least 1")
+	}
+	if err := settings.Dither.validate(); err != nil {
+		return nil, fmt.Errorf("TheSkyServiceInstance/CaptureFlatSets: %w", err)
+	}
+	if err := service.checkSubframe(ctx, settings.SearchSubframe, settings.Binning); err != nil {
+		return nil, fmt.Errorf("TheSkyServiceInstance/CaptureFlatSets: %w", err)
+	}
+	filterNames, err := service.FilterNamesContext(ctx)
+	if err != nil {
+		fmt.Println("TheSkyServiceInstance/CaptureFlatSets error from FilterNames:", err)
+		return nil, err
+	}
+	filterSlots := settings.FilterSlots
+	if len(filterSlots) == 0 {
+		for i := range filterNames {
+			filterSlots = append(filterSlots, i+1)
+		}
+	}
+	for _, slot := range filterSlots {
+		if slot < 1 || slot > len(filterNames) {
+			return nil, fmt.Errorf("TheSkyServiceInstance/CaptureFlatSets: no filter in slot %d", slot)
+		}
+	}
+
+	var ditherer *flatDitherer
+	if settings.Dither.enabled() {
+		if ditherer, err = service.startDithering(ctx, settings.Dither); err != nil {
+			fmt.Println("TheSkyServiceInstance/CaptureFlatSets error starting to dither:", err)
+			return nil, err
+		}
+	}
+
+	var results []FlatSetResult
+	for _, slot := range filterSlots {
+		result := service.captureFlatSet(ctx, settings, slot, filterNames[slot-1], ditherer)
+		results = append(results, result)
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		if errors.Is(result.Err, ErrDitherFailed) {
+			return results, result.Err
+		}
+	}
+	if ditherer != nil {
+		if err := service.finishDithering(ctx, ditherer); err != nil {
+			fmt.Println("TheSkyServiceInstance/CaptureFlatSets error returning from dither:", err)
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// captureFlatSet captures the flats for one filter, dithering before each if ditherer isn't nil
+func (service *TheSkyServiceInstance) captureFlatSet(ctx context.Context, settings FlatSetSettings, slot int, filterName string, ditherer *flatDitherer) FlatSetResult {
+	result := FlatSetResult{FilterSlot: slot, FilterName: filterName}
+	search := FlatExposureSearch{
+		FilterSlot:   slot,
+		Binning:      settings.Binning,
+		TargetADU:    settings.TargetADU,
+		Tolerance:    settings.Tolerance,
+		MinExposure:  settings.MinExposure,
+		MaxExposure:  settings.MaxExposure,
+		DownloadTime: settings.DownloadTime,
+		Subframe:     settings.SearchSubframe,
+	}
+	if settings.SearchDownloadTime > 0 {
+		search.DownloadTime = settings.SearchDownloadTime
+	}
+	result.Search, result.Err = service.FindFlatExposureContext(ctx, search)
+	if result.Err != nil {
+		return result
+	}
+	result.Exposure = result.Search.Exposure
+
+	maxRejected := valueOrDefault(settings.MaxRejected, settings.Count)
+	lowADU := float64(settings.TargetADU) * (1.0 - settings.Tolerance)
+	highADU := float64(settings.TargetADU) * (1.0 + settings.Tolerance)
+	for len(result.ADUs) < settings.Count {
+		if ditherer != nil {
+			if err := service.ditherBeforeFlat(ctx, "CaptureFlatSets", ditherer); err != nil {
+				fmt.Println("TheSkyServiceInstance/CaptureFlatSets error dithering:", err)
+				result.Err = err
+				break
+			}
+		}
+		captured, err := service.CaptureAndMeasureFlatFrameResultContext(ctx, result.Exposure, settings.Binning, slot, settings.DownloadTime, true)
+		if err != nil {
+			fmt.Println("TheSkyServiceInstance/CaptureFlatSets error from CaptureAndMeasureFlatFrame:", err)
+			result.Err = err
+			break
+		}
+		adu := captured.ADU
+		if float64(adu) >= lowADU && float64(adu) <= highADU {
+			result.Exposures = append(result.Exposures, result.Exposure)
+			result.ADUs = append(result.ADUs, adu)
+			continue
+		}
+		if service.verbosity >= 3 {
+			fmt.Printf("Flat in %s at %g seconds was %d ADU, out of tolerance\n", filterName, result.Exposure, adu)
+		}
+		result.Rejected = append(result.Rejected, FlatMeasurement{Exposure: result.Exposure, ADU: adu, Path: captured.Path})
+		if len(result.Rejected) >= maxRejected {
+			result.Err = fmt.Errorf("%w: %d rejected in filter %s", ErrTooManyRejectedFlats, len(result.Rejected), filterName)
+			break
+		}
+		result.Exposure = search.clampExposure(nextFlatExposure([]FlatMeasurement{{Exposure: result.Exposure, ADU: adu}}, settings.TargetADU))
+	}
+	result.MeanADU, result.StdDevADU = meanAndStdDev(result.ADUs)
+	return result
+}
+
+// meanAndStdDev returns the mean and sample standard deviation of the values
+func meanAndStdDev(values []int64) (float64, float64) {
+	if len(values) == 0 {
+		return 0.0, 0.0
+	}
+	sum := 0.0
+	for _, value := range values {
+		sum += float64(value)
+	}
+	mean := sum / float64(len(values))
+	if len(values) == 1 {
+		return mean, 0.0
+	}
+	sumOfSquares := 0.0
+	for _, value := range values {
+		sumOfSquares += (float64(value) - mean) * (float64(value) - mean)
+	}
+	return mean, math.Sqrt(sumOfSquares / float64(len(values)-1))
+}
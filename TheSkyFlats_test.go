@@ -0,0 +1,296 @@
+package goTheSkyX
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+)
+
+// setUpFlatPanel sets up the mock driver so that flat frames measure the ADU given by aduFor,
+// saturating at 65535, and are saved as flat_0001.fit, flat_0002.fit, ...  It returns a
+// pointer to the number of flats captured.
+func setUpFlatPanel(mockDriver *MockTheSkyDriver, binning int, aduFor func(filterSlot int, exposure float64) float64) *int {
+	var lastExposure float64
+	var lastSlot int
+	captures := 0
+	mockDriver.EXPECT().StartFlatFrameCapture(binning, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(binning int, seconds float64, filterSlot int, downloadTime float64, saveImage bool) error {
+			lastExposure = seconds
+			lastSlot = filterSlot
+			captures++
+			return nil
+		}).AnyTimes()
+	mockDriver.EXPECT().IsCaptureDone().Return(true, nil).AnyTimes()
+	mockDriver.EXPECT().GetCapturedImageInfo().DoAndReturn(func() (CaptureResult, error) {
+		return CaptureResult{Path: fmt.Sprintf("/flats/flat_%04d.fit", captures)}, nil
+	}).AnyTimes()
+	mockDriver.EXPECT().GetADUValue().DoAndReturn(func() (int64, error) {
+		return int64(math.Min(aduFor(lastSlot, lastExposure), 65535.0)), nil
+	}).AnyTimes()
+	return &captures
+}
+
+// setUpSimulatedFlatPanel sets up a flat panel giving bias + rate*exposure ADU
+func setUpSimulatedFlatPanel(mockDriver *MockTheSkyDriver, binning int, bias float64, rate float64) *int {
+	return setUpFlatPanel(mockDriver, binning, func(_ int, exposure float64) float64 {
+		return bias + rate*exposure
+	})
+}
+
+func TestFindFlatExposure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("converges on target", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		service.SetSimulateFlatCapture(false)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil).AnyTimes()
+		captures := setUpSimulatedFlatPanel(mockDriver, 1, 1000.0, 2000.0)
+
+		result, err := service.FindFlatExposure(FlatExposureSearch{
+			FilterSlot: 3, Binning: 1, TargetADU: 25000, Tolerance: 0.05,
+			MinExposure: 0.5, MaxExposure: 60.0, DownloadTime: 1.0,
+		})
+		require.Nil(t, err, "Search failed")
+		require.InDelta(t, 12.0, result.Exposure, 0.6)
+		require.InDelta(t, 25000, result.ADU, 1250)
+		require.Equal(t, len(result.History), *captures)
+		require.LessOrEqual(t, *captures, 4, "Search took too many exposures")
+	})
+
+	t.Run("converges using the service's flat simulation", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		model := NewDefaultFlatSimulationModel(42)
+		model.NoiseFraction = 0.01
+		service.SetFlatSimulationModel(model)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil).AnyTimes()
+		setUpSimulatedFlatPanel(mockDriver, 2, 0.0, 0.0)
+
+		// Green filter binned 2x2 is simulated as 11678 ADU/second - 293
+		result, err := service.FindFlatExposure(FlatExposureSearch{
+			FilterSlot: 2, Binning: 2, TargetADU: 25000, Tolerance: 0.05,
+			MinExposure: 0.1, MaxExposure: 30.0,
+		})
+		require.Nil(t, err, "Search failed")
+		require.InDelta(t, 2.17, result.Exposure, 0.15)
+	})
+
+	t.Run("recovers from saturated first exposure", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		service.SetSimulateFlatCapture(false)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil).AnyTimes()
+		setUpSimulatedFlatPanel(mockDriver, 2, 500.0, 40000.0)
+
+		result, err := service.FindFlatExposure(FlatExposureSearch{
+			FilterSlot: 1, Binning: 2, TargetADU: 30000, Tolerance: 0.05,
+			MinExposure: 0.1, MaxExposure: 30.0, InitialExposure: 5.0,
+		})
+		require.Nil(t, err, "Search failed")
+		require.Equal(t, int64(65535), result.History[0].ADU)
+		require.InDelta(t, 30000, result.ADU, 1500)
+	})
+
+	t.Run("panel too bright", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		service.SetSimulateFlatCapture(false)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil).AnyTimes()
+		setUpSimulatedFlatPanel(mockDriver, 1, 1000.0, 500000.0)
+
+		result, err := service.FindFlatExposure(FlatExposureSearch{
+			FilterSlot: 1, Binning: 1, TargetADU: 25000, Tolerance: 0.05,
+			MinExposure: 0.1, MaxExposure: 30.0,
+		})
+		require.ErrorIs(t, err, ErrFlatPanelTooBright)
+		require.Equal(t, 0.1, result.Exposure)
+	})
+
+	t.Run("panel too dim", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		service.SetSimulateFlatCapture(false)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil).AnyTimes()
+		setUpSimulatedFlatPanel(mockDriver, 1, 1000.0, 100.0)
+
+		result, err := service.FindFlatExposure(FlatExposureSearch{
+			FilterSlot: 5, Binning: 1, TargetADU: 25000, Tolerance: 0.05,
+			MinExposure: 0.1, MaxExposure: 30.0,
+		})
+		require.ErrorIs(t, err, ErrFlatPanelTooDim)
+		require.Equal(t, 30.0, result.Exposure)
+		require.Equal(t, int64(4000), result.ADU)
+	})
+
+	t.Run("gives up after maximum attempts", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		service.SetSimulateFlatCapture(false)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil).AnyTimes()
+		captures := setUpSimulatedFlatPanel(mockDriver, 1, 1000.0, 2000.0)
+
+		result, err := service.FindFlatExposure(FlatExposureSearch{
+			FilterSlot: 1, Binning: 1, TargetADU: 25000, Tolerance: 0.001,
+			MinExposure: 0.1, MaxExposure: 30.0, MaxAttempts: 1,
+		})
+		require.ErrorIs(t, err, ErrFlatSearchNotConverged)
+		require.Equal(t, 1, *captures)
+		require.Len(t, result.History, 1)
+	})
+
+	t.Run("cancellation stops the search", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		service.SetSimulateFlatCapture(false)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil).AnyTimes()
+		setUpSimulatedFlatPanel(mockDriver, 1, 1000.0, 2000.0)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := service.FindFlatExposureContext(ctx, FlatExposureSearch{
+			FilterSlot: 1, Binning: 1, TargetADU: 25000, Tolerance: 0.05,
+			MinExposure: 0.1, MaxExposure: 30.0,
+		})
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("invalid search", func(t *testing.T) {
+		service, _, _ := setUpConnectedMockService(ctrl)
+
+		_, err := service.FindFlatExposure(FlatExposureSearch{TargetADU: 25000, Tolerance: 0.05, MinExposure: 5.0, MaxExposure: 1.0})
+		require.NotNil(t, err, "Expected error for inverted exposure bounds")
+		_, err = service.FindFlatExposure(FlatExposureSearch{TargetADU: 25000, Tolerance: 0, MinExposure: 1.0, MaxExposure: 5.0})
+		require.NotNil(t, err, "Expected error for zero tolerance")
+	})
+}
+
+func TestCaptureFlatSets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Each filter passes a different amount of light
+	filterRates := map[int]float64{1: 2000.0, 2: 3000.0, 3: 1000.0, 4: 8000.0}
+
+	t.Run("captures a set through each filter", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		service.SetSimulateFlatCapture(false)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil).AnyTimes()
+		mockDriver.EXPECT().FilterNames().Return([]string{"Red", "Green", "Blue", "Lum"}, nil)
+		setUpFlatPanel(mockDriver, 1, func(slot int, exposure float64) float64 {
+			return 1000.0 + filterRates[slot]*exposure
+		})
+
+		results, err := service.CaptureFlatSets(FlatSetSettings{
+			FilterSlots: []int{1, 3}, Binning: 1, Count: 3, TargetADU: 25000, Tolerance: 0.05,
+			MinExposure: 0.1, MaxExposure: 60.0,
+		})
+		require.Nil(t, err, "CaptureFlatSets failed")
+		require.Len(t, results, 2)
+		require.Equal(t, "blue", results[1].FilterName)
+		for _, result := range results {
+			require.Nil(t, result.Err)
+			require.Len(t, result.ADUs, 3)
+			require.Empty(t, result.Rejected)
+			require.InDelta(t, 25000.0, result.MeanADU, 1250.0)
+			require.Equal(t, 0.0, result.StdDevADU, "Steady panel should give identical flats")
+		}
+		require.Greater(t, results[1].Exposure, results[0].Exposure, "Blue is dimmer so needs longer")
+	})
+
+	t.Run("adjusts exposure when the panel drifts", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		service.SetSimulateFlatCapture(false)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil).AnyTimes()
+		mockDriver.EXPECT().FilterNames().Return([]string{"Red", "Green", "Blue", "Lum"}, nil)
+		// Panel fades by 1.5% with every exposure
+		brightness := 1.0
+		setUpFlatPanel(mockDriver, 1, func(slot int, exposure float64) float64 {
+			brightness *= 0.985
+			return 1000.0 + filterRates[slot]*brightness*exposure
+		})
+
+		results, err := service.CaptureFlatSets(FlatSetSettings{
+			FilterSlots: []int{2}, Binning: 1, Count: 5, TargetADU: 25000, Tolerance: 0.05,
+			MinExposure: 0.1, MaxExposure: 60.0,
+		})
+		require.Nil(t, err, "CaptureFlatSets failed")
+		result := results[0]
+		require.Nil(t, result.Err)
+		require.Len(t, result.ADUs, 5)
+		require.NotEmpty(t, result.Rejected, "Expected drifting panel to give some rejected flats")
+		require.Greater(t, result.Exposure, result.Search.Exposure, "Exposure should have lengthened as panel faded")
+		require.Greater(t, result.StdDevADU, 0.0)
+	})
+
+	t.Run("gives up on a filter after too many rejects", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		service.SetSimulateFlatCapture(false)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil).AnyTimes()
+		mockDriver.EXPECT().FilterNames().Return([]string{"Red", "Green", "Blue", "Lum"}, nil)
+		// Panel flickers wildly once the saved flats start
+		captures := 0
+		setUpFlatPanel(mockDriver, 1, func(slot int, exposure float64) float64 {
+			captures++
+			if captures > 3 && captures%2 == 0 {
+				return 500.0
+			}
+			if captures > 3 {
+				return 60000.0
+			}
+			return 1000.0 + filterRates[slot]*exposure
+		})
+
+		results, err := service.CaptureFlatSets(FlatSetSettings{
+			FilterSlots: []int{1}, Binning: 1, Count: 5, TargetADU: 25000, Tolerance: 0.05,
+			MinExposure: 0.1, MaxExposure: 60.0, MaxRejected: 3,
+		})
+		require.Nil(t, err, "Filter failure should not fail the run")
+		require.ErrorIs(t, results[0].Err, ErrTooManyRejectedFlats)
+		require.Len(t, results[0].Rejected, 3)
+		// The first saved flat is capture 4, which flickers
+		require.Equal(t, "/flats/flat_0004.fit", results[0].Rejected[0].Path, "Rejected flat's path should be recorded")
+		require.Equal(t, "/flats/flat_0005.fit", results[0].Rejected[1].Path)
+	})
+
+	t.Run("problem with one filter doesn't stop the others", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		service.SetSimulateFlatCapture(false)
+		mockDelayService.EXPECT().DelayDuration(gomock.Any()).Return(0, nil).AnyTimes()
+		mockDriver.EXPECT().FilterNames().Return([]string{"Red", "Green", "Blue", "Lum"}, nil)
+		setUpFlatPanel(mockDriver, 1, func(slot int, exposure float64) float64 {
+			return 1000.0 + filterRates[slot]*exposure
+		})
+
+		// Luminance is too bright at the minimum exposure we allow
+		results, err := service.CaptureFlatSets(FlatSetSettings{
+			Binning: 1, Count: 2, TargetADU: 25000, Tolerance: 0.05,
+			MinExposure: 5.0, MaxExposure: 60.0,
+		})
+		require.Nil(t, err, "Filter failure should not fail the run")
+		require.Len(t, results, 4, "Empty slot list should mean every filter")
+		require.ErrorIs(t, results[3].Err, ErrFlatPanelTooBright)
+		require.Empty(t, results[3].ADUs)
+		for _, result := range results[:3] {
+			require.Nil(t, result.Err)
+			require.Len(t, result.ADUs, 2)
+		}
+	})
+
+	t.Run("rejects slot not in filter wheel", func(t *testing.T) {
+		service, mockDriver, _ := setUpConnectedMockService(ctrl)
+		mockDriver.EXPECT().FilterNames().Return([]string{"Red", "Green"}, nil)
+
+		_, err := service.CaptureFlatSets(FlatSetSettings{
+			FilterSlots: []int{3}, Binning: 1, Count: 2, TargetADU: 25000, Tolerance: 0.05,
+			MinExposure: 0.1, MaxExposure: 60.0,
+		})
+		require.ErrorContains(t, err, "no filter in slot 3")
+	})
+
+	t.Run("mean and standard deviation", func(t *testing.T) {
+		mean, stdDev := meanAndStdDev([]int64{2, 4, 4, 4, 5, 5, 7, 9})
+		require.Equal(t, 5.0, mean)
+		require.InDelta(t, 2.138, stdDev, 0.001)
+		mean, stdDev = meanAndStdDev(nil)
+		require.Equal(t, 0.0, mean)
+		require.Equal(t, 0.0, stdDev)
+	})
+}
@@ -0,0 +1,80 @@
+package goTheSkyX
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+//	Image statistics, for quality control of calibration frames.  GetADUValue gives only the
+//	mean, which hides the problems we most want to catch: a flat with a hot spot from the panel
+//	has a plausible mean but a large spread and saturated pixels, and a dark with a light leak
+//	has a maximum far above its median.  ImageStats measures the most recent image in TheSkyX
+//	itself, so the pixels don't have to be sent over the network.
+//
+//	The measurement can be limited to the centre of the image, e.g. 0.5 for the middle half of
+//	its width and height, to ignore vignetting or amp glow at the edges.  It reads every pixel
+//	of the region in a script, so on a large unbinned image it takes some seconds.
+
+// ImageStats describes the pixel values of an image, or of its central region
+type ImageStats struct {
+	CentralFraction float64 // Of the width and height measured; 1 for the whole image
+	PixelCount      int
+	Mean            float64
+	Median          int64
+	StdDev          float64
+	Min             int64
+	Max             int64
+	SaturatedPixels int   // At or above saturatedADU
+	HotPixelADU     int64 // How far above the median a pixel must be to count as hot; 0 if not counted
+	HotPixels       int   // More than HotPixelADU above the median
+}
+
+// SaturatedFraction is the proportion of the pixels measured that are saturated
+func (stats ImageStats) SaturatedFraction() float64 {
+	if stats.PixelCount == 0 {
+		return 0.0
+	}
+	return float64(stats.SaturatedPixels) / float64(stats.PixelCount)
+}
+
+// HotPixelFraction is the proportion of the pixels measured that are hot
+func (stats ImageStats) HotPixelFraction() float64 {
+	if stats.PixelCount == 0 {
+		return 0.0
+	}
+	return float64(stats.HotPixels) / float64(stats.PixelCount)
+}
+
+// ImageStats measures the most recently captured image, over the given central fraction of its
+// width and height: 0 or 1 for the whole image
+func (service *TheSkyServiceInstance) ImageStats(centralFraction float64) (ImageStats, error) {
+	return service.ImageStatsContext(context.Background(), centralFraction)
+}
+
+func (service *TheSkyServiceInstance) ImageStatsContext(ctx context.Context, centralFraction float64) (ImageStats, error) {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Println("TheSkyServiceInstance/ImageStats ", centralFraction)
+	}
+	if !service.isOpen {
+		return ImageStats{}, errors.New("TheSkyServiceInstance/ImageStats: Connection not open")
+	}
+	if centralFraction < 0.0 || centralFraction > 1.0 {
+		return ImageStats{}, fmt.Errorf("TheSkyServiceInstance/ImageStats: central fraction %g is not between 0 and 1", centralFraction)
+	}
+	if centralFraction == 0.0 {
+		centralFraction = 1.0
+	}
+	if err := ctx.Err(); err != nil {
+		return ImageStats{}, err
+	}
+	stats, err := service.driverFor(ctx).GetImageStats(centralFraction, 0)
+	if err != nil {
+		fmt.Println("TheSkyServiceInstance/ImageStats error from driver:", err)
+		return ImageStats{}, err
+	}
+	if service.verbosity >= 4 || service.debug {
+		fmt.Printf("TheSkyServiceInstance/ImageStats: %+v\n", stats)
+	}
+	return stats, nil
+}
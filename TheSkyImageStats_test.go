@@ -0,0 +1,91 @@
+package goTheSkyX
+
+import (
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestImageStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("uniform flat", func(t *testing.T) {
+		fake, service := startFakeForSaving(t, ctrl)
+		defer fake.Close()
+		defer service.Close()
+		fake.SetSensor(64, 48, 3.8)
+
+		adu, err := service.CaptureAndMeasureFlatFrame(0.2, 1, 1, 0.0, false)
+		require.Nil(t, err, "Capture failed")
+		stats, err := service.ImageStats(0.0)
+		require.Nil(t, err, "Unable to measure image")
+		require.Equal(t, 1.0, stats.CentralFraction)
+		require.Equal(t, 64*48, stats.PixelCount)
+		require.InDelta(t, float64(adu), stats.Mean, 5.0)
+		require.InDelta(t, adu, stats.Median, 10.0)
+		require.Greater(t, stats.StdDev, 1.0)
+		require.Less(t, stats.StdDev, 50.0)
+		require.Less(t, stats.Min, stats.Median)
+		require.Greater(t, stats.Max, stats.Median)
+		require.Equal(t, 0, stats.SaturatedPixels)
+	})
+
+	t.Run("flat with a hot spot", func(t *testing.T) {
+		fake, service := startFakeForSaving(t, ctrl)
+		defer fake.Close()
+		defer service.Close()
+		fake.SetSensor(64, 48, 3.8)
+		fake.SetHotSpot(0.1, 70000.0)
+
+		_, err := service.CaptureAndMeasureFlatFrame(0.2, 1, 1, 0.0, false)
+		require.Nil(t, err, "Capture failed")
+		stats, err := service.ImageStats(0.5)
+		require.Nil(t, err, "Unable to measure image")
+		require.Equal(t, 32*24, stats.PixelCount, "Only the central half should be measured")
+		require.Equal(t, int64(maxADU), stats.Max)
+		require.Greater(t, stats.SaturatedPixels, 0)
+		require.Greater(t, stats.SaturatedFraction(), 0.0)
+		require.Greater(t, stats.StdDev, 1000.0)
+		require.Less(t, stats.Median, int64(saturatedADU), "Median should not be pulled up by the hot spot")
+	})
+
+	t.Run("binned dark", func(t *testing.T) {
+		fake, service := startFakeForSaving(t, ctrl)
+		defer fake.Close()
+		defer service.Close()
+		fake.SetSensor(64, 48, 3.8)
+
+		require.Nil(t, service.CaptureDarkFrame(2, 0.1, 0.0))
+		stats, err := service.ImageStats(1.0)
+		require.Nil(t, err, "Unable to measure image")
+		require.Equal(t, 32*24, stats.PixelCount)
+		require.InDelta(t, 1000.0, stats.Mean, 5.0)
+	})
+
+	t.Run("central fraction out of range", func(t *testing.T) {
+		service, _, _ := setUpConnectedMockService(ctrl)
+		_, err := service.ImageStats(1.5)
+		require.NotNil(t, err)
+		_, err = service.ImageStats(-0.1)
+		require.NotNil(t, err)
+	})
+
+	t.Run("parse statistics", func(t *testing.T) {
+		stats, err := parseImageStats("3072\t1300.25\t1300\t18.5\t1236\t1364\t0\t12")
+		require.Nil(t, err)
+		require.Equal(t, ImageStats{
+			PixelCount: 3072, Mean: 1300.25, Median: 1300, StdDev: 18.5, Min: 1236, Max: 1364, HotPixels: 12,
+		}, stats)
+	})
+
+	t.Run("parse malformed statistics", func(t *testing.T) {
+		var malformed *MalformedReplyError
+		_, err := parseImageStats("3072\t1300.25\t1300")
+		require.ErrorAs(t, err, &malformed)
+		_, err = parseImageStats("3072\t1300.25\t1300\tNaN-ish\t1236\t1364\t0\t0")
+		require.ErrorAs(t, err, &malformed)
+		_, err = parseImageStats("0\tNaN\t0\tNaN\t65535\t0\t0\t0")
+		require.ErrorAs(t, err, &malformed)
+	})
+}
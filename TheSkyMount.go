@@ -0,0 +1,231 @@
+package goTheSkyX
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+//	Minimal mount control, enough for calibration: slew to a flat panel's altitude and azimuth,
+//	switch tracking off so the panel stays in view, and park at the end of the session.  Slews
+//	and parks are started asynchronously and then polled, in the same way as captures.
+
+// TelescopePosition is a snapshot of where the mount is pointing and what it is doing
+type TelescopePosition struct {
+	Altitude float64 // Degrees above the horizon
+	Azimuth  float64 // Degrees east of north
+	Tracking bool
+	Parked   bool
+}
+
+// ErrSlewTimeout is returned (wrapped) when a slew or park doesn't finish in time
+var ErrSlewTimeout = errors.New("timed out waiting for mount to finish slewing")
+
+// ConnectTelescope asks TheSkyX to connect to the mount
+func (service *TheSkyServiceInstance) ConnectTelescope() error {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Println("TheSkyServiceInstance/ConnectTelescope ")
+	}
+	if !service.isOpen {
+		return errors.New("TheSkyServiceInstance/ConnectTelescope: Connection not open")
+	}
+	if err := service.driver.ConnectTelescope(); err != nil {
+		fmt.Println("TheSkyServiceInstance/ConnectTelescope error from driver:", err)
+		return err
+	}
+	return nil
+}
+
+// SlewToAltAz slews the mount to the given altitude and azimuth, in degrees, e.g. to point at
+// a flat panel, and waits for the slew to finish.  If it takes longer than the timeout,
+// ErrSlewTimeout is returned; if the context is cancelled, the slew is aborted.
+func (service *TheSkyServiceInstance) SlewToAltAz(altitude float64, azimuth float64, pollingIntervalSeconds int, timeoutMinutes int) error {
+	return service.SlewToAltAzContext(context.Background(), altitude, azimuth, pollingIntervalSeconds, timeoutMinutes)
+}
+
+func (service *TheSkyServiceInstance) SlewToAltAzContext(ctx context.Context, altitude float64, azimuth float64, pollingIntervalSeconds int, timeoutMinutes int) error {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Printf("TheSkyServiceInstance/SlewToAltAz(%g, %g)\n", altitude, azimuth)
+	}
+	if !service.isOpen {
+		return errors.New("TheSkyServiceInstance/SlewToAltAz: Connection not open")
+	}
+	if altitude < -90.0 || altitude > 90.0 {
+		return fmt.Errorf("TheSkyServiceInstance/SlewToAltAz: altitude %g is not between -90 and 90", altitude)
+	}
+	if azimuth < 0.0 || azimuth >= 360.0 {
+		return fmt.Errorf("TheSkyServiceInstance/SlewToAltAz: azimuth %g is not between 0 and 360", azimuth)
+	}
+	if pollingIntervalSeconds < 1 {
+		return errors.New("TheSkyServiceInstance/SlewToAltAz: polling interval must be at least 1 second")
+	}
+	if timeoutMinutes < 1 {
+		return errors.New("TheSkyServiceInstance/SlewToAltAz: timeout must be at least 1 minute")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := service.driverFor(ctx).StartSlewToAltAz(altitude, azimuth); err != nil {
+		fmt.Println("TheSkyServiceInstance/SlewToAltAz error from driver:", err)
+		return err
+	}
+	return service.waitForSlew(ctx, "SlewToAltAz", pollingIntervalSeconds, timeoutMinutes)
+}
+
+// Park moves the mount to its park position and waits for it to get there.  The mount stays
+// connected.  Timeout and cancellation are as for SlewToAltAz.
+func (service *TheSkyServiceInstance) Park(pollingIntervalSeconds int, timeoutMinutes int) error {
+	return service.ParkContext(context.Background(), pollingIntervalSeconds, timeoutMinutes)
+}
+
+func (service *TheSkyServiceInstance) ParkContext(ctx context.Context, pollingIntervalSeconds int, timeoutMinutes int) error {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Println("TheSkyServiceInstance/Park ")
+	}
+	if !service.isOpen {
+		return errors.New("TheSkyServiceInstance/Park: Connection not open")
+	}
+	if pollingIntervalSeconds < 1 {
+		return errors.New("TheSkyServiceInstance/Park: polling interval must be at least 1 second")
+	}
+	if timeoutMinutes < 1 {
+		return errors.New("TheSkyServiceInstance/Park: timeout must be at least 1 minute")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := service.driverFor(ctx).StartPark(); err != nil {
+		fmt.Println("TheSkyServiceInstance/Park error from driver:", err)
+		return err
+	}
+	return service.waitForSlew(ctx, "Park", pollingIntervalSeconds, timeoutMinutes)
+}
+
+// Unpark releases the mount from its park position so it can slew again
+func (service *TheSkyServiceInstance) Unpark() error {
+	return service.UnparkContext(context.Background())
+}
+
+func (service *TheSkyServiceInstance) UnparkContext(ctx context.Context) error {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Println("TheSkyServiceInstance/Unpark ")
+	}
+	if !service.isOpen {
+		return errors.New("TheSkyServiceInstance/Unpark: Connection not open")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := service.driverFor(ctx).Unpark(); err != nil {
+		fmt.Println("TheSkyServiceInstance/Unpark error from driver:", err)
+		return err
+	}
+	return nil
+}
+
+// SetTracking switches sidereal tracking on or off.  Turn it off after slewing to a flat
+// panel, or the mount will drift away from it.
+func (service *TheSkyServiceInstance) SetTracking(on bool) error {
+	return service.SetTrackingContext(context.Background(), on)
+}
+
+func (service *TheSkyServiceInstance) SetTrackingContext(ctx context.Context, on bool) error {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Printf("TheSkyServiceInstance/SetTracking(%t)\n", on)
+	}
+	if !service.isOpen {
+		return errors.New("TheSkyServiceInstance/SetTracking: Connection not open")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := service.driverFor(ctx).SetTracking(on); err != nil {
+		fmt.Println("TheSkyServiceInstance/SetTracking error from driver:", err)
+		return err
+	}
+	return nil
+}
+
+// GetTelescopePosition retrieves the mount's altitude, azimuth, tracking and parked state
+func (service *TheSkyServiceInstance) GetTelescopePosition() (TelescopePosition, error) {
+	return service.GetTelescopePositionContext(context.Background())
+}
+
+func (service *TheSkyServiceInstance) GetTelescopePositionContext(ctx context.Context) (TelescopePosition, error) {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Println("TheSkyServiceInstance/GetTelescopePosition ")
+	}
+	if !service.isOpen {
+		return TelescopePosition{}, errors.New("TheSkyServiceInstance/GetTelescopePosition: Connection not open")
+	}
+	if err := ctx.Err(); err != nil {
+		return TelescopePosition{}, err
+	}
+	position, err := service.driverFor(ctx).GetTelescopePosition()
+	if err != nil {
+		fmt.Println("TheSkyServiceInstance/GetTelescopePosition error from driver:", err)
+		return TelescopePosition{}, err
+	}
+	return position, nil
+}
+
+// IsSlewComplete reports whether the mount has finished its last slew or park
+func (service *TheSkyServiceInstance) IsSlewComplete() (bool, error) {
+	if service.verbosity >= 5 || service.debug {
+		fmt.Println("TheSkyServiceInstance/IsSlewComplete ")
+	}
+	if !service.isOpen {
+		return false, errors.New("TheSkyServiceInstance/IsSlewComplete: Connection not open")
+	}
+	complete, err := service.driver.IsSlewComplete()
+	if err != nil {
+		fmt.Println("TheSkyServiceInstance/IsSlewComplete error from driver:", err)
+		return false, err
+	}
+	return complete, nil
+}
+
+// waitForSlew polls the mount until its slew or park is complete.  If the context is cancelled
+// the slew is aborted, so the mount isn't left moving with nobody watching it.
+func (service *TheSkyServiceInstance) waitForSlew(ctx context.Context, operation string, pollingIntervalSeconds int, timeoutMinutes int) error {
+	maximumWaitSeconds := timeoutMinutes * 60
+	secondsWaitedSoFar := 0
+	for {
+		complete, err := service.driverFor(ctx).IsSlewComplete()
+		if err != nil {
+			fmt.Printf("TheSkyServiceInstance/%s error from IsSlewComplete: %v\n", operation, err)
+			return err
+		}
+		if complete {
+			if service.verbosity >= 4 {
+				fmt.Println("slew is complete, returning")
+			}
+			return nil
+		}
+		if secondsWaitedSoFar >= maximumWaitSeconds {
+			return fmt.Errorf("TheSkyServiceInstance/%s: %w after %d minutes", operation, ErrSlewTimeout, timeoutMinutes)
+		}
+		if service.verbosity >= 5 {
+			fmt.Printf("  Mount still slewing, waiting %d seconds to check again\n", pollingIntervalSeconds)
+		}
+		if err := service.delayContext(ctx, pollingIntervalSeconds); err != nil {
+			return service.abortSlewIfCancelled(ctx, err)
+		}
+		secondsWaitedSoFar += pollingIntervalSeconds
+	}
+}
+
+// abortSlewIfCancelled is abortIfCancelled for the mount: if the wait failed because the
+// context was cancelled, stop the slew and return ctx.Err()
+func (service *TheSkyServiceInstance) abortSlewIfCancelled(ctx context.Context, err error) error {
+	if ctx.Err() == nil || !errors.Is(err, ctx.Err()) {
+		return err
+	}
+	if service.verbosity >= 4 || service.debug {
+		fmt.Println("TheSkyServiceInstance: cancelled during slew, aborting slew")
+	}
+	if abortErr := service.driver.AbortSlew(); abortErr != nil {
+		fmt.Println("TheSkyServiceInstance error aborting slew:", abortErr)
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,197 @@
+package goTheSkyX
+
+import (
+	"context"
+	"github.com/RMcDOttawa/goMockableDelay"
+	"github.com/RMcDOttawa/goTheSkyX/fakeTheSkyX"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// startFakeForMount starts a fake server on a simulated clock, with a driver connected to the
+// mount.  The returned function advances the clock.
+func startFakeForMount(t *testing.T) (*fakeTheSkyX.FakeTheSkyServer, TheSkyDriver, func(time.Duration)) {
+	simulatedTime := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	fake := fakeTheSkyX.NewFakeTheSkyServer(false, 0)
+	fake.SetClock(func() time.Time { return simulatedTime })
+	fake.SetSlewRate(5.0)
+	fake.SetParkPosition(45.0, 0.0)
+	require.Nil(t, fake.Start(), "Unable to start fake server")
+	driver := NewTheSkyDriver(false, 0)
+	require.Nil(t, driver.Connect("localhost", fake.Port()), "Unable to connect driver")
+	require.Nil(t, driver.ConnectTelescope(), "Unable to connect telescope")
+	return fake, driver, func(elapsed time.Duration) { simulatedTime = simulatedTime.Add(elapsed) }
+}
+
+func TestMountDriver(t *testing.T) {
+
+	t.Run("slew to flat panel and stop tracking", func(t *testing.T) {
+		fake, driver, advanceClock := startFakeForMount(t)
+		defer fake.Close()
+		defer driver.Close()
+
+		position, err := driver.GetTelescopePosition()
+		require.Nil(t, err, "Unable to get position")
+		require.Equal(t, TelescopePosition{Altitude: 45.0, Azimuth: 0.0, Parked: true}, position)
+
+		require.Nil(t, driver.Unpark(), "Unable to unpark")
+		require.Nil(t, driver.SetTracking(true))
+		require.Nil(t, driver.StartSlewToAltAz(20.0, 90.0), "Unable to start slew")
+		complete, err := driver.IsSlewComplete()
+		require.Nil(t, err)
+		require.False(t, complete, "90 degree slew at 5 degrees/second should take 18 seconds")
+		advanceClock(18 * time.Second)
+		complete, err = driver.IsSlewComplete()
+		require.Nil(t, err)
+		require.True(t, complete)
+
+		require.Nil(t, driver.SetTracking(false))
+		position, err = driver.GetTelescopePosition()
+		require.Nil(t, err, "Unable to get position")
+		require.Equal(t, TelescopePosition{Altitude: 20.0, Azimuth: 90.0}, position)
+	})
+
+	t.Run("park and abort", func(t *testing.T) {
+		fake, driver, advanceClock := startFakeForMount(t)
+		defer fake.Close()
+		defer driver.Close()
+
+		require.Nil(t, driver.Unpark())
+		require.Nil(t, driver.StartSlewToAltAz(45.0, 50.0))
+		advanceClock(4 * time.Second)
+		require.Nil(t, driver.AbortSlew(), "Unable to abort slew")
+		position, err := driver.GetTelescopePosition()
+		require.Nil(t, err)
+		require.InDelta(t, 20.0, position.Azimuth, 0.001, "Abort should stop the mount where it is")
+
+		require.Nil(t, driver.SetTracking(true))
+		require.Nil(t, driver.StartPark(), "Unable to start park")
+		advanceClock(10 * time.Second)
+		complete, err := driver.IsSlewComplete()
+		require.Nil(t, err)
+		require.True(t, complete)
+		position, err = driver.GetTelescopePosition()
+		require.Nil(t, err, "Telescope should stay connected after parking")
+		require.Equal(t, TelescopePosition{Altitude: 45.0, Azimuth: 0.0, Parked: true}, position)
+	})
+
+	t.Run("parked mount refuses to slew", func(t *testing.T) {
+		fake, driver, _ := startFakeForMount(t)
+		defer fake.Close()
+		defer driver.Close()
+
+		require.ErrorIs(t, driver.StartSlewToAltAz(20.0, 90.0), ErrDeviceParked, "Expected parked mount to refuse to slew")
+	})
+
+	t.Run("telescope must be connected", func(t *testing.T) {
+		fake, driver := startFakeForDriver(t)
+		defer fake.Close()
+		defer driver.Close()
+
+		_, err := driver.GetTelescopePosition()
+		require.ErrorContains(t, err, "Telescope not connected")
+	})
+
+	t.Run("parse position with numeric flags", func(t *testing.T) {
+		position, err := parseTelescopePosition("12.5\t270\t1\t0")
+		require.Nil(t, err)
+		require.Equal(t, TelescopePosition{Altitude: 12.5, Azimuth: 270.0, Tracking: true}, position)
+		_, err = parseTelescopePosition("12.5\t270\tyes\t0")
+		var malformed *MalformedReplyError
+		require.ErrorAs(t, err, &malformed)
+	})
+}
+
+func TestMountService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("slew waits until complete", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		gomock.InOrder(
+			mockDriver.EXPECT().StartSlewToAltAz(20.0, 90.0).Return(nil),
+			mockDriver.EXPECT().IsSlewComplete().Return(false, nil).Times(2),
+			mockDriver.EXPECT().IsSlewComplete().Return(true, nil),
+		)
+		mockDelayService.EXPECT().DelayDuration(2).Return(2, nil).Times(2)
+
+		require.Nil(t, service.SlewToAltAz(20.0, 90.0, 2, 1), "Slew failed")
+	})
+
+	t.Run("slew times out", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		mockDriver.EXPECT().StartSlewToAltAz(20.0, 90.0).Return(nil)
+		mockDriver.EXPECT().IsSlewComplete().Return(false, nil).Times(4)
+		mockDelayService.EXPECT().DelayDuration(20).Return(20, nil).Times(3)
+
+		err := service.SlewToAltAz(20.0, 90.0, 20, 1)
+		require.ErrorIs(t, err, ErrSlewTimeout)
+	})
+
+	t.Run("cancelling aborts the slew", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		mockDriver.EXPECT().StartSlewToAltAz(20.0, 90.0).Return(nil)
+		mockDriver.EXPECT().IsSlewComplete().Return(false, nil)
+		mockDelayService.EXPECT().DelayDuration(2).DoAndReturn(func(int) (int, error) {
+			cancel()
+			return 2, nil
+		})
+		mockDriver.EXPECT().AbortSlew().Return(nil)
+
+		err := service.SlewToAltAzContext(ctx, 20.0, 90.0, 2, 1)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("invalid coordinates are rejected", func(t *testing.T) {
+		service, _, _ := setUpConnectedMockService(ctrl)
+		require.NotNil(t, service.SlewToAltAz(95.0, 90.0, 2, 1), "Expected error for altitude 95")
+		require.NotNil(t, service.SlewToAltAz(20.0, 360.0, 2, 1), "Expected error for azimuth 360")
+	})
+
+	t.Run("invalid polling interval and timeout are rejected", func(t *testing.T) {
+		service, _, _ := setUpConnectedMockService(ctrl)
+		require.NotNil(t, service.SlewToAltAz(20.0, 90.0, 0, 1), "Expected error for polling interval 0")
+		require.NotNil(t, service.SlewToAltAz(20.0, 90.0, -2, 1), "Expected error for negative polling interval")
+		require.NotNil(t, service.SlewToAltAz(20.0, 90.0, 2, 0), "Expected error for timeout 0")
+		require.NotNil(t, service.Park(0, 2), "Expected error for polling interval 0")
+		require.NotNil(t, service.Park(5, -1), "Expected error for negative timeout")
+	})
+
+	t.Run("park waits until complete", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		gomock.InOrder(
+			mockDriver.EXPECT().StartPark().Return(nil),
+			mockDriver.EXPECT().IsSlewComplete().Return(false, nil),
+			mockDriver.EXPECT().IsSlewComplete().Return(true, nil),
+		)
+		mockDelayService.EXPECT().DelayDuration(5).Return(5, nil)
+
+		require.Nil(t, service.Park(5, 2), "Park failed")
+	})
+
+	t.Run("flat panel session against fake server", func(t *testing.T) {
+		fake := fakeTheSkyX.NewFakeTheSkyServer(false, 0)
+		fake.SetSlewRate(1.0e6)
+		require.Nil(t, fake.Start(), "Unable to start fake server")
+		defer fake.Close()
+		service := NewTheSkyService(goMockableDelay.NewDelayService(false, 0), false, 0, true)
+		require.Nil(t, service.Connect("localhost", fake.Port()))
+		defer service.Close()
+
+		require.Nil(t, service.ConnectTelescope())
+		require.Nil(t, service.Unpark())
+		require.Nil(t, service.SlewToAltAz(10.0, 180.0, 1, 1), "Slew failed")
+		require.Nil(t, service.SetTracking(false))
+		position, err := service.GetTelescopePosition()
+		require.Nil(t, err)
+		require.Equal(t, TelescopePosition{Altitude: 10.0, Azimuth: 180.0}, position)
+		require.Nil(t, service.Park(1, 1), "Park failed")
+		position, err = service.GetTelescopePosition()
+		require.Nil(t, err)
+		require.True(t, position.Parked)
+	})
+}
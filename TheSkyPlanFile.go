@@ -0,0 +1,414 @@
+package goTheSkyX
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//	Calibration plan files, so a night's run can be changed by editing text rather than code.
+//	Plans are YAML; since JSON is a subset of YAML, JSON plan files are read by the same code.
+//	An example:
+//
+//		cooling:
+//		  target: -10           # degrees C
+//		  tolerance: 0.5
+//		  settleSeconds: 120
+//		  pollingSeconds: 10
+//		  timeoutMinutes: 30
+//		warmUp:                 # optional; omit to leave the cooler running
+//		  finalTemperature: 10
+//		  stepDegrees: 5
+//		  stepSeconds: 60
+//		frames:
+//		  - type: dark
+//		    binning: 1
+//		    exposures: [60, 120, 300]   # one set of "count" frames per exposure
+//		    count: 16
+//		  - type: bias
+//		    binning: 1
+//		    count: 32
+//		  - type: flat
+//		    binning: 1
+//		    filters: [Red, Green, Blue] # one set of "count" frames per filter
+//		    exposure: 2.5
+//		    count: 16
+//		    targetADU: 25000
+//		    aduTolerance: 0.1
+//
+//	Every error found reading or checking the file is a *PlanFileError giving the line at fault.
+
+// PlanFileError is a problem with a plan file, at the given line (1-based; 0 if unknown)
+type PlanFileError struct {
+	Line    int
+	Message string
+}
+
+func (e *PlanFileError) Error() string {
+	if e.Line == 0 {
+		return "plan file: " + e.Message
+	}
+	return fmt.Sprintf("plan file line %d: %s", e.Line, e.Message)
+}
+
+type planFile struct {
+	Cooling *planFileCooling `yaml:"cooling"`
+	WarmUp  *planFileWarmUp  `yaml:"warmUp"`
+	Frames  []planFileFrame  `yaml:"frames"`
+	lines   planFileLines
+}
+
+type planFileCooling struct {
+	Target         *float64 `yaml:"target"`
+	Tolerance      float64  `yaml:"tolerance"`
+	SettleSeconds  int      `yaml:"settleSeconds"`
+	PollingSeconds int      `yaml:"pollingSeconds"`
+	TimeoutMinutes int      `yaml:"timeoutMinutes"`
+	lines          planFileLines
+}
+
+type planFileWarmUp struct {
+	FinalTemperature *float64 `yaml:"finalTemperature"`
+	StepDegrees      float64  `yaml:"stepDegrees"`
+	StepSeconds      int      `yaml:"stepSeconds"`
+	lines            planFileLines
+}
+
+type planFileFrame struct {
+	Type         string    `yaml:"type"`
+	Binning      int       `yaml:"binning"`
+	Exposure     float64   `yaml:"exposure"`
+	Exposures    []float64 `yaml:"exposures"`
+	Count        int       `yaml:"count"`
+	Filter       string    `yaml:"filter"`
+	Filters      []string  `yaml:"filters"`
+	TargetADU    int64     `yaml:"targetADU"`
+	ADUTolerance float64   `yaml:"aduTolerance"`
+	lines        planFileLines
+}
+
+// Defaults for optional plan file settings
+const defaultPlanCoolingTolerance = 0.5
+const defaultPlanSettleSeconds = 60
+const defaultPlanPollingSeconds = 10
+const defaultPlanCoolingTimeout = 30
+const defaultPlanWarmUpStep = 5.0
+const defaultPlanWarmUpStepSeconds = 60
+const defaultPlanADUTolerance = 0.1
+
+// planFileLines records the line of each field in a mapping, and of the mapping itself,
+// so that errors found after decoding can still point at the right line
+type planFileLines struct {
+	start  int
+	fields map[string]int
+}
+
+// line returns the line of the named field, or of the whole mapping if the field is absent
+func (lines planFileLines) line(field string) int {
+	if line, found := lines.fields[field]; found {
+		return line
+	}
+	return lines.start
+}
+
+// LoadCalibrationPlan reads a YAML or JSON plan file and returns the plan it describes
+func LoadCalibrationPlan(path string) (CalibrationPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return CalibrationPlan{}, err
+	}
+	return ParseCalibrationPlan(data)
+}
+
+// ParseCalibrationPlan interprets the contents of a YAML or JSON plan file
+func ParseCalibrationPlan(data []byte) (CalibrationPlan, error) {
+	var file planFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&file); err != nil {
+		if errors.Is(err, io.EOF) {
+			return CalibrationPlan{}, &PlanFileError{Message: "plan file is empty"}
+		}
+		return CalibrationPlan{}, planFileErrorFrom(err)
+	}
+	return file.toPlan()
+}
+
+func (file *planFile) UnmarshalYAML(node *yaml.Node) error {
+	type plain planFile
+	lines, err := decodeKnownFields(node, (*plain)(file))
+	file.lines = lines
+	return err
+}
+
+func (cooling *planFileCooling) UnmarshalYAML(node *yaml.Node) error {
+	type plain planFileCooling
+	lines, err := decodeKnownFields(node, (*plain)(cooling))
+	cooling.lines = lines
+	return err
+}
+
+func (warmUp *planFileWarmUp) UnmarshalYAML(node *yaml.Node) error {
+	type plain planFileWarmUp
+	lines, err := decodeKnownFields(node, (*plain)(warmUp))
+	warmUp.lines = lines
+	return err
+}
+
+func (frame *planFileFrame) UnmarshalYAML(node *yaml.Node) error {
+	type plain planFileFrame
+	lines, err := decodeKnownFields(node, (*plain)(frame))
+	frame.lines = lines
+	return err
+}
+
+// decodeKnownFields decodes a mapping node into the target struct, rejecting keys that aren't
+// fields of the struct (so misspelled settings aren't silently ignored), and returns the line
+// of each key
+func decodeKnownFields(node *yaml.Node, target interface{}) (planFileLines, error) {
+	lines := planFileLines{start: node.Line, fields: make(map[string]int)}
+	if node.Kind != yaml.MappingNode {
+		return lines, &PlanFileError{Line: node.Line, Message: "expected a set of \"name: value\" settings"}
+	}
+	known := yamlFieldNames(reflect.TypeOf(target).Elem())
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if !known[key.Value] {
+			return lines, &PlanFileError{Line: key.Line, Message: fmt.Sprintf("unknown setting %q", key.Value)}
+		}
+		lines.fields[key.Value] = key.Line
+	}
+	if err := node.Decode(target); err != nil {
+		return lines, planFileErrorFrom(err)
+	}
+	return lines, nil
+}
+
+// yamlFieldNames returns the yaml names of a struct's fields
+func yamlFieldNames(structType reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+	for i := 0; i < structType.NumField(); i++ {
+		name, _, _ := strings.Cut(structType.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// yamlErrorLine finds the line number in the yaml package's error messages
+var yamlErrorLine = regexp.MustCompile(`line (\d+): (.*)`)
+
+// planFileErrorFrom converts an error from the yaml package into a PlanFileError
+func planFileErrorFrom(err error) error {
+	var planFileError *PlanFileError
+	if errors.As(err, &planFileError) {
+		return planFileError
+	}
+	message := err.Error()
+	var typeError *yaml.TypeError
+	if errors.As(err, &typeError) && len(typeError.Errors) > 0 {
+		message = typeError.Errors[0]
+	}
+	if match := yamlErrorLine.FindStringSubmatch(message); match != nil {
+		line, _ := strconv.Atoi(match[1])
+		return &PlanFileError{Line: line, Message: match[2]}
+	}
+	return &PlanFileError{Message: strings.TrimPrefix(message, "yaml: ")}
+}
+
+// toPlan checks the decoded file and converts it to a CalibrationPlan
+func (file *planFile) toPlan() (CalibrationPlan, error) {
+	var plan CalibrationPlan
+	if file.Cooling != nil {
+		cooling, err := file.Cooling.toSettings()
+		if err != nil {
+			return CalibrationPlan{}, err
+		}
+		plan.Cooling = &cooling
+	}
+	if file.WarmUp != nil {
+		warmUp, err := file.WarmUp.toSettings()
+		if err != nil {
+			return CalibrationPlan{}, err
+		}
+		plan.WarmUp = &warmUp
+	}
+	if len(file.Frames) == 0 {
+		return CalibrationPlan{}, &PlanFileError{Line: file.lines.line("frames"), Message: "plan has no frames"}
+	}
+	for _, frame := range file.Frames {
+		entries, err := frame.toEntries()
+		if err != nil {
+			return CalibrationPlan{}, err
+		}
+		plan.Entries = append(plan.Entries, entries...)
+	}
+	return plan, nil
+}
+
+func (cooling *planFileCooling) toSettings() (CoolingWaitSettings, error) {
+	if cooling.Target == nil {
+		return CoolingWaitSettings{}, &PlanFileError{Line: cooling.lines.start, Message: "cooling needs a target temperature"}
+	}
+	settings := CoolingWaitSettings{
+		TargetTemperature:      *cooling.Target,
+		Tolerance:              settingOrDefault(cooling.lines, "tolerance", cooling.Tolerance, defaultPlanCoolingTolerance),
+		SettleSeconds:          settingOrDefault(cooling.lines, "settleSeconds", cooling.SettleSeconds, defaultPlanSettleSeconds),
+		PollingIntervalSeconds: settingOrDefault(cooling.lines, "pollingSeconds", cooling.PollingSeconds, defaultPlanPollingSeconds),
+		TimeoutMinutes:         settingOrDefault(cooling.lines, "timeoutMinutes", cooling.TimeoutMinutes, defaultPlanCoolingTimeout),
+	}
+	if settings.Tolerance < 0 {
+		return settings, &PlanFileError{Line: cooling.lines.line("tolerance"), Message: "tolerance can't be negative"}
+	}
+	if settings.SettleSeconds < 0 {
+		return settings, &PlanFileError{Line: cooling.lines.line("settleSeconds"), Message: "settleSeconds can't be negative"}
+	}
+	if settings.PollingIntervalSeconds < 1 {
+		return settings, &PlanFileError{Line: cooling.lines.line("pollingSeconds"), Message: "pollingSeconds must be at least 1"}
+	}
+	if settings.TimeoutMinutes < 1 {
+		return settings, &PlanFileError{Line: cooling.lines.line("timeoutMinutes"), Message: "timeoutMinutes must be at least 1"}
+	}
+	return settings, nil
+}
+
+func (warmUp *planFileWarmUp) toSettings() (WarmUpSettings, error) {
+	if warmUp.FinalTemperature == nil {
+		return WarmUpSettings{}, &PlanFileError{Line: warmUp.lines.start, Message: "warmUp needs a finalTemperature"}
+	}
+	settings := WarmUpSettings{
+		FinalTemperature:    *warmUp.FinalTemperature,
+		StepDegrees:         settingOrDefault(warmUp.lines, "stepDegrees", warmUp.StepDegrees, defaultPlanWarmUpStep),
+		StepIntervalSeconds: settingOrDefault(warmUp.lines, "stepSeconds", warmUp.StepSeconds, defaultPlanWarmUpStepSeconds),
+	}
+	if settings.StepDegrees <= 0 {
+		return settings, &PlanFileError{Line: warmUp.lines.line("stepDegrees"), Message: "stepDegrees must be greater than 0"}
+	}
+	if settings.StepIntervalSeconds < 1 {
+		return settings, &PlanFileError{Line: warmUp.lines.line("stepSeconds"), Message: "stepSeconds must be at least 1"}
+	}
+	return settings, nil
+}
+
+// toEntries expands one frames item into plan entries - one per exposure, or per filter
+func (frame *planFileFrame) toEntries() ([]CalibrationPlanEntry, error) {
+	lineError := func(field string, format string, args ...interface{}) error {
+		return &PlanFileError{Line: frame.lines.line(field), Message: fmt.Sprintf(format, args...)}
+	}
+	var frameType FrameType
+	switch strings.ToLower(frame.Type) {
+	case "dark":
+		frameType = DarkFrame
+	case "bias":
+		frameType = BiasFrame
+	case "flat":
+		frameType = FlatFrame
+	case "":
+		return nil, lineError("type", "frame type is missing (dark, bias or flat)")
+	default:
+		return nil, lineError("type", "unknown frame type %q (expected dark, bias or flat)", frame.Type)
+	}
+	if frame.Binning < 1 {
+		return nil, lineError("binning", "binning must be at least 1")
+	}
+	if frame.Count < 1 {
+		return nil, lineError("count", "count must be at least 1")
+	}
+
+	exposures := frame.Exposures
+	if _, given := frame.lines.fields["exposure"]; given {
+		if len(exposures) > 0 {
+			return nil, lineError("exposure", "give either exposure or exposures, not both")
+		}
+		exposures = []float64{frame.Exposure}
+	}
+	if frameType == BiasFrame {
+		if len(exposures) > 0 {
+			return nil, lineError("exposure", "bias frames don't have an exposure")
+		}
+		exposures = []float64{0.0}
+	} else {
+		if len(exposures) == 0 {
+			return nil, lineError("type", "%s frames need an exposure", frameType)
+		}
+		for _, exposure := range exposures {
+			if exposure <= 0 {
+				field := "exposure"
+				if len(frame.Exposures) > 0 {
+					field = "exposures"
+				}
+				return nil, lineError(field, "exposure must be greater than 0, not %g", exposure)
+			}
+		}
+	}
+
+	filters := frame.Filters
+	if frame.Filter != "" {
+		if len(filters) > 0 {
+			return nil, lineError("filter", "give either filter or filters, not both")
+		}
+		filters = []string{frame.Filter}
+	}
+	aduTolerance := frame.ADUTolerance
+	if frameType == FlatFrame {
+		if len(filters) == 0 {
+			return nil, lineError("type", "flat frames need a filter")
+		}
+		if frame.TargetADU < 0 || frame.TargetADU > maxADU {
+			return nil, lineError("targetADU", "targetADU must be between 0 and %d", maxADU)
+		}
+		if _, given := frame.lines.fields["aduTolerance"]; !given {
+			aduTolerance = defaultPlanADUTolerance
+		}
+		if aduTolerance < 0 || aduTolerance >= 1 {
+			return nil, lineError("aduTolerance", "aduTolerance must be a fraction between 0 and 1")
+		}
+	} else {
+		for _, field := range []string{"filter", "filters", "targetADU", "aduTolerance"} {
+			if _, given := frame.lines.fields[field]; given {
+				return nil, lineError(field, "%s only applies to flat frames", field)
+			}
+		}
+		filters = []string{""}
+	}
+
+	var entries []CalibrationPlanEntry
+	for _, filter := range filters {
+		for _, exposure := range exposures {
+			entries = append(entries, CalibrationPlanEntry{
+				FrameType:       frameType,
+				Binning:         frame.Binning,
+				ExposureSeconds: exposure,
+				Count:           frame.Count,
+				Filter:          filter,
+				TargetADU:       frame.TargetADU,
+				ADUTolerance:    aduTolerance,
+			})
+		}
+	}
+	return entries, nil
+}
+
+// settingOrDefault returns the value, or the default if the named field was not in the file.
+// An explicit zero is kept, and left to the range checks.
+func settingOrDefault[T int | float64](lines planFileLines, field string, value T, defaultValue T) T {
+	if _, given := lines.fields[field]; !given {
+		return defaultValue
+	}
+	return value
+}
+
+// valueOrDefault returns the value, or the default if the value was not given (is zero)
+func valueOrDefault[T int | float64](value T, defaultValue T) T {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
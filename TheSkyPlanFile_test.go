@@ -0,0 +1,141 @@
+package goTheSkyX
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const examplePlanYAML = `# A typical night
+cooling:
+  target: -10
+  settleSeconds: 120
+warmUp:
+  finalTemperature: 10
+frames:
+  - type: dark
+    binning: 1
+    exposures: [60, 120, 300]
+    count: 16
+  - type: bias
+    binning: 1
+    count: 32
+  - type: flat
+    binning: 2
+    filters: [Red, Green, Blue]
+    exposure: 2.5
+    count: 16
+    targetADU: 25000
+`
+
+const examplePlanJSON = `{
+	"cooling": {"target": -10, "settleSeconds": 120},
+	"warmUp": {"finalTemperature": 10},
+	"frames": [
+		{"type": "dark", "binning": 1, "exposures": [60, 120, 300], "count": 16},
+		{"type": "bias", "binning": 1, "count": 32},
+		{"type": "flat", "binning": 2, "filters": ["Red", "Green", "Blue"], "exposure": 2.5,
+			"count": 16, "targetADU": 25000}
+	]
+}`
+
+func TestParseCalibrationPlan(t *testing.T) {
+
+	checkExamplePlan := func(t *testing.T, plan CalibrationPlan) {
+		require.NotNil(t, plan.Cooling)
+		require.Equal(t, CoolingWaitSettings{
+			TargetTemperature:      -10.0,
+			Tolerance:              defaultPlanCoolingTolerance,
+			SettleSeconds:          120,
+			PollingIntervalSeconds: defaultPlanPollingSeconds,
+			TimeoutMinutes:         defaultPlanCoolingTimeout,
+		}, *plan.Cooling)
+		require.NotNil(t, plan.WarmUp)
+		require.Equal(t, 10.0, plan.WarmUp.FinalTemperature)
+		require.Len(t, plan.Entries, 7)
+		require.Equal(t, CalibrationPlanEntry{FrameType: DarkFrame, Binning: 1, ExposureSeconds: 300, Count: 16}, plan.Entries[2])
+		require.Equal(t, CalibrationPlanEntry{FrameType: BiasFrame, Binning: 1, Count: 32}, plan.Entries[3])
+		require.Equal(t, CalibrationPlanEntry{FrameType: FlatFrame, Binning: 2, ExposureSeconds: 2.5, Count: 16,
+			Filter: "Green", TargetADU: 25000, ADUTolerance: defaultPlanADUTolerance}, plan.Entries[5])
+		require.Nil(t, plan.Validate())
+	}
+
+	t.Run("YAML plan", func(t *testing.T) {
+		plan, err := ParseCalibrationPlan([]byte(examplePlanYAML))
+		require.Nil(t, err, "Unable to parse plan")
+		checkExamplePlan(t, plan)
+	})
+
+	t.Run("JSON plan", func(t *testing.T) {
+		plan, err := ParseCalibrationPlan([]byte(examplePlanJSON))
+		require.Nil(t, err, "Unable to parse plan")
+		checkExamplePlan(t, plan)
+	})
+
+	t.Run("load from file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "plan.yaml")
+		require.Nil(t, os.WriteFile(path, []byte(examplePlanYAML), 0o644))
+		plan, err := LoadCalibrationPlan(path)
+		require.Nil(t, err, "Unable to load plan")
+		checkExamplePlan(t, plan)
+	})
+
+	t.Run("errors give the offending line", func(t *testing.T) {
+		badPlans := []struct {
+			name    string
+			plan    string
+			line    int
+			message string
+		}{
+			{"unknown setting", "frames:\n  - type: dark\n    binning: 1\n    exposre: 60\n    count: 1\n", 4, "unknown setting \"exposre\""},
+			{"wrong value type", "frames:\n  - type: bias\n    binning: two\n    count: 1\n", 3, "cannot unmarshal"},
+			{"unknown frame type", "frames:\n  - type: light\n    binning: 1\n    count: 1\n", 2, "unknown frame type"},
+			{"bad count", "frames:\n  - type: bias\n    binning: 1\n    count: 0\n", 4, "count must be at least 1"},
+			{"missing count", "frames:\n  - type: bias\n    binning: 1\n", 2, "count must be at least 1"},
+			{"dark without exposure", "frames:\n  - type: bias\n    binning: 1\n    count: 1\n  - type: dark\n    binning: 1\n    count: 1\n", 5, "need an exposure"},
+			{"negative exposure in list", "frames:\n  - type: dark\n    binning: 1\n    count: 1\n    exposures: [10, -5]\n", 5, "exposure must be greater than 0"},
+			{"filter on a dark", "frames:\n  - type: dark\n    binning: 1\n    count: 1\n    exposure: 10\n    filter: Red\n", 6, "only applies to flat frames"},
+			{"flat without filter", "frames:\n  - type: flat\n    binning: 1\n    count: 1\n    exposure: 1\n", 2, "need a filter"},
+			{"target ADU too high", "frames:\n  - type: flat\n    binning: 1\n    count: 1\n    exposure: 1\n    filter: Red\n    targetADU: 70000\n", 7, "targetADU"},
+			{"cooling without target", "cooling:\n  tolerance: 1\nframes:\n  - type: bias\n    binning: 1\n    count: 1\n", 2, "target temperature"},
+			{"no frames", "cooling:\n  target: -10\n", 1, "no frames"},
+			{"syntax error", "frames:\n  - type: bias\n    binning: 1\n    count: : 2\n", 4, "mapping values are not allowed"},
+			{"zero polling interval", "cooling:\n  target: -10\n  pollingSeconds: 0\nframes:\n  - type: bias\n    binning: 1\n    count: 1\n", 3, "pollingSeconds must be at least 1"},
+			{"zero warm-up step", "warmUp:\n  finalTemperature: 10\n  stepDegrees: 0\nframes:\n  - type: bias\n    binning: 1\n    count: 1\n", 3, "stepDegrees must be greater than 0"},
+		}
+		for _, bad := range badPlans {
+			_, err := ParseCalibrationPlan([]byte(bad.plan))
+			var planFileError *PlanFileError
+			require.ErrorAs(t, err, &planFileError, bad.name)
+			require.Equal(t, bad.line, planFileError.Line, "%s: %v", bad.name, err)
+			require.Contains(t, planFileError.Message, bad.message, bad.name)
+		}
+	})
+
+	t.Run("explicit zero settings are kept, not defaulted", func(t *testing.T) {
+		plan, err := ParseCalibrationPlan([]byte("cooling:\n  target: -10\n  tolerance: 0\n  settleSeconds: 0\nframes:\n  - type: bias\n    binning: 1\n    count: 1\n"))
+		require.Nil(t, err, "Unable to parse plan")
+		require.NotNil(t, plan.Cooling)
+		require.Equal(t, 0.0, plan.Cooling.Tolerance)
+		require.Equal(t, 0, plan.Cooling.SettleSeconds)
+		require.Equal(t, defaultPlanPollingSeconds, plan.Cooling.PollingIntervalSeconds)
+	})
+
+	t.Run("empty plan file", func(t *testing.T) {
+		for _, empty := range []string{"", "# nothing here\n"} {
+			_, err := ParseCalibrationPlan([]byte(empty))
+			var planFileError *PlanFileError
+			require.ErrorAs(t, err, &planFileError)
+			require.Equal(t, "plan file: plan file is empty", err.Error())
+		}
+	})
+
+	t.Run("JSON errors give the offending line", func(t *testing.T) {
+		_, err := ParseCalibrationPlan([]byte("{\n  \"frames\": [\n    {\"type\": \"bias\", \"binning\": 1, \"count\": -2}\n  ]\n}\n"))
+		var planFileError *PlanFileError
+		require.ErrorAs(t, err, &planFileError)
+		require.Equal(t, 3, planFileError.Line)
+		require.ErrorContains(t, err, "plan file line 3: count must be at least 1")
+	})
+}
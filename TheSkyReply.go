@@ -0,0 +1,78 @@
+package goTheSkyX
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+//	Reading and parsing replies from the TheSkyX server.
+//
+//	A reply has the form  <data, if any>|<error line>  where the error line always ends with
+//	"Error = N." - e.g. "-10\n|No error. Error = 0.".  A reply may arrive split across several
+//	TCP segments, so we keep reading until we have seen that terminating error line.
+
+// ErrReplyTooLarge is returned (wrapped) when a reply exceeds the driver's maximum reply size
+var ErrReplyTooLarge = errors.New("TheSkyX reply too large")
+
+// ErrReplyTimeout is returned (wrapped) when the complete reply does not arrive within the
+// driver's reply timeout
+var ErrReplyTimeout = errors.New("timed out waiting for TheSkyX reply")
+
+// MalformedReplyError is returned when a reply from the server can't be understood
+type MalformedReplyError struct {
+	Reason string
+	Reply  string
+}
+
+func (e *MalformedReplyError) Error() string {
+	return fmt.Sprintf("malformed TheSkyX reply (%s): %q", e.Reason, e.Reply)
+}
+
+const defaultMaxReplySize = 1024 * 1024
+const defaultReplyTimeout = 5 * time.Minute
+
+// replyTerminator matches the error line that ends every reply
+var replyTerminator = regexp.MustCompile(`\|[^|]*Error = -?\d+\.\s*$`)
+
+// readReply reads from the connection until a complete reply has arrived, and returns it.
+func readReply(conn net.Conn, maxReplySize int) (string, error) {
+	var reply []byte
+	buffer := make([]byte, maxTheSkyBuffer)
+	for {
+		numRead, err := conn.Read(buffer)
+		reply = append(reply, buffer[:numRead]...)
+		if len(reply) > maxReplySize {
+			return "", fmt.Errorf("%w: more than %d bytes", ErrReplyTooLarge, maxReplySize)
+		}
+		if replyTerminator.Match(reply) {
+			return string(reply), nil
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(reply) > 0 {
+				return "", &MalformedReplyError{Reason: "connection closed before end of reply", Reply: string(reply)}
+			}
+			return "", err
+		}
+	}
+}
+
+// embeddedReplyEnd matches the end of a reply inside the data part of another, which means
+// the replies are out of step with the commands
+var embeddedReplyEnd = regexp.MustCompile(`\|[^|]*Error = -?\d+\.`)
+
+// parseReply splits a complete reply into its data and its error line
+func parseReply(reply string) (string, string, error) {
+	separator := strings.LastIndex(reply, "|")
+	if separator < 0 {
+		return "", "", &MalformedReplyError{Reason: "no \"|\" separator", Reply: reply}
+	}
+	if embeddedReplyEnd.MatchString(reply[:separator]) {
+		return "", "", &MalformedReplyError{Reason: "more than one reply", Reply: reply}
+	}
+	return reply[:separator], strings.TrimSpace(reply[separator+1:]), nil
+}
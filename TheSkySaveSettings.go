@@ -0,0 +1,183 @@
+package goTheSkyX
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//	Where captured frames are saved and what they are called.  By default TheSkyX's AutoSave
+//	saves them, to its AutoSave directory with its own names.  With SetSaveSettings the
+//	service saves each frame itself, to a given directory with a name made from a template,
+//	and with extra FITS keywords in its header, e.g.
+//
+//		service.SetSaveSettings(SaveSettings{
+//			Directory:        `D:\Calibration\Darks`,
+//			FileNameTemplate: "{type}_{exposure}s_bin{binning}_{temperature}C_{sequence}",
+//			FITSKeywords:     map[string]string{"OBSERVER": "RMcD", "LIBRARY": "2024-01"},
+//		})
+//
+//	gives files like "dark_300s_bin1_-10C_0042.fit".  The template placeholders are:
+//
+//		{type}         dark, bias or flat
+//		{exposure}     exposure in seconds
+//		{binning}      binning, e.g. 2 for 2x2
+//		{temperature}  sensor temperature when the frame was saved, to the nearest degree
+//		{filter}       filter name; empty for darks and bias frames
+//		{sequence}     4-digit count of frames saved since SetSaveSettings, starting at 1
+
+// SaveSettings controls how the service saves captured frames.  The zero value leaves saving
+// to TheSkyX's AutoSave.
+type SaveSettings struct {
+	Directory        string            // On the TheSkyX computer; "" for TheSkyX's AutoSave directory
+	FileNameTemplate string            // See above; "" for defaultFileNameTemplate.  ".fit" is added.
+	FITSKeywords     map[string]string // Added to each frame's header, replacing any of the same name
+}
+
+// FITSKeyword is one header keyword added to a saved frame
+type FITSKeyword struct {
+	Name  string
+	Value string
+}
+
+const defaultFileNameTemplate = "{type}_{exposure}s_{binning}x{binning}_{sequence}"
+
+// capturedFrame describes the frame just captured, for naming it
+type capturedFrame struct {
+	frameType  FrameType
+	exposure   float64
+	binning    int
+	filterSlot int
+}
+
+var templatePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+var fitsKeywordName = regexp.MustCompile(`^[A-Z0-9_-]{1,8}$`)
+var unsafeFileNameCharacters = regexp.MustCompile(`[^A-Za-z0-9._+-]+`)
+
+var knownTemplatePlaceholders = map[string]bool{
+	"{type}": true, "{exposure}": true, "{binning}": true, "{temperature}": true, "{filter}": true, "{sequence}": true,
+}
+
+func (settings SaveSettings) isZero() bool {
+	return settings.Directory == "" && settings.FileNameTemplate == "" && len(settings.FITSKeywords) == 0
+}
+
+func (settings SaveSettings) validate() error {
+	for _, placeholder := range templatePlaceholder.FindAllString(settings.FileNameTemplate, -1) {
+		if !knownTemplatePlaceholders[placeholder] {
+			return fmt.Errorf("unknown placeholder %s in file name template", placeholder)
+		}
+	}
+	leftOver := templatePlaceholder.ReplaceAllString(settings.FileNameTemplate, "")
+	if strings.ContainsAny(leftOver, "{}") {
+		return fmt.Errorf("unmatched brace in file name template %q", settings.FileNameTemplate)
+	}
+	if strings.ContainsAny(leftOver, `/\:`) {
+		return fmt.Errorf("file name template %q must not contain a directory; use Directory", settings.FileNameTemplate)
+	}
+	for name := range settings.FITSKeywords {
+		if !fitsKeywordName.MatchString(name) {
+			return fmt.Errorf("invalid FITS keyword %q: must be 1 to 8 upper case letters, digits, - or _", name)
+		}
+	}
+	return nil
+}
+
+// SetSaveSettings sets where, and under what names, captured frames are saved, and what FITS
+// keywords are added to them.  Passing the zero value goes back to TheSkyX's AutoSave.  The
+// frame sequence number restarts at 1.
+func (service *TheSkyServiceInstance) SetSaveSettings(settings SaveSettings) error {
+	if err := settings.validate(); err != nil {
+		return fmt.Errorf("TheSkyServiceInstance/SetSaveSettings: %w", err)
+	}
+	service.saveSequence = 0
+	if settings.isZero() {
+		service.saveSettings = nil
+		service.driver.SetAutoSave(true)
+		return nil
+	}
+	service.saveSettings = &settings
+	service.driver.SetAutoSave(false)
+	return nil
+}
+
+// saveCapturedFrame saves the frame just captured according to the save settings, and returns
+// the path it was saved to.  If there are no save settings, TheSkyX's AutoSave has saved it
+// already and we don't know where, so "" is returned.
+func (service *TheSkyServiceInstance) saveCapturedFrame(ctx context.Context, frame capturedFrame) (string, error) {
+	if service.saveSettings == nil {
+		return "", nil
+	}
+	return service.saveCapturedFrameWith(ctx, service.saveSettings, frame)
+}
+
+// saveCapturedFrameWith saves the frame just captured according to the given save settings,
+// and returns the path it was saved to
+func (service *TheSkyServiceInstance) saveCapturedFrameWith(ctx context.Context, settings *SaveSettings, frame capturedFrame) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	service.saveSequence++
+	fileName, err := service.expandFileNameTemplate(ctx, settings, frame)
+	if err != nil {
+		return "", err
+	}
+	var keywords []FITSKeyword
+	for name, value := range settings.FITSKeywords {
+		keywords = append(keywords, FITSKeyword{Name: name, Value: value})
+	}
+	sort.Slice(keywords, func(i, j int) bool { return keywords[i].Name < keywords[j].Name })
+
+	path, err := service.driverFor(ctx).SaveImage(strings.TrimRight(settings.Directory, `/\`), fileName, keywords)
+	if err != nil {
+		fmt.Println("TheSkyServiceInstance error from driver saving image:", err)
+		return "", err
+	}
+	if service.verbosity >= 4 || service.debug {
+		fmt.Println("TheSkyServiceInstance: saved frame to", path)
+	}
+	return path, nil
+}
+
+// expandFileNameTemplate makes the file name for a frame.  The temperature and filter name are
+// only asked for if the template uses them.
+func (service *TheSkyServiceInstance) expandFileNameTemplate(ctx context.Context, settings *SaveSettings, frame capturedFrame) (string, error) {
+	template := settings.FileNameTemplate
+	if template == "" {
+		template = defaultFileNameTemplate
+	}
+	values := map[string]string{
+		"{type}":     frame.frameType.String(),
+		"{exposure}": fmt.Sprintf("%g", frame.exposure),
+		"{binning}":  fmt.Sprintf("%d", frame.binning),
+		"{sequence}": fmt.Sprintf("%04d", service.saveSequence),
+		"{filter}":   "",
+	}
+	if strings.Contains(template, "{temperature}") {
+		temperature, err := service.driverFor(ctx).GetCameraTemperature()
+		if err != nil {
+			fmt.Println("TheSkyServiceInstance error from driver getting temperature for file name:", err)
+			return "", err
+		}
+		values["{temperature}"] = fmt.Sprintf("%d", int(math.Round(temperature)))
+	}
+	if strings.Contains(template, "{filter}") && frame.filterSlot != FilterSlotNoFilter {
+		filterNames, err := service.driverFor(ctx).FilterNames()
+		if err != nil {
+			fmt.Println("TheSkyServiceInstance error from driver getting filter name for file name:", err)
+			return "", err
+		}
+		if frame.filterSlot < 1 || frame.filterSlot > len(filterNames) {
+			return "", errors.New("TheSkyServiceInstance: no filter name for file name")
+		}
+		values["{filter}"] = strings.TrimSpace(filterNames[frame.filterSlot-1])
+	}
+	fileName := templatePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		return unsafeFileNameCharacters.ReplaceAllString(values[placeholder], "_")
+	})
+	return fileName + ".fit", nil
+}
@@ -0,0 +1,97 @@
+package goTheSkyX
+
+import (
+	"github.com/RMcDOttawa/goMockableDelay"
+	"github.com/RMcDOttawa/goTheSkyX/fakeTheSkyX"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// startFakeForSaving starts a fake server on a simulated clock, with the camera at -10, and a
+// service connected to it whose delays advance the clock instead of waiting
+func startFakeForSaving(t *testing.T, ctrl *gomock.Controller) (*fakeTheSkyX.FakeTheSkyServer, TheSkyService) {
+	simulatedTime := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	fake := fakeTheSkyX.NewFakeTheSkyServer(false, 0)
+	fake.SetClock(func() time.Time { return simulatedTime })
+	fake.SetAmbientTemperature(-10.0)
+	require.Nil(t, fake.Start(), "Unable to start fake server")
+	mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
+	mockDelayService.EXPECT().DelayDuration(gomock.Any()).DoAndReturn(func(seconds int) (int, error) {
+		simulatedTime = simulatedTime.Add(time.Duration(seconds) * time.Second)
+		return seconds, nil
+	}).AnyTimes()
+	service := NewTheSkyService(mockDelayService, false, 0, false)
+	require.Nil(t, service.Connect("localhost", fake.Port()), "Unable to connect service")
+	return fake, service
+}
+
+func TestSaveSettings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("darks saved with templated names and keywords", func(t *testing.T) {
+		fake, service := startFakeForSaving(t, ctrl)
+		defer fake.Close()
+		defer service.Close()
+
+		require.Nil(t, service.SetSaveSettings(SaveSettings{
+			Directory:        `D:\Calibration\Darks\`,
+			FileNameTemplate: "{type}_{exposure}s_bin{binning}_{temperature}C_{sequence}",
+			FITSKeywords:     map[string]string{"OBSERVER": "R \"Mac\" McD", "LIBRARY": "2024-01"},
+		}))
+		require.Nil(t, service.CaptureDarkFrame(2, 0.05, 0.0))
+		require.Nil(t, service.CaptureBiasFrame(1, 0.0))
+
+		saved := fake.SavedImages()
+		require.Len(t, saved, 2)
+		require.Equal(t, `D:\Calibration\Darks/dark_0.05s_bin2_-10C_0001.fit`, saved[0].Path)
+		require.Equal(t, "R \"Mac\" McD", saved[0].Keywords["OBSERVER"])
+		require.Equal(t, "2024-01", saved[0].Keywords["LIBRARY"])
+		require.Equal(t, `D:\Calibration\Darks/bias_0s_bin1_-10C_0002.fit`, saved[1].Path)
+		require.Equal(t, 0, fake.SavedImageCount(), "AutoSave should be off")
+	})
+
+	t.Run("flats saved to AutoSave directory with filter name", func(t *testing.T) {
+		fake, service := startFakeForSaving(t, ctrl)
+		defer fake.Close()
+		defer service.Close()
+		fake.SetFilterNames([]string{"Red", "Green", "H-alpha 7nm"})
+
+		require.Nil(t, service.SetSaveSettings(SaveSettings{FileNameTemplate: "{filter}_{sequence}"}))
+		_, err := service.CaptureAndMeasureFlatFrame(0.5, 1, 3, 0.0, true)
+		require.Nil(t, err)
+		_, err = service.CaptureAndMeasureFlatFrame(0.5, 1, 3, 0.0, false)
+		require.Nil(t, err)
+
+		saved := fake.SavedImages()
+		require.Len(t, saved, 1, "Unsaved test exposure should not be saved")
+		require.Regexp(t, `/Camera AutoSave/H-alpha_7nm_0001\.fit$`, saved[0].Path)
+	})
+
+	t.Run("zero settings go back to AutoSave", func(t *testing.T) {
+		fake, service := startFakeForSaving(t, ctrl)
+		defer fake.Close()
+		defer service.Close()
+
+		require.Nil(t, service.SetSaveSettings(SaveSettings{Directory: "/data"}))
+		require.Nil(t, service.CaptureDarkFrame(1, 0.05, 0.0))
+		require.Nil(t, service.SetSaveSettings(SaveSettings{}))
+		require.Nil(t, service.CaptureDarkFrame(1, 0.05, 0.0))
+
+		saved := fake.SavedImages()
+		require.Len(t, saved, 1)
+		require.Equal(t, "/data/dark_0.05s_1x1_0001.fit", saved[0].Path, "Default template should be used")
+		require.Equal(t, 1, fake.SavedImageCount())
+	})
+
+	t.Run("invalid settings are rejected", func(t *testing.T) {
+		service := NewTheSkyService(nil, false, 0, false)
+		require.NotNil(t, service.SetSaveSettings(SaveSettings{FileNameTemplate: "{type}_{gain}"}))
+		require.NotNil(t, service.SetSaveSettings(SaveSettings{FileNameTemplate: "{type"}))
+		require.NotNil(t, service.SetSaveSettings(SaveSettings{FileNameTemplate: "darks/{type}"}))
+		require.NotNil(t, service.SetSaveSettings(SaveSettings{FITSKeywords: map[string]string{"observer": "x"}}))
+		require.NotNil(t, service.SetSaveSettings(SaveSettings{FITSKeywords: map[string]string{"TOOLONGNAME": "x"}}))
+	})
+}
@@ -0,0 +1,436 @@
+package goTheSkyX
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/RMcDOttawa/goMockableDelay"
+	"math"
+)
+
+//	CalibrationSequencer runs a calibration plan - a list of dark, bias and flat frame sets - from
+//	start to finish: cool the camera, measure the download time for each binning, capture each
+//	set, and report what was captured.  Frames that fail with a transient error (lost link,
+//	reply timeout) are retried; frames that still fail are recorded and the plan carries on.
+//	Flats with a target ADU have their exposure found with FindFlatExposure first, and a flat
+//	that still comes out of tolerance is rejected and shot again.
+
+type CalibrationSequencer interface {
+	Run(plan CalibrationPlan) (CalibrationReport, error)
+	RunContext(ctx context.Context, plan CalibrationPlan) (CalibrationReport, error)
+	SetRetries(maxRetries int, retryDelaySeconds int)
+}
+
+type CalibrationSequencerInstance struct {
+	service           TheSkyService
+	delayService      goMockableDelay.DelayService
+	debug             bool
+	verbosity         int
+	maxRetries        int
+	retryDelaySeconds int
+}
+
+// FrameType is the kind of calibration frame in a plan entry
+type FrameType int
+
+const (
+	DarkFrame FrameType = iota
+	BiasFrame
+	FlatFrame
+)
+
+func (frameType FrameType) String() string {
+	switch frameType {
+	case DarkFrame:
+		return "dark"
+	case BiasFrame:
+		return "bias"
+	case FlatFrame:
+		return "flat"
+	}
+	return fmt.Sprintf("FrameType(%d)", int(frameType))
+}
+
+// CalibrationPlanEntry is one set of identical frames
+type CalibrationPlanEntry struct {
+	FrameType       FrameType
+	Binning         int
+	ExposureSeconds float64 // Ignored for bias frames; for flats with a TargetADU, the first exposure tried
+	Count           int
+	// Flat frames only
+	Filter       string  // Filter name, as configured in TheSkyX
+	TargetADU    int64   // Average ADU the flats should have; 0 to take them all at ExposureSeconds
+	ADUTolerance float64 // Fraction of TargetADU a flat may be off by, e.g. 0.1 for 10%
+}
+
+// ErrFlatADUOutOfRange is recorded (wrapped) against flat frames whose average ADU is not
+// within tolerance of the plan's target.  They have been saved, so should be deleted.
+var ErrFlatADUOutOfRange = errors.New("flat frame ADU out of range")
+
+// flatSearchRange is how far from the plan's exposure we look for the exposure giving flats
+// of the target ADU: up to this many times shorter or longer
+const flatSearchRange = 10.0
+
+// CalibrationPlan is the list of frame sets to capture, and how to look after the cooler.
+// If Cooling is nil the camera is used at whatever temperature it is at; if WarmUp is nil
+// the cooler is left running when the plan is done.
+type CalibrationPlan struct {
+	Cooling *CoolingWaitSettings
+	WarmUp  *WarmUpSettings
+	Entries []CalibrationPlanEntry
+}
+
+// FrameResult records how capturing one frame went
+type FrameResult struct {
+	Attempts int
+	Exposure float64 // Seconds; for flats with a TargetADU this is adjusted to reach the target
+	ADU      int64   // Average ADU, for flat frames
+	Path     string  // Where a rejected flat was saved, so it can be removed; "" if not rejected
+	Err      error   // nil if the frame was captured
+}
+
+// CalibrationSetResult records the frames captured for one plan entry.  For flats with a
+// TargetADU, Frames includes the rejected flats as well as the Count good ones.
+type CalibrationSetResult struct {
+	Entry  CalibrationPlanEntry
+	Search FlatExposureResult // Finding the exposure, for flats with a TargetADU
+	Frames []FrameResult
+}
+
+// CalibrationReport summarizes a calibration run
+type CalibrationReport struct {
+	CameraTemperature float64         // After cooling; 0 if the plan did no cooling
+	CoolerPower       float64         // After cooling; 0 if the plan did no cooling
+	DownloadTimes     map[int]float64 // Measured download time, by binning
+	Sets              []CalibrationSetResult
+}
+
+// maxADU is the largest pixel value a 16-bit camera can report
+const maxADU = 65535
+
+const defaultSequencerRetries = 2
+const defaultSequencerRetryDelay = 10
+
+// NewCalibrationSequencer is the constructor for a sequencer running plans on the given service
+func NewCalibrationSequencer(service TheSkyService,
+	delayService goMockableDelay.DelayService,
+	debug bool,
+	verbosity int) CalibrationSequencer {
+	sequencer := &CalibrationSequencerInstance{
+		service:           service,
+		delayService:      delayService,
+		debug:             debug,
+		verbosity:         verbosity,
+		maxRetries:        defaultSequencerRetries,
+		retryDelaySeconds: defaultSequencerRetryDelay,
+	}
+	return sequencer
+}
+
+// SetRetries sets how many times a frame failing with a transient error is retried, and how
+// long to wait before each retry
+func (sequencer *CalibrationSequencerInstance) SetRetries(maxRetries int, retryDelaySeconds int) {
+	sequencer.maxRetries = maxRetries
+	sequencer.retryDelaySeconds = retryDelaySeconds
+}
+
+// Validate checks that the plan makes sense before any time is spent cooling the camera
+func (plan CalibrationPlan) Validate() error {
+	if len(plan.Entries) == 0 {
+		return errors.New("calibration plan has no entries")
+	}
+	for i, entry := range plan.Entries {
+		if err := entry.validate(); err != nil {
+			return fmt.Errorf("calibration plan entry %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (entry CalibrationPlanEntry) validate() error {
+	if entry.FrameType != DarkFrame && entry.FrameType != BiasFrame && entry.FrameType != FlatFrame {
+		return fmt.Errorf("unknown frame type %v", entry.FrameType)
+	}
+	if entry.Binning < 1 {
+		return fmt.Errorf("binning must be at least 1, not %d", entry.Binning)
+	}
+	if entry.Count < 1 {
+		return fmt.Errorf("count must be at least 1, not %d", entry.Count)
+	}
+	if entry.FrameType != BiasFrame && entry.ExposureSeconds <= 0 {
+		return fmt.Errorf("%s frame exposure must be greater than 0, not %g", entry.FrameType, entry.ExposureSeconds)
+	}
+	if entry.FrameType == FlatFrame {
+		if entry.Filter == "" {
+			return errors.New("flat frames need a filter")
+		}
+		if entry.TargetADU < 0 || entry.TargetADU > maxADU {
+			return fmt.Errorf("target ADU must be between 0 and %d, not %d", maxADU, entry.TargetADU)
+		}
+		if entry.ADUTolerance < 0 || entry.ADUTolerance >= 1 {
+			return fmt.Errorf("ADU tolerance must be a fraction between 0 and 1, not %g", entry.ADUTolerance)
+		}
+		if entry.TargetADU > 0 && entry.ADUTolerance == 0 {
+			return errors.New("ADU tolerance must be greater than 0 when there is a target ADU")
+		}
+	}
+	return nil
+}
+
+// Succeeded is the number of frames in the set that were captured
+func (set CalibrationSetResult) Succeeded() int {
+	count := 0
+	for _, frame := range set.Frames {
+		if frame.Err == nil {
+			count++
+		}
+	}
+	return count
+}
+
+// Failed is the number of frames in the set that could not be captured
+func (set CalibrationSetResult) Failed() int {
+	return len(set.Frames) - set.Succeeded()
+}
+
+// Succeeded is the total number of frames captured
+func (report CalibrationReport) Succeeded() int {
+	total := 0
+	for _, set := range report.Sets {
+		total += set.Succeeded()
+	}
+	return total
+}
+
+// Failed is the total number of frames that could not be captured
+func (report CalibrationReport) Failed() int {
+	total := 0
+	for _, set := range report.Sets {
+		total += set.Failed()
+	}
+	return total
+}
+
+// Run executes the plan.  It returns an error, along with the report so far, only if the run
+// could not continue (invalid plan, cooling failed, cancelled); individual frame failures are
+// recorded in the report.
+func (sequencer *CalibrationSequencerInstance) Run(plan CalibrationPlan) (CalibrationReport, error) {
+	return sequencer.RunContext(context.Background(), plan)
+}
+
+func (sequencer *CalibrationSequencerInstance) RunContext(ctx context.Context, plan CalibrationPlan) (CalibrationReport, error) {
+	if sequencer.verbosity >= 4 || sequencer.debug {
+		fmt.Printf("CalibrationSequencerInstance/Run(%d entries)\n", len(plan.Entries))
+	}
+	report := CalibrationReport{DownloadTimes: make(map[int]float64)}
+	if err := plan.Validate(); err != nil {
+		return report, err
+	}
+
+	if plan.Cooling != nil {
+		if err := sequencer.service.StartCoolingContext(ctx, plan.Cooling.TargetTemperature); err != nil {
+			fmt.Println("CalibrationSequencerInstance/Run error from StartCooling:", err)
+			return report, err
+		}
+		temperature, coolerPower, err := sequencer.service.WaitForTargetTemperatureContext(ctx, *plan.Cooling)
+		if err != nil {
+			fmt.Println("CalibrationSequencerInstance/Run error from WaitForTargetTemperature:", err)
+			return report, err
+		}
+		report.CameraTemperature = temperature
+		report.CoolerPower = coolerPower
+	}
+
+	var filters []FilterInfo
+	for _, entry := range plan.Entries {
+		downloadTime, err := sequencer.downloadTime(ctx, &report, entry.Binning)
+		if err != nil {
+			return report, err
+		}
+		filterSlot := FilterSlotNoFilter
+		if entry.FrameType == FlatFrame {
+			if filterSlot, err = sequencer.filterSlot(ctx, &filters, entry.Filter); err != nil {
+				return report, err
+			}
+		}
+		set := CalibrationSetResult{Entry: entry}
+		if entry.FrameType == FlatFrame && entry.TargetADU > 0 {
+			sequencer.captureTargetedFlats(ctx, &set, filterSlot, downloadTime)
+		} else {
+			for frame := 0; frame < entry.Count && ctx.Err() == nil; frame++ {
+				sequencer.logFrame(entry, frame, entry.ExposureSeconds)
+				set.Frames = append(set.Frames, sequencer.captureFrame(ctx, entry, entry.ExposureSeconds, filterSlot, downloadTime))
+			}
+		}
+		report.Sets = append(report.Sets, set)
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+	}
+
+	if plan.WarmUp != nil {
+		if err := sequencer.service.WarmUpAndStopCoolingContext(ctx, *plan.WarmUp); err != nil {
+			fmt.Println("CalibrationSequencerInstance/Run error from WarmUpAndStopCooling:", err)
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// downloadTime returns the download time for the given binning, measuring it the first time
+func (sequencer *CalibrationSequencerInstance) downloadTime(ctx context.Context, report *CalibrationReport, binning int) (float64, error) {
+	if downloadTime, measured := report.DownloadTimes[binning]; measured {
+		return downloadTime, nil
+	}
+	var downloadTime float64
+	_, err := sequencer.withRetries(ctx, func() error {
+		var err error
+		downloadTime, err = sequencer.service.MeasureDownloadTimeContext(ctx, binning)
+		return err
+	})
+	if err != nil {
+		fmt.Println("CalibrationSequencerInstance/Run error from MeasureDownloadTime:", err)
+		return 0.0, err
+	}
+	report.DownloadTimes[binning] = downloadTime
+	return downloadTime, nil
+}
+
+// filterSlot returns the (one-based) slot of the named filter, reading the filter details from
+// TheSkyX the first time they are needed.  Names are resolved as by ResolveFilter, so a plan
+// can give a band such as "Ha" and run on any rig.
+func (sequencer *CalibrationSequencerInstance) filterSlot(ctx context.Context, filters *[]FilterInfo, filterName string) (int, error) {
+	if *filters == nil {
+		filterDetails, err := sequencer.service.FilterDetailsContext(ctx)
+		if err != nil {
+			fmt.Println("CalibrationSequencerInstance/Run error from FilterDetails:", err)
+			return FilterSlotNoFilter, err
+		}
+		*filters = filterDetails
+	}
+	filter, err := resolveFilter(*filters, filterName)
+	if err != nil {
+		return FilterSlotNoFilter, fmt.Errorf("CalibrationSequencerInstance/Run: %w", err)
+	}
+	return filter.Slot, nil
+}
+
+// captureTargetedFlats captures the flats for an entry with a TargetADU.  The exposure is found
+// first, starting from the plan's exposure, then flats are taken until Count of them are within
+// tolerance.  A flat out of tolerance means the light source has drifted: it is recorded as
+// failed, with its path so it can be deleted, and shot again with the exposure adjusted.  We
+// give up on the entry after Count rejected flats.
+func (sequencer *CalibrationSequencerInstance) captureTargetedFlats(ctx context.Context, set *CalibrationSetResult, filterSlot int, downloadTime float64) {
+	entry := set.Entry
+	search := FlatExposureSearch{
+		FilterSlot:      filterSlot,
+		Binning:         entry.Binning,
+		TargetADU:       entry.TargetADU,
+		Tolerance:       entry.ADUTolerance,
+		MinExposure:     entry.ExposureSeconds / flatSearchRange,
+		MaxExposure:     entry.ExposureSeconds * flatSearchRange,
+		InitialExposure: entry.ExposureSeconds,
+		DownloadTime:    downloadTime,
+	}
+	_, err := sequencer.withRetries(ctx, func() error {
+		var err error
+		set.Search, err = sequencer.service.FindFlatExposureContext(ctx, search)
+		return err
+	})
+	if err != nil {
+		fmt.Println("CalibrationSequencerInstance/Run error from FindFlatExposure:", err)
+		if ctx.Err() != nil {
+			return
+		}
+		// None of the flats can be taken
+		for len(set.Frames) < entry.Count {
+			set.Frames = append(set.Frames, FrameResult{Err: fmt.Errorf("finding flat exposure: %w", err)})
+		}
+		return
+	}
+
+	exposure := set.Search.Exposure
+	rejected := 0
+	for len(set.Frames)-rejected < entry.Count && ctx.Err() == nil {
+		sequencer.logFrame(entry, len(set.Frames)-rejected, exposure)
+		result := sequencer.captureFrame(ctx, entry, exposure, filterSlot, downloadTime)
+		set.Frames = append(set.Frames, result)
+		if !errors.Is(result.Err, ErrFlatADUOutOfRange) {
+			continue
+		}
+		rejected++
+		if rejected >= entry.Count {
+			fmt.Printf("CalibrationSequencerInstance/Run: giving up on %s flats after %d rejected\n", entry.Filter, rejected)
+			return
+		}
+		exposure = search.clampExposure(nextFlatExposure([]FlatMeasurement{{Exposure: exposure, ADU: result.ADU}}, entry.TargetADU))
+	}
+}
+
+// logFrame reports the frame about to be captured
+func (sequencer *CalibrationSequencerInstance) logFrame(entry CalibrationPlanEntry, frame int, exposure float64) {
+	if sequencer.verbosity >= 3 {
+		fmt.Printf("Capturing %s frame %d of %d, binned %d, %g seconds\n",
+			entry.FrameType, frame+1, entry.Count, entry.Binning, exposure)
+	}
+}
+
+// captureFrame captures one frame of the given entry, with retries.  A flat out of tolerance of
+// the entry's TargetADU fails with ErrFlatADUOutOfRange.
+func (sequencer *CalibrationSequencerInstance) captureFrame(ctx context.Context, entry CalibrationPlanEntry, exposure float64, filterSlot int, downloadTime float64) FrameResult {
+	var captured CaptureResult
+	attempts, err := sequencer.withRetries(ctx, func() error {
+		var err error
+		switch entry.FrameType {
+		case BiasFrame:
+			return sequencer.service.CaptureBiasFrameContext(ctx, entry.Binning, downloadTime)
+		case FlatFrame:
+			captured, err = sequencer.service.CaptureAndMeasureFlatFrameResultContext(ctx, exposure, entry.Binning, filterSlot, downloadTime, true)
+			return err
+		}
+		return sequencer.service.CaptureDarkFrameContext(ctx, entry.Binning, exposure, downloadTime)
+	})
+	result := FrameResult{Attempts: attempts, Exposure: exposure, ADU: captured.ADU, Err: err}
+	if entry.FrameType == BiasFrame {
+		result.Exposure = 0.0
+	}
+	if err == nil && entry.FrameType == FlatFrame && entry.TargetADU > 0 {
+		allowed := float64(entry.TargetADU) * entry.ADUTolerance
+		if math.Abs(float64(captured.ADU-entry.TargetADU)) > allowed {
+			result.Err = fmt.Errorf("%w: %d, target %d +/- %.0f", ErrFlatADUOutOfRange, captured.ADU, entry.TargetADU, allowed)
+			result.Path = captured.Path
+		}
+	}
+	if result.Err != nil {
+		fmt.Printf("CalibrationSequencerInstance/Run: %s frame failed after %d attempts: %v\n", entry.FrameType, attempts, result.Err)
+	}
+	return result
+}
+
+// withRetries runs the operation, retrying it after a delay if it fails with a transient
+// error.  It returns the number of attempts made and the last error.
+func (sequencer *CalibrationSequencerInstance) withRetries(ctx context.Context, operation func() error) (int, error) {
+	attempts := 0
+	for {
+		attempts++
+		err := operation()
+		if err == nil || attempts > sequencer.maxRetries || !isTransientError(err) || ctx.Err() != nil {
+			return attempts, err
+		}
+		if sequencer.verbosity >= 3 {
+			fmt.Printf("Transient error (%v), retrying in %d seconds\n", err, sequencer.retryDelaySeconds)
+		}
+		if err := delayWithContext(ctx, sequencer.delayService, sequencer.retryDelaySeconds); err != nil {
+			return attempts, err
+		}
+	}
+}
+
+// isTransientError reports whether an error is one that may well go away if we try again:
+// the link to TheSkyX or to the camera dropped, or a reply took too long.
+func isTransientError(err error) bool {
+	return errors.Is(err, ErrServerUnreachable) ||
+		errors.Is(err, ErrReplyTimeout) ||
+		errors.Is(err, ErrCommNoLink) ||
+		errors.Is(err, ErrNoLink)
+}
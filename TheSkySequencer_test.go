@@ -0,0 +1,260 @@
+package goTheSkyX
+
+import (
+	"context"
+	"errors"
+	"github.com/RMcDOttawa/goMockableDelay"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCalibrationSequencer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("runs plan end to end", func(t *testing.T) {
+		mockService := NewMockTheSkyService(ctrl)
+		mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
+		sequencer := NewCalibrationSequencer(mockService, mockDelayService, false, 0)
+
+		cooling := CoolingWaitSettings{TargetTemperature: -10.0, Tolerance: 0.5, SettleSeconds: 60, PollingIntervalSeconds: 10, TimeoutMinutes: 20}
+		warmUp := WarmUpSettings{FinalTemperature: 10.0, StepDegrees: 5.0, StepIntervalSeconds: 60}
+		plan := CalibrationPlan{
+			Cooling: &cooling,
+			WarmUp:  &warmUp,
+			Entries: []CalibrationPlanEntry{
+				{FrameType: DarkFrame, Binning: 1, ExposureSeconds: 300, Count: 2},
+				{FrameType: BiasFrame, Binning: 1, Count: 3},
+				{FrameType: DarkFrame, Binning: 2, ExposureSeconds: 60, Count: 1},
+			},
+		}
+
+		gomock.InOrder(
+			mockService.EXPECT().StartCoolingContext(gomock.Any(), -10.0).Return(nil),
+			mockService.EXPECT().WaitForTargetTemperatureContext(gomock.Any(), cooling).Return(-10.1, 55.0, nil),
+			mockService.EXPECT().MeasureDownloadTimeContext(gomock.Any(), 1).Return(4.0, nil),
+			mockService.EXPECT().CaptureDarkFrameContext(gomock.Any(), 1, 300.0, 4.0).Return(nil).Times(2),
+			mockService.EXPECT().CaptureBiasFrameContext(gomock.Any(), 1, 4.0).Return(nil).Times(3),
+			mockService.EXPECT().MeasureDownloadTimeContext(gomock.Any(), 2).Return(1.5, nil),
+			mockService.EXPECT().CaptureDarkFrameContext(gomock.Any(), 2, 60.0, 1.5).Return(nil),
+			mockService.EXPECT().WarmUpAndStopCoolingContext(gomock.Any(), warmUp).Return(nil),
+		)
+
+		report, err := sequencer.Run(plan)
+		require.Nil(t, err, "Sequencer run failed")
+		require.Equal(t, 6, report.Succeeded())
+		require.Equal(t, 0, report.Failed())
+		require.Len(t, report.Sets, 3)
+		require.Equal(t, map[int]float64{1: 4.0, 2: 1.5}, report.DownloadTimes)
+		require.Equal(t, -10.1, report.CameraTemperature)
+		require.Equal(t, 55.0, report.CoolerPower)
+	})
+
+	t.Run("retries transient errors", func(t *testing.T) {
+		mockService := NewMockTheSkyService(ctrl)
+		mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
+		sequencer := NewCalibrationSequencer(mockService, mockDelayService, false, 0)
+		sequencer.SetRetries(2, 15)
+
+		plan := CalibrationPlan{Entries: []CalibrationPlanEntry{{FrameType: BiasFrame, Binning: 1, Count: 1}}}
+		mockService.EXPECT().MeasureDownloadTimeContext(gomock.Any(), 1).Return(2.0, nil)
+		gomock.InOrder(
+			mockService.EXPECT().CaptureBiasFrameContext(gomock.Any(), 1, 2.0).Return(&TheSkyXError{Code: 200, Message: "Link lost"}),
+			mockService.EXPECT().CaptureBiasFrameContext(gomock.Any(), 1, 2.0).Return(ErrReplyTimeout),
+			mockService.EXPECT().CaptureBiasFrameContext(gomock.Any(), 1, 2.0).Return(nil),
+		)
+		mockDelayService.EXPECT().DelayDuration(15).Return(15, nil).Times(2)
+
+		report, err := sequencer.Run(plan)
+		require.Nil(t, err, "Sequencer run failed")
+		require.Equal(t, 1, report.Succeeded())
+		require.Equal(t, 3, report.Sets[0].Frames[0].Attempts)
+	})
+
+	t.Run("records frames that still fail and carries on", func(t *testing.T) {
+		mockService := NewMockTheSkyService(ctrl)
+		mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
+		sequencer := NewCalibrationSequencer(mockService, mockDelayService, false, 0)
+		sequencer.SetRetries(1, 5)
+
+		plan := CalibrationPlan{Entries: []CalibrationPlanEntry{{FrameType: DarkFrame, Binning: 1, ExposureSeconds: 10, Count: 3}}}
+		mockService.EXPECT().MeasureDownloadTimeContext(gomock.Any(), 1).Return(2.0, nil)
+		gomock.InOrder(
+			// Permanent error - not retried
+			mockService.EXPECT().CaptureDarkFrameContext(gomock.Any(), 1, 10.0, 2.0).Return(errors.New("camera on fire")),
+			// Transient error that persists past the retries
+			mockService.EXPECT().CaptureDarkFrameContext(gomock.Any(), 1, 10.0, 2.0).Return(ErrReplyTimeout).Times(2),
+			mockService.EXPECT().CaptureDarkFrameContext(gomock.Any(), 1, 10.0, 2.0).Return(nil),
+		)
+		mockDelayService.EXPECT().DelayDuration(5).Return(5, nil)
+
+		report, err := sequencer.Run(plan)
+		require.Nil(t, err, "Frame failures should not fail the run")
+		require.Equal(t, 1, report.Succeeded())
+		require.Equal(t, 2, report.Failed())
+		frames := report.Sets[0].Frames
+		require.Equal(t, 1, frames[0].Attempts)
+		require.Equal(t, 2, frames[1].Attempts)
+		require.ErrorIs(t, frames[1].Err, ErrReplyTimeout)
+	})
+
+	t.Run("flat frames use named filter and find exposure first", func(t *testing.T) {
+		mockService := NewMockTheSkyService(ctrl)
+		mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
+		sequencer := NewCalibrationSequencer(mockService, mockDelayService, false, 0)
+
+		plan := CalibrationPlan{Entries: []CalibrationPlanEntry{
+			{FrameType: FlatFrame, Binning: 1, ExposureSeconds: 2.5, Count: 2, Filter: "Green", TargetADU: 25000, ADUTolerance: 0.1},
+			{FrameType: FlatFrame, Binning: 1, ExposureSeconds: 4, Count: 1, Filter: "blue", TargetADU: 25000, ADUTolerance: 0.1},
+		}}
+		mockService.EXPECT().MeasureDownloadTimeContext(gomock.Any(), 1).Return(2.0, nil)
+		mockService.EXPECT().FilterDetailsContext(gomock.Any()).Return(filterInfoFromNames([]string{"red", "green", "blue"}), nil)
+		gomock.InOrder(
+			mockService.EXPECT().FindFlatExposureContext(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, search FlatExposureSearch) (FlatExposureResult, error) {
+					require.Equal(t, 2, search.FilterSlot)
+					require.Equal(t, 2.5, search.InitialExposure)
+					require.Equal(t, 0.1, search.Tolerance)
+					return FlatExposureResult{Exposure: 3.0, ADU: 25100}, nil
+				}),
+			mockService.EXPECT().CaptureAndMeasureFlatFrameResultContext(gomock.Any(), 3.0, 1, 2, 2.0, true).
+				Return(CaptureResult{Path: "/flats/1.fit", ADU: 24000}, nil),
+			// Light source brightened: rejected, and shot again shorter
+			mockService.EXPECT().CaptureAndMeasureFlatFrameResultContext(gomock.Any(), 3.0, 1, 2, 2.0, true).
+				Return(CaptureResult{Path: "/flats/2.fit", ADU: 31000}, nil),
+			mockService.EXPECT().CaptureAndMeasureFlatFrameResultContext(gomock.Any(), 2.419, 1, 2, 2.0, true).
+				Return(CaptureResult{Path: "/flats/3.fit", ADU: 25200}, nil),
+			mockService.EXPECT().FindFlatExposureContext(gomock.Any(), gomock.Any()).Return(FlatExposureResult{Exposure: 4.0, ADU: 25000}, nil),
+			mockService.EXPECT().CaptureAndMeasureFlatFrameResultContext(gomock.Any(), 4.0, 1, 3, 2.0, true).
+				Return(CaptureResult{Path: "/flats/4.fit", ADU: 25500}, nil),
+		)
+
+		report, err := sequencer.Run(plan)
+		require.Nil(t, err, "Sequencer run failed")
+		require.Equal(t, 3, report.Succeeded())
+		frames := report.Sets[0].Frames
+		require.Len(t, frames, 3)
+		require.Equal(t, int64(24000), frames[0].ADU)
+		require.Equal(t, "", frames[0].Path, "Good flats aren't marked for removal")
+		require.ErrorIs(t, frames[1].Err, ErrFlatADUOutOfRange)
+		require.Equal(t, "/flats/2.fit", frames[1].Path)
+		require.Equal(t, 2.419, frames[2].Exposure)
+		require.Equal(t, 3.0, report.Sets[0].Search.Exposure)
+	})
+
+	t.Run("flats without a target are taken at the plan exposure", func(t *testing.T) {
+		mockService := NewMockTheSkyService(ctrl)
+		mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
+		sequencer := NewCalibrationSequencer(mockService, mockDelayService, false, 0)
+
+		plan := CalibrationPlan{Entries: []CalibrationPlanEntry{{FrameType: FlatFrame, Binning: 1, ExposureSeconds: 2.5, Count: 2, Filter: "red"}}}
+		mockService.EXPECT().MeasureDownloadTimeContext(gomock.Any(), 1).Return(2.0, nil)
+		mockService.EXPECT().FilterDetailsContext(gomock.Any()).Return(filterInfoFromNames([]string{"red", "green", "blue"}), nil)
+		mockService.EXPECT().CaptureAndMeasureFlatFrameResultContext(gomock.Any(), 2.5, 1, 1, 2.0, true).
+			Return(CaptureResult{ADU: 60000}, nil).Times(2)
+
+		report, err := sequencer.Run(plan)
+		require.Nil(t, err, "Sequencer run failed")
+		require.Equal(t, 2, report.Succeeded())
+	})
+
+	t.Run("flats give up when exposure can't be found or too many are rejected", func(t *testing.T) {
+		mockService := NewMockTheSkyService(ctrl)
+		mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
+		sequencer := NewCalibrationSequencer(mockService, mockDelayService, false, 0)
+
+		plan := CalibrationPlan{Entries: []CalibrationPlanEntry{
+			{FrameType: FlatFrame, Binning: 1, ExposureSeconds: 2.5, Count: 3, Filter: "red", TargetADU: 25000, ADUTolerance: 0.1},
+			{FrameType: FlatFrame, Binning: 1, ExposureSeconds: 2.5, Count: 2, Filter: "green", TargetADU: 25000, ADUTolerance: 0.1},
+		}}
+		mockService.EXPECT().MeasureDownloadTimeContext(gomock.Any(), 1).Return(2.0, nil)
+		mockService.EXPECT().FilterDetailsContext(gomock.Any()).Return(filterInfoFromNames([]string{"red", "green", "blue"}), nil)
+		gomock.InOrder(
+			mockService.EXPECT().FindFlatExposureContext(gomock.Any(), gomock.Any()).
+				Return(FlatExposureResult{Exposure: 25.0, ADU: 9000}, ErrFlatPanelTooDim),
+			mockService.EXPECT().FindFlatExposureContext(gomock.Any(), gomock.Any()).Return(FlatExposureResult{Exposure: 3.0, ADU: 25000}, nil),
+			mockService.EXPECT().CaptureAndMeasureFlatFrameResultContext(gomock.Any(), gomock.Any(), 1, 2, 2.0, true).
+				Return(CaptureResult{Path: "/flats/bad.fit", ADU: 40000}, nil).Times(2),
+		)
+
+		report, err := sequencer.Run(plan)
+		require.Nil(t, err, "Flat failures should not fail the run")
+		require.Equal(t, 0, report.Succeeded())
+		require.Len(t, report.Sets[0].Frames, 3, "Every frame of the set should be recorded as failed")
+		require.ErrorIs(t, report.Sets[0].Frames[0].Err, ErrFlatPanelTooDim)
+		require.Len(t, report.Sets[1].Frames, 2, "Should stop after Count rejected flats")
+		for _, frame := range report.Sets[1].Frames {
+			require.ErrorIs(t, frame.Err, ErrFlatADUOutOfRange)
+		}
+	})
+
+	t.Run("target ADU needs a tolerance", func(t *testing.T) {
+		plan := CalibrationPlan{Entries: []CalibrationPlanEntry{{FrameType: FlatFrame, Binning: 1, ExposureSeconds: 2.5, Count: 2, Filter: "red", TargetADU: 25000}}}
+		require.ErrorContains(t, plan.Validate(), "ADU tolerance")
+	})
+
+	t.Run("unknown filter stops the run", func(t *testing.T) {
+		mockService := NewMockTheSkyService(ctrl)
+		mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
+		sequencer := NewCalibrationSequencer(mockService, mockDelayService, false, 0)
+
+		plan := CalibrationPlan{Entries: []CalibrationPlanEntry{{FrameType: FlatFrame, Binning: 1, ExposureSeconds: 1, Count: 1, Filter: "Ha"}}}
+		mockService.EXPECT().MeasureDownloadTimeContext(gomock.Any(), 1).Return(2.0, nil)
+		mockService.EXPECT().FilterDetailsContext(gomock.Any()).Return(filterInfoFromNames([]string{"red", "green", "blue"}), nil)
+
+		_, err := sequencer.Run(plan)
+		require.ErrorContains(t, err, "no filter named \"Ha\"")
+		require.ErrorIs(t, err, ErrUnknownFilter)
+	})
+
+	t.Run("cooling failure stops the run", func(t *testing.T) {
+		mockService := NewMockTheSkyService(ctrl)
+		mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
+		sequencer := NewCalibrationSequencer(mockService, mockDelayService, false, 0)
+
+		cooling := CoolingWaitSettings{TargetTemperature: -20.0, Tolerance: 0.5, PollingIntervalSeconds: 10, TimeoutMinutes: 20}
+		plan := CalibrationPlan{Cooling: &cooling, Entries: []CalibrationPlanEntry{{FrameType: BiasFrame, Binning: 1, Count: 1}}}
+		mockService.EXPECT().StartCoolingContext(gomock.Any(), -20.0).Return(nil)
+		mockService.EXPECT().WaitForTargetTemperatureContext(gomock.Any(), cooling).Return(-5.0, 100.0, ErrCoolingTimeout)
+
+		_, err := sequencer.Run(plan)
+		require.ErrorIs(t, err, ErrCoolingTimeout)
+	})
+
+	t.Run("cancellation stops the run", func(t *testing.T) {
+		mockService := NewMockTheSkyService(ctrl)
+		mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
+		sequencer := NewCalibrationSequencer(mockService, mockDelayService, false, 0)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		plan := CalibrationPlan{Entries: []CalibrationPlanEntry{{FrameType: BiasFrame, Binning: 1, Count: 5}}}
+		mockService.EXPECT().MeasureDownloadTimeContext(gomock.Any(), 1).Return(2.0, nil)
+		mockService.EXPECT().CaptureBiasFrameContext(gomock.Any(), 1, 2.0).Return(nil)
+		mockService.EXPECT().CaptureBiasFrameContext(gomock.Any(), 1, 2.0).DoAndReturn(
+			func(ctx context.Context, binning int, downloadTime float64) error {
+				cancel()
+				return ctx.Err()
+			})
+
+		report, err := sequencer.RunContext(ctx, plan)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Equal(t, 1, report.Succeeded())
+		require.Equal(t, 1, report.Failed())
+	})
+
+	t.Run("invalid plan is rejected before starting", func(t *testing.T) {
+		mockService := NewMockTheSkyService(ctrl)
+		mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
+		sequencer := NewCalibrationSequencer(mockService, mockDelayService, false, 0)
+
+		_, err := sequencer.Run(CalibrationPlan{})
+		require.NotNil(t, err, "Expected error for empty plan")
+		_, err = sequencer.Run(CalibrationPlan{Entries: []CalibrationPlanEntry{
+			{FrameType: BiasFrame, Binning: 1, Count: 1},
+			{FrameType: DarkFrame, Binning: 1, ExposureSeconds: 0, Count: 1},
+		}})
+		require.ErrorContains(t, err, "entry 2")
+	})
+}
@@ -1,12 +1,11 @@
 package goTheSkyX
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/RMcDOttawa/goMockableDelay"
 	"math"
-	"math/rand/v2"
-	"runtime/debug"
 	"strings"
 	"time"
 )
@@ -14,6 +13,10 @@ import (
 //	TheSkyService is a high-level interface to the set of logical services we use to control
 //	the TheSkyX app running on the network. It abstracts away the complexities of making up
 //	JavaScript command packets and using sockets to communicate.
+//
+//	Methods that can take a while have a ...Context variant.  Cancelling the context stops the
+//	wait, including a wait for TheSkyX to reply to a command, and returns ctx.Err(); if an
+//	exposure is in progress it is aborted on the camera.
 
 type TheSkyService interface {
 	//	BAsic Controls
@@ -25,30 +28,105 @@ type TheSkyService interface {
 	//	Camera
 	ConnectCamera() error
 	StartCooling(targetTemp float64) error
+	StartCoolingContext(ctx context.Context, targetTemp float64) error
 	GetCameraTemperature() (float64, error)
+	GetCameraTemperatureContext(ctx context.Context) (float64, error)
+	GetCoolerStatus() (CoolerStatus, error)
+	GetCoolerStatusContext(ctx context.Context) (CoolerStatus, error)
 	StopCooling() error
+	StopCoolingContext(ctx context.Context) error
+	CameraCapabilities() (CameraCapabilities, error)
+	CameraCapabilitiesContext(ctx context.Context) (CameraCapabilities, error)
+	WarmUpAndStopCooling(settings WarmUpSettings) error
+	WarmUpAndStopCoolingContext(ctx context.Context, settings WarmUpSettings) error
 	WaitForCameraInactive(pollingIntervalSeconds int, timeoutMinutes int) error
+	WaitForCameraInactiveContext(ctx context.Context, pollingIntervalSeconds int, timeoutMinutes int) error
+	WaitForTargetTemperature(settings CoolingWaitSettings) (float64, float64, error)
+	WaitForTargetTemperatureContext(ctx context.Context, settings CoolingWaitSettings) (float64, float64, error)
 	//	Filter Wheel
 	HasFilterWheel() (bool, error)
-	NumberOfFilters() (int, error)  // Number of up to first blank name
+	HasFilterWheelContext(ctx context.Context) (bool, error)
+	NumberOfFilters() (int, error) // Number of up to first blank name
+	NumberOfFiltersContext(ctx context.Context) (int, error)
 	FilterNames() ([]string, error) // Names up to first blank name
+	FilterNamesContext(ctx context.Context) ([]string, error)
+	SelectFilter(filterSlot int) error
+	SelectFilterContext(ctx context.Context, filterSlot int) error
+	SelectFilterByName(filterName string) (int, error)
+	SelectFilterByNameContext(ctx context.Context, filterName string) (int, error)
+	CurrentFilterSlot() (int, error)
+	CurrentFilterSlotContext(ctx context.Context) (int, error)
+	WaitForFilterWheel(timeoutSeconds int) (int, error)
+	WaitForFilterWheelContext(ctx context.Context, timeoutSeconds int) (int, error)
+	DisconnectFilterWheel() error
+	DisconnectFilterWheelContext(ctx context.Context) error
+	FilterDetails() ([]FilterInfo, error) // Names, bands and local metadata of each slot
+	FilterDetailsContext(ctx context.Context) ([]FilterInfo, error)
+	ResolveFilter(filterName string) (FilterInfo, error)
+	ResolveFilterContext(ctx context.Context, filterName string) (FilterInfo, error)
+	SetFilterOverrides(overrides FilterOverrides)
+	SetSaveSettings(settings SaveSettings) error
+	SetDarkQualityCheck(check DarkQualityCheck) error
+	//	Mount
+	ConnectTelescope() error
+	SlewToAltAz(altitude float64, azimuth float64, pollingIntervalSeconds int, timeoutMinutes int) error
+	SlewToAltAzContext(ctx context.Context, altitude float64, azimuth float64, pollingIntervalSeconds int, timeoutMinutes int) error
+	Park(pollingIntervalSeconds int, timeoutMinutes int) error
+	ParkContext(ctx context.Context, pollingIntervalSeconds int, timeoutMinutes int) error
+	Unpark() error
+	UnparkContext(ctx context.Context) error
+	SetTracking(on bool) error
+	SetTrackingContext(ctx context.Context, on bool) error
+	GetTelescopePosition() (TelescopePosition, error)
+	GetTelescopePositionContext(ctx context.Context) (TelescopePosition, error)
+	IsSlewComplete() (bool, error)
 	//	Frame Capture
 	MeasureDownloadTime(binning int) (float64, error)
+	MeasureDownloadTimeContext(ctx context.Context, binning int) (float64, error)
+	MeasureSubframeDownloadTime(binning int, subframe Subframe) (float64, error)
+	MeasureSubframeDownloadTimeContext(ctx context.Context, binning int, subframe Subframe) (float64, error)
 	CaptureDarkFrame(binning int, seconds float64, downloadTime float64) error
+	CaptureDarkFrameContext(ctx context.Context, binning int, seconds float64, downloadTime float64) error
 	CaptureBiasFrame(binning int, downloadTime float64) error // for mocking
+	CaptureBiasFrameContext(ctx context.Context, binning int, downloadTime float64) error
 	CaptureAndMeasureFlatFrame(exposure float64, binning int, filterSlot int, downloadTime float64, saveImage bool) (int64, error)
+	CaptureAndMeasureFlatFrameContext(ctx context.Context, exposure float64, binning int, filterSlot int, downloadTime float64, saveImage bool) (int64, error)
+	CaptureDarkFrameResult(binning int, seconds float64, downloadTime float64) (CaptureResult, error)
+	CaptureDarkFrameResultContext(ctx context.Context, binning int, seconds float64, downloadTime float64) (CaptureResult, error)
+	CaptureBiasFrameResult(binning int, downloadTime float64) (CaptureResult, error)
+	CaptureBiasFrameResultContext(ctx context.Context, binning int, downloadTime float64) (CaptureResult, error)
+	CaptureAndMeasureFlatFrameResult(exposure float64, binning int, filterSlot int, downloadTime float64, saveImage bool) (CaptureResult, error)
+	CaptureAndMeasureFlatFrameResultContext(ctx context.Context, exposure float64, binning int, filterSlot int, downloadTime float64, saveImage bool) (CaptureResult, error)
+	ImageStats(centralFraction float64) (ImageStats, error)
+	ImageStatsContext(ctx context.Context, centralFraction float64) (ImageStats, error)
+	FindFlatExposure(search FlatExposureSearch) (FlatExposureResult, error)
+	FindFlatExposureContext(ctx context.Context, search FlatExposureSearch) (FlatExposureResult, error)
+	CaptureFlatSets(settings FlatSetSettings) ([]FlatSetResult, error)
+	CaptureFlatSetsContext(ctx context.Context, settings FlatSetSettings) ([]FlatSetResult, error)
+	CaptureTwilightFlats(settings TwilightFlatSettings) ([]FlatSetResult, error)
+	CaptureTwilightFlatsContext(ctx context.Context, settings TwilightFlatSettings) ([]FlatSetResult, error)
 	SetSimulateFlatCapture(flag bool)
 	SetSimulationNoiseFraction(fraction float64)
+	SetFlatSimulationModel(model FlatSimulationModel)
+	SetSimulatedSkyBrightness(brightness func(now time.Time) float64)
 }
 
 type TheSkyServiceInstance struct {
-	driver                  TheSkyDriver
-	isOpen                  bool
-	delayService            goMockableDelay.DelayService
-	debug                   bool
-	verbosity               int
-	simulateFlatCapture     bool
-	simulationNoiseFraction float64
+	driver                 TheSkyDriver
+	isOpen                 bool
+	delayService           goMockableDelay.DelayService
+	debug                  bool
+	verbosity              int
+	simulateFlatCapture    bool
+	flatSimulationModel    FlatSimulationModel
+	simulatedSkyBrightness func(now time.Time) float64
+	clock                  func() time.Time
+	filterOverrides        FilterOverrides
+	cameraCapabilities     *CameraCapabilities // nil until CameraCapabilities is called
+	biasFramesRefused      bool                // The camera has refused a bias frame
+	saveSettings           *SaveSettings       // nil to leave saving to TheSkyX's AutoSave
+	saveSequence           int                 // Frames saved since SetSaveSettings
+	darkQualityCheck       *DarkQualityCheck   // nil not to check dark frames
 }
 
 const minimumTimeoutForDark = 10.0 * 60.0
@@ -58,6 +136,9 @@ const minimumTimeoutForBias = 3.0 * 60.0
 
 func (service *TheSkyServiceInstance) SetDriver(driver TheSkyDriver) {
 	service.driver = driver
+	if service.saveSettings != nil {
+		driver.SetAutoSave(false)
+	}
 }
 
 func (service *TheSkyServiceInstance) SetDebug(debug bool) {
@@ -72,8 +153,24 @@ func (service *TheSkyServiceInstance) SetSimulateFlatCapture(flag bool) {
 	service.simulateFlatCapture = flag
 }
 
+// SetSimulationNoiseFraction sets the noise level of the simulated flat frames.  It applies
+// to the standard table-driven simulation model; other models manage their own noise.
 func (service *TheSkyServiceInstance) SetSimulationNoiseFraction(fraction float64) {
-	service.simulationNoiseFraction = fraction
+	if model, isTable := service.flatSimulationModel.(*TableFlatSimulationModel); isTable {
+		model.NoiseFraction = fraction
+	}
+}
+
+// SetFlatSimulationModel replaces the model used to simulate flat frame ADU values
+func (service *TheSkyServiceInstance) SetFlatSimulationModel(model FlatSimulationModel) {
+	service.flatSimulationModel = model
+}
+
+// SetSimulatedSkyBrightness makes simulated flat frames vary with time, e.g. to simulate the
+// sky at twilight.  The function gives the brightness, relative to the usual simulated level,
+// at the given time.  nil (the default) means constant brightness.
+func (service *TheSkyServiceInstance) SetSimulatedSkyBrightness(brightness func(now time.Time) float64) {
+	service.simulatedSkyBrightness = brightness
 }
 
 // NewTheSkyService is the constructor for the instance of this service
@@ -82,13 +179,14 @@ func NewTheSkyService(delayService goMockableDelay.DelayService,
 	verbosity int,
 	simulateFlatFrameADUs bool) TheSkyService {
 	service := &TheSkyServiceInstance{
-		isOpen:                  false,
-		driver:                  NewTheSkyDriver(debug, verbosity),
-		delayService:            delayService,
-		debug:                   debug,
-		verbosity:               verbosity,
-		simulateFlatCapture:     simulateFlatFrameADUs,
-		simulationNoiseFraction: 0.2,
+		isOpen:              false,
+		driver:              NewTheSkyDriver(debug, verbosity),
+		delayService:        delayService,
+		debug:               debug,
+		verbosity:           verbosity,
+		simulateFlatCapture: simulateFlatFrameADUs,
+		flatSimulationModel: NewDefaultFlatSimulationModel(uint64(time.Now().UnixNano())),
+		clock:               time.Now,
 	}
 	return service
 }
@@ -130,20 +228,27 @@ func (service *TheSkyServiceInstance) ConnectCamera() error {
 }
 
 func (service *TheSkyServiceInstance) WaitForCameraInactive(pollingIntervalSeconds int, timeoutMinutes int) error {
+	return service.WaitForCameraInactiveContext(context.Background(), pollingIntervalSeconds, timeoutMinutes)
+}
+
+func (service *TheSkyServiceInstance) WaitForCameraInactiveContext(ctx context.Context, pollingIntervalSeconds int, timeoutMinutes int) error {
 	if service.verbosity >= 5 {
 		fmt.Printf("TheSkyServiceInstance/WaitForCameraInactive(%d)\n", pollingIntervalSeconds)
 	}
 	if !service.isOpen {
 		return errors.New("TheSkyServiceInstance/WaitForCameraInactive: Connection not open")
 	}
-	err := service.driver.ConnectCamera()
+	err := service.driverFor(ctx).ConnectCamera()
 	if err != nil {
 		fmt.Println("TheSkyServiceInstance/ConnectCamera error from driver:", err)
 		return err
 	}
 	timeoutTime := time.Now().Add(time.Duration(timeoutMinutes) * time.Minute)
 	for {
-		done, err := service.driver.IsCaptureDone()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		done, err := service.driverFor(ctx).IsCaptureDone()
 		if err != nil {
 			fmt.Println("TheSkyServiceInstance/WaitForCameraInactive error from IsCaptureDone:", err)
 			return err
@@ -157,7 +262,9 @@ func (service *TheSkyServiceInstance) WaitForCameraInactive(pollingIntervalSecon
 		if service.verbosity >= 5 {
 			fmt.Printf("  Camera not done, waiting %d seconds to try again\n", pollingIntervalSeconds)
 		}
-		_, err = service.delayService.DelayDuration(pollingIntervalSeconds)
+		if err := service.delayContext(ctx, pollingIntervalSeconds); err != nil {
+			return err
+		}
 		if time.Now().After(timeoutTime) {
 			return errors.New("timed out waiting for camera to finish")
 		}
@@ -177,19 +284,28 @@ func (service *TheSkyServiceInstance) Close() error {
 		return err
 	}
 	service.isOpen = false
+	service.cameraCapabilities = nil
+	service.biasFramesRefused = false
 	return nil
 }
 
 // StartCooling turns on the camera's thermoelectric cooler (TEC) and sets target temp
 func (service *TheSkyServiceInstance) StartCooling(targetTemp float64) error {
+	return service.StartCoolingContext(context.Background(), targetTemp)
+}
+
+func (service *TheSkyServiceInstance) StartCoolingContext(ctx context.Context, targetTemp float64) error {
 	if service.debug || service.verbosity >= 4 {
 		fmt.Printf("TheSkyServiceInstance/startCooling(%g) entered\n", targetTemp)
 	}
 	if !service.isOpen {
 		return errors.New("TheSkyServiceInstance/StartCooling: Connection not open")
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-	if err := service.driver.StartCooling(targetTemp); err != nil {
+	if err := service.driverFor(ctx).StartCooling(targetTemp); err != nil {
 		fmt.Println("TheSkyServiceInstance/StartCooling error from driver:", err)
 		return err
 	}
@@ -200,11 +316,18 @@ func (service *TheSkyServiceInstance) StartCooling(targetTemp float64) error {
 }
 
 func (service *TheSkyServiceInstance) StopCooling() error {
+	return service.StopCoolingContext(context.Background())
+}
+
+func (service *TheSkyServiceInstance) StopCoolingContext(ctx context.Context) error {
 	//fmt.Println("TheSkyServiceInstance/StopCooling()")
 	if !service.isOpen {
 		return errors.New("TheSkyServiceInstance/StopCooling: Connection not open")
 	}
-	err := service.driver.StopCooling()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := service.driverFor(ctx).StopCooling()
 	if err != nil {
 		fmt.Println("TheSkyServiceInstance/StopCooling error from driver:", err)
 		return err
@@ -214,11 +337,18 @@ func (service *TheSkyServiceInstance) StopCooling() error {
 }
 
 func (service *TheSkyServiceInstance) GetCameraTemperature() (float64, error) {
+	return service.GetCameraTemperatureContext(context.Background())
+}
+
+func (service *TheSkyServiceInstance) GetCameraTemperatureContext(ctx context.Context) (float64, error) {
 	//fmt.Println("TheSkyServiceInstance/GetCameraTemperature()")
 	if !service.isOpen {
 		return 0.0, errors.New("TheSkyServiceInstance/GetCameraTemperature: Connection not open")
 	}
-	temp, err := service.driver.GetCameraTemperature()
+	if err := ctx.Err(); err != nil {
+		return 0.0, err
+	}
+	temp, err := service.driverFor(ctx).GetCameraTemperature()
 	if err != nil {
 		fmt.Println("TheSkyServiceInstance/GetCameraTemperature error from driver:", err)
 		return temp, err
@@ -227,10 +357,17 @@ func (service *TheSkyServiceInstance) GetCameraTemperature() (float64, error) {
 }
 
 func (service *TheSkyServiceInstance) MeasureDownloadTime(binning int) (float64, error) {
+	return service.MeasureDownloadTimeContext(context.Background(), binning)
+}
+
+func (service *TheSkyServiceInstance) MeasureDownloadTimeContext(ctx context.Context, binning int) (float64, error) {
 	if !service.isOpen {
 		return 0.0, errors.New("TheSkyServiceInstance/MeasureDownloadTime: Connection not open")
 	}
-	downloadTime, err := service.driver.MeasureDownloadTime(binning)
+	if err := service.checkBinning(binning); err != nil {
+		return 0.0, fmt.Errorf("TheSkyServiceInstance/MeasureDownloadTime: %w", err)
+	}
+	downloadTime, err := service.driverFor(ctx).MeasureDownloadTimeContext(ctx, binning)
 	if err != nil {
 		fmt.Println("TheSkyServiceInstance/MeasureDownloadTime error from driver:", err)
 		return downloadTime, err
@@ -244,10 +381,75 @@ const timeoutFactor = 5.0   // How much longer to wait than the exposure time
 const shortTimeForBiasExposure = 0.1
 
 func (service *TheSkyServiceInstance) CaptureDarkFrame(binning int, seconds float64, downloadTime float64) error {
+	return service.CaptureDarkFrameContext(context.Background(), binning, seconds, downloadTime)
+}
+
+func (service *TheSkyServiceInstance) CaptureDarkFrameContext(ctx context.Context, binning int, seconds float64, downloadTime float64) error {
+	_, err := service.captureDarkFrame(ctx, binning, seconds, downloadTime, false)
+	return err
+}
+
+// CaptureDarkFrameResult is CaptureDarkFrame, also returning where the frame was saved and how
+// it was taken
+func (service *TheSkyServiceInstance) CaptureDarkFrameResult(binning int, seconds float64, downloadTime float64) (CaptureResult, error) {
+	return service.CaptureDarkFrameResultContext(context.Background(), binning, seconds, downloadTime)
+}
+
+func (service *TheSkyServiceInstance) CaptureDarkFrameResultContext(ctx context.Context, binning int, seconds float64, downloadTime float64) (CaptureResult, error) {
+	return service.captureDarkFrame(ctx, binning, seconds, downloadTime, true)
+}
+
+// captureDarkFrame takes a dark frame, re-shooting it if it fails the dark quality check.  If
+// wantResult, it then asks TheSkyX about the image.
+func (service *TheSkyServiceInstance) captureDarkFrame(ctx context.Context, binning int, seconds float64, downloadTime float64, wantResult bool) (CaptureResult, error) {
 	if service.verbosity >= 4 || service.debug {
 		fmt.Printf("TheSkyServiceInstance/CaptureDarkFrame(%d, %g, %g) \n", binning, seconds, downloadTime)
 	}
-	err := service.driver.StartDarkFrameCapture(binning, seconds, downloadTime)
+	if err := ctx.Err(); err != nil {
+		return CaptureResult{}, err
+	}
+	if err := service.checkBinning(binning); err != nil {
+		return CaptureResult{}, fmt.Errorf("TheSkyServiceInstance/CaptureDarkFrame: %w", err)
+	}
+	//	Frames failing the quality check, if there is one, are taken again.  So that they aren't
+	//	kept, AutoSave is off while checking and frames that pass are saved here instead.
+	saveChecked := service.darkQualityCheck != nil && service.saveSettings == nil
+	if saveChecked {
+		service.driverFor(ctx).SetAutoSave(false)
+		defer service.driverFor(ctx).SetAutoSave(true)
+	}
+	for attempt := 1; ; attempt++ {
+		if err := service.exposeDarkFrame(ctx, binning, seconds, downloadTime); err != nil {
+			return CaptureResult{}, err
+		}
+		rejection, err := service.darkFrameProblems(ctx, seconds, attempt)
+		if err != nil {
+			return CaptureResult{}, err
+		}
+		if rejection == nil {
+			break
+		}
+		if attempt > service.darkQualityCheck.Reshoots {
+			return CaptureResult{}, fmt.Errorf("TheSkyServiceInstance/CaptureDarkFrame: %w", rejection)
+		}
+		if service.verbosity >= 3 || service.debug {
+			fmt.Println("TheSkyServiceInstance/CaptureDarkFrame: re-shooting rejected dark frame:", rejection)
+		}
+	}
+	frame := capturedFrame{frameType: DarkFrame, exposure: seconds, binning: binning, filterSlot: FilterSlotNoFilter}
+	if saveChecked {
+		if _, err := service.saveCapturedFrameWith(ctx, &SaveSettings{}, frame); err != nil {
+			return CaptureResult{}, err
+		}
+	} else if _, err := service.saveCapturedFrame(ctx, frame); err != nil {
+		return CaptureResult{}, err
+	}
+	return service.captureResult(ctx, frame, wantResult)
+}
+
+// exposeDarkFrame takes a dark frame and waits for it to be downloaded
+func (service *TheSkyServiceInstance) exposeDarkFrame(ctx context.Context, binning int, seconds float64, downloadTime float64) error {
+	err := service.driverFor(ctx).StartDarkFrameCapture(binning, seconds, downloadTime)
 	if err != nil {
 		fmt.Println("TheSkyServiceInstance/StartDarkFrameCapture error from driver:", err)
 		return err
@@ -257,15 +459,15 @@ func (service *TheSkyServiceInstance) CaptureDarkFrame(binning int, seconds floa
 	if service.verbosity >= 4 {
 		fmt.Println("Exposure started. Waiting for ", delayUntilComplete)
 	}
-	if _, err := service.delayService.DelayDuration(delayUntilComplete); err != nil {
+	if err := service.delayContext(ctx, delayUntilComplete); err != nil {
 		fmt.Println("TheSkyServiceInstance/CaptureDarkFrame error from delaypkg service:", err)
-		return err
+		return service.abortIfCancelled(ctx, err)
 	}
 	//	Now we poll the camera repeatedly until it reports done
 	maximumWaitSeconds := math.Max((seconds+downloadTime)*timeoutFactor, minimumTimeoutForDark)
 	secondsWaitedSoFar := 0.0
 	for {
-		done, err := service.driver.IsCaptureDone()
+		done, err := service.driverFor(ctx).IsCaptureDone()
 		if err != nil {
 			fmt.Println("TheSkyServiceInstance/CaptureDarkFrame error from IsCaptureDone:", err)
 			return err
@@ -282,57 +484,99 @@ func (service *TheSkyServiceInstance) CaptureDarkFrame(binning int, seconds floa
 		if service.verbosity >= 4 {
 			fmt.Println("Camera not finished. Delaying ", pollingInterval)
 		}
-		if _, err := service.delayService.DelayDuration(int(math.Round(pollingInterval))); err != nil {
+		if err := service.delayContext(ctx, int(math.Round(pollingInterval))); err != nil {
 			fmt.Println("TheSkyServiceInstance/CaptureDarkFrame error from polling delaypkg service:", err)
-			return err
+			return service.abortIfCancelled(ctx, err)
 		}
 		secondsWaitedSoFar += pollingInterval
 	}
 }
 
 func (service *TheSkyServiceInstance) CaptureBiasFrame(binning int, downloadTime float64) error {
+	return service.CaptureBiasFrameContext(context.Background(), binning, downloadTime)
+}
+
+func (service *TheSkyServiceInstance) CaptureBiasFrameContext(ctx context.Context, binning int, downloadTime float64) error {
+	_, err := service.captureBiasFrame(ctx, binning, downloadTime, false)
+	return err
+}
+
+// CaptureBiasFrameResult is CaptureBiasFrame, also returning where the frame was saved and how
+// it was taken
+func (service *TheSkyServiceInstance) CaptureBiasFrameResult(binning int, downloadTime float64) (CaptureResult, error) {
+	return service.CaptureBiasFrameResultContext(context.Background(), binning, downloadTime)
+}
+
+func (service *TheSkyServiceInstance) CaptureBiasFrameResultContext(ctx context.Context, binning int, downloadTime float64) (CaptureResult, error) {
+	return service.captureBiasFrame(ctx, binning, downloadTime, true)
+}
+
+// captureBiasFrame takes a bias frame, or a minimum-length dark if the camera can't.  If
+// wantResult, it then asks TheSkyX about the image.
+func (service *TheSkyServiceInstance) captureBiasFrame(ctx context.Context, binning int, downloadTime float64, wantResult bool) (CaptureResult, error) {
 	if service.verbosity >= 4 || service.debug {
 		fmt.Printf("TheSkyServiceInstance/CaptureBiasFrame(%d, %g) \n", binning, downloadTime)
 	}
-	err := service.driver.StartBiasFrameCapture(binning, downloadTime)
+	if err := ctx.Err(); err != nil {
+		return CaptureResult{}, err
+	}
+	if service.biasFramesUnsupported() {
+		if service.verbosity >= 4 || service.debug {
+			fmt.Println("Camera can't take bias frames, taking minimum-length dark instead")
+		}
+		return service.captureDarkFrame(ctx, binning, minimumDarkExposure, downloadTime, wantResult)
+	}
+	if err := service.checkBinning(binning); err != nil {
+		return CaptureResult{}, fmt.Errorf("TheSkyServiceInstance/CaptureBiasFrame: %w", err)
+	}
+	err := service.driverFor(ctx).StartBiasFrameCapture(binning, downloadTime)
+	if err != nil && service.biasFrameRefused(err) {
+		if service.verbosity >= 4 || service.debug {
+			fmt.Println("Camera refused a bias frame, taking minimum-length dark instead")
+		}
+		return service.captureDarkFrame(ctx, binning, minimumDarkExposure, downloadTime, wantResult)
+	}
 	if err != nil {
 		fmt.Println("TheSkyServiceInstance/StartBiasFrameCapture error from driver:", err)
-		return err
+		return CaptureResult{}, err
 	}
 	//	Now we'll wait until the exposure is probably over - exposure time + download time
 	delayUntilComplete := int(math.Round(shortTimeForBiasExposure + downloadTime + AndALittleExtra))
 	if service.verbosity >= 4 {
 		fmt.Println("Exposure started. Waiting for ", delayUntilComplete)
 	}
-	if _, err := service.delayService.DelayDuration(delayUntilComplete); err != nil {
+	if err := service.delayContext(ctx, delayUntilComplete); err != nil {
 		fmt.Println("TheSkyServiceInstance/CaptureBiasFrame error from delaypkg service:", err)
-		return err
+		return CaptureResult{}, service.abortIfCancelled(ctx, err)
 	}
 	//	Now we poll the camera repeatedly until it reports done
 	maximumWaitSeconds := math.Max((shortTimeForBiasExposure+downloadTime)*timeoutFactor, minimumTimeoutForBias)
 	secondsWaitedSoFar := 0.0
 	for {
-		done, err := service.driver.IsCaptureDone()
+		done, err := service.driverFor(ctx).IsCaptureDone()
 		if err != nil {
 			fmt.Println("TheSkyServiceInstance/CaptureBiasFrame error from IsCaptureDone:", err)
-			return err
+			return CaptureResult{}, err
 		}
 		if done {
 			if service.verbosity >= 4 {
 				fmt.Println("capture is done, returning")
 			}
-			return nil
+			frame := capturedFrame{frameType: BiasFrame, binning: binning, filterSlot: FilterSlotNoFilter}
+			if _, err := service.saveCapturedFrame(ctx, frame); err != nil {
+				return CaptureResult{}, err
+			}
+			return service.captureResult(ctx, frame, wantResult)
 		}
 		if secondsWaitedSoFar > maximumWaitSeconds {
-			return errors.New("TheSkyServiceInstance/CaptureBiasFrame: Timeout waiting for capture to finish")
+			return CaptureResult{}, errors.New("TheSkyServiceInstance/CaptureBiasFrame: Timeout waiting for capture to finish")
 		}
 		if service.verbosity >= 4 {
 			fmt.Println("Camera not finished. Delaying ", pollingInterval)
 		}
-		if _, err := service.delayService.DelayDuration(int(math.Round(pollingInterval))); err != nil {
-			//if _, err := service.delayService.DelayDuration(10); err != nil {
+		if err := service.delayContext(ctx, int(math.Round(pollingInterval))); err != nil {
 			fmt.Println("TheSkyServiceInstance/CaptureBiasFrame error from polling delaypkg service:", err)
-			return err
+			return CaptureResult{}, service.abortIfCancelled(ctx, err)
 		}
 		secondsWaitedSoFar += pollingInterval
 	}
@@ -344,45 +588,70 @@ func (service *TheSkyServiceInstance) CaptureBiasFrame(binning int, downloadTime
 // This, if used, is run after the driver runs the CaptureFlat routine so we still exercise the driver and the waiting
 
 func (service *TheSkyServiceInstance) CaptureAndMeasureFlatFrame(exposure float64, binning int, filterSlot int, downloadTime float64, saveImage bool) (int64, error) {
+	return service.CaptureAndMeasureFlatFrameContext(context.Background(), exposure, binning, filterSlot, downloadTime, saveImage)
+}
+
+func (service *TheSkyServiceInstance) CaptureAndMeasureFlatFrameContext(ctx context.Context, exposure float64, binning int, filterSlot int, downloadTime float64, saveImage bool) (int64, error) {
+	result, err := service.captureFlatFrame(ctx, exposure, binning, filterSlot, downloadTime, saveImage, false)
+	return result.ADU, err
+}
+
+// CaptureAndMeasureFlatFrameResult is CaptureAndMeasureFlatFrame, returning the ADU in a
+// CaptureResult along with where the frame was saved and how it was taken
+func (service *TheSkyServiceInstance) CaptureAndMeasureFlatFrameResult(exposure float64, binning int, filterSlot int, downloadTime float64, saveImage bool) (CaptureResult, error) {
+	return service.CaptureAndMeasureFlatFrameResultContext(context.Background(), exposure, binning, filterSlot, downloadTime, saveImage)
+}
+
+func (service *TheSkyServiceInstance) CaptureAndMeasureFlatFrameResultContext(ctx context.Context, exposure float64, binning int, filterSlot int, downloadTime float64, saveImage bool) (CaptureResult, error) {
+	return service.captureFlatFrame(ctx, exposure, binning, filterSlot, downloadTime, saveImage, true)
+}
+
+// captureFlatFrame takes a flat frame and measures its average ADU.  If wantResult, it then
+// asks TheSkyX about the image.
+func (service *TheSkyServiceInstance) captureFlatFrame(ctx context.Context, exposure float64, binning int, filterSlot int, downloadTime float64, saveImage bool, wantResult bool) (CaptureResult, error) {
 	if service.verbosity >= 4 || service.debug {
 		fmt.Printf("TheSkyServiceInstance/CaptureAndMeasureFlatFrame(%g, %d, %g, %t) \n", exposure, binning, downloadTime, saveImage)
 	}
-	if exposure == 0.0 {
-		fmt.Printf("TheSkyServiceInstance/CaptureAndMeasureFlatFrame ASSERT FAIL, exposure 0 (%g, %d, %g, %t) \n", exposure, binning, downloadTime, saveImage)
-		debug.PrintStack()
-		panic("Exposure=0")
+	if exposure <= 0.0 {
+		return CaptureResult{}, fmt.Errorf("TheSkyServiceInstance/CaptureAndMeasureFlatFrame: exposure must be greater than 0, not %g", exposure)
+	}
+	if err := ctx.Err(); err != nil {
+		return CaptureResult{}, err
 	}
-	err := service.driver.StartFlatFrameCapture(binning, exposure, filterSlot, downloadTime, saveImage)
+	if err := service.checkBinning(binning); err != nil {
+		return CaptureResult{}, fmt.Errorf("TheSkyServiceInstance/CaptureAndMeasureFlatFrame: %w", err)
+	}
+	err := service.driverFor(ctx).StartFlatFrameCapture(binning, exposure, filterSlot, downloadTime, saveImage)
 	if err != nil {
 		fmt.Println("TheSkyServiceInstance/StartFlatFrameCapture error from driver:", err)
-		return 0, err
+		return CaptureResult{}, err
 	}
 	//	Now we'll wait until the exposure is probably over - exposure time + download time
 	delayUntilComplete := int(math.Round(exposure + downloadTime + AndALittleExtra))
 	if service.verbosity >= 4 {
 		fmt.Println("Exposure started. Waiting for ", delayUntilComplete)
 	}
-	if _, err := service.delayService.DelayDuration(delayUntilComplete); err != nil {
+	if err := service.delayContext(ctx, delayUntilComplete); err != nil {
 		fmt.Println("TheSkyServiceInstance/CaptureAndMeasureFlatFrame error from delaypkg service:", err)
-		return 0, err
+		return CaptureResult{}, service.abortIfCancelled(ctx, err)
 	}
 	//	Now we poll the camera repeatedly until it reports done
 	maximumWaitSeconds := math.Max((exposure+downloadTime)*timeoutFactor, minimumTimeoutForDark)
 	secondsWaitedSoFar := 0.0
 	for {
-		done, err := service.driver.IsCaptureDone()
+		done, err := service.driverFor(ctx).IsCaptureDone()
 		if err != nil {
 			fmt.Println("TheSkyServiceInstance/CaptureAndMeasureFlatFrame error from IsCaptureDone:", err)
-			return 0, err
+			return CaptureResult{}, err
 		}
 		if done {
 			if service.verbosity >= 4 {
 				fmt.Println("capture is done, returning")
 			}
-			aduValue, err := service.driver.GetADUValue()
+			aduValue, err := service.driverFor(ctx).GetADUValue()
 			if err != nil {
 				fmt.Println("TheSkyServiceInstance/CaptureAndMeasureFlatFrame error from GetADUValue:", err)
-				return 0, err
+				return CaptureResult{}, err
 			}
 			if service.simulateFlatCapture {
 				simulatedAduValue, _ := service.simulatedFrameCapture(exposure, binning, filterSlot, downloadTime, saveImage)
@@ -391,61 +660,107 @@ func (service *TheSkyServiceInstance) CaptureAndMeasureFlatFrame(exposure float6
 				}
 				aduValue = simulatedAduValue
 			}
+			frame := capturedFrame{frameType: FlatFrame, exposure: exposure, binning: binning, filterSlot: filterSlot}
+			if saveImage {
+				if _, err := service.saveCapturedFrame(ctx, frame); err != nil {
+					return CaptureResult{}, err
+				}
+			}
 			if service.verbosity >= 4 {
 				fmt.Println("   Returned ADU value:", aduValue)
 			}
-			return aduValue, nil
+			result, err := service.captureResult(ctx, frame, wantResult)
+			if err != nil {
+				return CaptureResult{}, err
+			}
+			if !saveImage {
+				result.Path = ""
+			}
+			result.ADU = aduValue
+			return result, nil
 		}
 		if secondsWaitedSoFar > maximumWaitSeconds {
-			return 0, errors.New("TheSkyServiceInstance/CaptureAndMeasureFlatFrame: Timeout waiting for capture to finish")
+			return CaptureResult{}, errors.New("TheSkyServiceInstance/CaptureAndMeasureFlatFrame: Timeout waiting for capture to finish")
 		}
 		if service.verbosity >= 4 {
 			fmt.Println("Camera not finished. Delaying ", pollingInterval)
 		}
-		if _, err := service.delayService.DelayDuration(int(math.Round(pollingInterval))); err != nil {
-			fmt.Println("TheSkyServiceInstance/CaptureDarkFrame error from polling delaypkg service:", err)
-			return 0, err
+		if err := service.delayContext(ctx, int(math.Round(pollingInterval))); err != nil {
+			fmt.Println("TheSkyServiceInstance/CaptureAndMeasureFlatFrame error from polling delaypkg service:", err)
+			return CaptureResult{}, service.abortIfCancelled(ctx, err)
 		}
 		secondsWaitedSoFar += pollingInterval
 	}
 }
 
-// Simulate a frame capture by doing a simple linear formula with experimental slope and intercept,
-// and add a bit of noise
+// delayContext waits the given number of seconds using the delay service, but returns early
+// with ctx.Err() if the context is cancelled first.
+func (service *TheSkyServiceInstance) delayContext(ctx context.Context, seconds int) error {
+	return delayWithContext(ctx, service.delayService, seconds)
+}
+
+// delayWithContext is delayContext for any delay service.  Delay services know nothing of
+// contexts, so the delay is taken a second at a time, checking the context in between; a
+// cancel is noticed within a second.  A mock delay service returns at once, so it is asked
+// for the whole delay in one call, as tests expect.
+func delayWithContext(ctx context.Context, delayService goMockableDelay.DelayService, seconds int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if _, isMock := delayService.(*goMockableDelay.MockDelayService); isMock {
+		if _, err := delayService.DelayDuration(seconds); err != nil {
+			return err
+		}
+		return ctx.Err()
+	}
+	for waited := 0; waited < seconds; waited++ {
+		if _, err := delayService.DelayDuration(1); err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// contextDriver is implemented by drivers that can stop waiting for the server when a context
+// is cancelled.  Mock drivers don't, and are used as they are.
+type contextDriver interface {
+	withContext(ctx context.Context) TheSkyDriver
+}
+
+// driverFor returns the driver to send commands with on behalf of an operation using the given
+// context, so that cancelling the context interrupts a command waiting on the server
+func (service *TheSkyServiceInstance) driverFor(ctx context.Context) TheSkyDriver {
+	if driver, ok := service.driver.(contextDriver); ok && ctx.Done() != nil {
+		return driver.withContext(ctx)
+	}
+	return service.driver
+}
+
+// abortIfCancelled is used when a wait during a capture has failed.  If that is because the
+// context was cancelled, we ask the camera to abort the exposure in progress, and return ctx.Err().
+func (service *TheSkyServiceInstance) abortIfCancelled(ctx context.Context, err error) error {
+	if ctx.Err() == nil || !errors.Is(err, ctx.Err()) {
+		return err
+	}
+	if service.verbosity >= 4 || service.debug {
+		fmt.Println("TheSkyServiceInstance: cancelled during capture, aborting exposure")
+	}
+	if abortErr := service.driver.AbortExposure(); abortErr != nil {
+		fmt.Println("TheSkyServiceInstance error aborting exposure:", abortErr)
+	}
+	return ctx.Err()
+}
+
+// Simulate a frame capture using the flat simulation model
 func (service *TheSkyServiceInstance) simulatedFrameCapture(exposure float64, binning int, filterSlot int, _ float64, _ bool) (int64, error) {
-	var slope float64
-	var intercept float64
-	if (binning == 1) && (filterSlot == 4) {
-		// Luminance, binned 1x1
-		slope = 721.8
-		intercept = 19817.0
-	} else if (binning == 2) && (filterSlot == 1) {
-		// Red filter, binned 2x2
-		slope = 7336.7
-		intercept = -100.48
-	} else if (binning == 2) && (filterSlot == 2) {
-		// Green filter, binned 2x2
-		slope = 11678.0
-		intercept = -293.09
-	} else if (binning == 2) && (filterSlot == 3) {
-		// Blue filter, binned 2x2
-		slope = 6820.4
-		intercept = 1858.3
-	} else if (binning == 1) && (filterSlot == 5) {
-		// H-alpha filter, binned 1x1
-		slope = 67.247
-		intercept = 2632.7
-	} else {
-		slope = 721.8
-		intercept = 19817.0
-	}
-	calculatedResult := slope*exposure + intercept
-
-	// Now we'll put a small percentage noise into the value, so it has some variability for realism
-	randFactorZeroCentered := service.simulationNoiseFraction * (rand.Float64() - 0.5)
-	noisyResult := calculatedResult + randFactorZeroCentered*calculatedResult
-	roundedNoisyResult := math.Round(noisyResult)
-	intResult := int64(math.Min(roundedNoisyResult, 65535.0))
+	brightness := 1.0
+	if service.simulatedSkyBrightness != nil {
+		brightness = service.simulatedSkyBrightness(service.clock())
+	}
+	intResult := service.flatSimulationModel.SimulateADU(exposure, binning, filterSlot, brightness)
 
 	if service.verbosity >= 4 {
 		fmt.Printf("Simulated flat adu for exp %g, binning %d, filter %d = %d\n", exposure, binning, filterSlot, intResult)
@@ -459,15 +774,23 @@ func (service *TheSkyServiceInstance) simulatedFrameCapture(exposure float64, bi
 //	Determine if the filter wheel is connected
 //		If yes, then there is a filter wheel (duh)
 //		If no, then try to connect.
-//			If that fails, there is no filter wheel.
+//			If that fails with ERR_CANNOT_COLORGRAB, there is no filter wheel.
+//			If it fails some other way, we can't tell, so return the error.
 //			If the connect succeeds, then there is a filter wheel; and disconnect again
 func (service *TheSkyServiceInstance) HasFilterWheel() (bool, error) {
+	return service.HasFilterWheelContext(context.Background())
+}
+
+func (service *TheSkyServiceInstance) HasFilterWheelContext(ctx context.Context) (bool, error) {
 	if service.verbosity >= 4 || service.debug {
 		fmt.Println("TheSkyServiceInstance/HasFilterWheel ")
 	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
 
 	// Ask if filter wheel is connected
-	isConnected, err := service.driver.FilterWheelIsConnected()
+	isConnected, err := service.driverFor(ctx).FilterWheelIsConnected()
 	//	Success means there is a wheel
 	if err != nil {
 		fmt.Println("HasFilterWheel error from driver checking if connected:", err)
@@ -478,16 +801,24 @@ func (service *TheSkyServiceInstance) HasFilterWheel() (bool, error) {
 	}
 
 	// Not connected.  Try to connect
-	err = service.driver.FilterWheelConnect()
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	err = service.driverFor(ctx).FilterWheelConnect()
 
-	//	Failure?  No filter wheel
-	if err != nil {
-		//fmt.Println("Filterwheel error connect code: ", err)
+	//	Failure because there's no wheel?  No filter wheel
+	if errors.Is(err, ErrCannotColorGrab) {
 		return false, nil
 	}
-	//	Success?  Filter wheel.  And disconnect.
-
-	_ = service.driver.FilterWheelDisconnect()
+	if err != nil {
+		fmt.Println("HasFilterWheel error from driver connecting filter wheel:", err)
+		return false, err
+	}
+	//	Success?  Filter wheel.  And disconnect, to leave it as we found it, even if cancelled.
+	if err := service.driverFor(ctx).FilterWheelDisconnect(); err != nil {
+		fmt.Println("HasFilterWheel error from driver disconnecting filter wheel:", err)
+		return true, err
+	}
 	return true, nil
 
 }
@@ -498,12 +829,19 @@ func (service *TheSkyServiceInstance) HasFilterWheel() (bool, error) {
 //	returns an absurd number of filters with names that get filled in automatically.
 //	Instead, we are going to retrieve the actual filter names, and count up to, not including, the first blank one
 func (service *TheSkyServiceInstance) NumberOfFilters() (int, error) {
+	return service.NumberOfFiltersContext(context.Background())
+}
+
+func (service *TheSkyServiceInstance) NumberOfFiltersContext(ctx context.Context) (int, error) {
 	if service.verbosity >= 4 || service.debug {
 		fmt.Println("TheSkyServiceInstance/NumberOfFilters ")
 	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 
 	// Ask driver for filter names
-	filterNames, err := service.driver.FilterNames()
+	filterNames, err := service.driverFor(ctx).FilterNames()
 	if err != nil {
 		fmt.Println("NumberOfFilters error from driver retrieving filter names:", err)
 		return 0, err
@@ -520,12 +858,19 @@ func (service *TheSkyServiceInstance) NumberOfFilters() (int, error) {
 }
 
 func (service *TheSkyServiceInstance) FilterNames() ([]string, error) {
+	return service.FilterNamesContext(context.Background())
+}
+
+func (service *TheSkyServiceInstance) FilterNamesContext(ctx context.Context) ([]string, error) {
 	if service.verbosity >= 4 || service.debug {
 		fmt.Println("TheSkyServiceInstance/FilterNames ")
 	}
+	if err := ctx.Err(); err != nil {
+		return []string{}, err
+	}
 
 	// Ask driver for filter names
-	filterNames, err := service.driver.FilterNames()
+	filterNames, err := service.driverFor(ctx).FilterNames()
 	if err != nil {
 		fmt.Println("FilterNames error from driver retrieving filter names:", err)
 		return []string{}, err
@@ -2,8 +2,10 @@ package goTheSkyX
 
 import (
 	"github.com/RMcDOttawa/goMockableDelay"
+	"github.com/RMcDOttawa/goTheSkyX/fakeTheSkyX"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
+	"os"
 	"strings"
 	"sync"
 	"testing"
@@ -15,6 +17,10 @@ import (
 // To run these tests, you need to have TheSkyX running on the same machine as the tests are running.
 // TheSkyX must be running in Server mode, and the port number must match the port number in the tests.
 // TheSkyX must be connected to a camera simulator (not real camera - so response is immediate) and filter wheel simulator.
+//
+// By default, the tests run against the in-process fake server in package fakeTheSkyX, so they can run
+// offline.  To run them against a real TheSkyX instead, set environment variable THESKYX_SERVER to its
+// host name; the port is then 3040.
 
 //	The following constant turns the tests off to prevent them from running during continuous integration
 //	or when test ./... is used, except when we want to run them.
@@ -31,8 +37,34 @@ const isThereInFactAFilterWheel = true
 const expectedNumberOfFilters = 5 // Number of slots in the bisque filter wheel simulator
 var expectedFilterNames = [...]string{"red", "green", "blue", "luminance", "ha"}
 
+const realServerEnvironmentVariable = "THESKYX_SERVER"
+const realServerPort = 3040
+
+// Address of the server the integration tests talk to, set up by startIntegrationServer
+var integrationServer = "localhost"
+var integrationPort = realServerPort
+
+// startIntegrationServer starts a fake TheSkyX server for the tests to use, unless the environment
+// says to use a real one.  The returned function shuts down whatever was started.
+func startIntegrationServer(t *testing.T) func() {
+	if realServer := os.Getenv(realServerEnvironmentVariable); realServer != "" {
+		integrationServer = realServer
+		integrationPort = realServerPort
+		return func() {}
+	}
+	fake := fakeTheSkyX.NewFakeTheSkyServer(false, 0)
+	err := fake.Start()
+	require.Nil(t, err, "Unable to start fake TheSkyX server")
+	integrationServer = "localhost"
+	integrationPort = fake.Port()
+	return func() { _ = fake.Close() }
+}
+
 func TestServerIntegration(t *testing.T) {
 	if runIntegrationTests {
+		stopServer := startIntegrationServer(t)
+		defer stopServer()
+
 		t.Run("Integration test camera cooler", func(t *testing.T) {
 			testFuncMutex.Lock()
 			defer testFuncMutex.Unlock()
@@ -45,7 +77,7 @@ func TestServerIntegration(t *testing.T) {
 			realDelayService := goMockableDelay.NewDelayService(false, 1)
 			server := NewTheSkyService(mockDelayService, false, 1, true)
 
-			err := server.Connect("localhost", 3040)
+			err := server.Connect(integrationServer, integrationPort)
 			require.Nil(t, err, "Unable to connect to service")
 			err = server.ConnectCamera()
 			require.Nil(t, err, "Unable to connect to camera")
@@ -77,7 +109,7 @@ func TestServerIntegration(t *testing.T) {
 			realDelayService := goMockableDelay.NewDelayService(false, 1)
 			server := NewTheSkyService(realDelayService, false, 1, true)
 
-			err := server.Connect("localhost", 3040)
+			err := server.Connect(integrationServer, integrationPort)
 			require.Nil(t, err, "Unable to connect to service")
 			err = server.ConnectCamera()
 			require.Nil(t, err, "Unable to connect to camera")
@@ -103,7 +135,7 @@ func TestServerIntegration(t *testing.T) {
 			realDelayService := goMockableDelay.NewDelayService(false, 1)
 			server := NewTheSkyService(realDelayService, false, 1, true)
 
-			err := server.Connect("localhost", 3040)
+			err := server.Connect(integrationServer, integrationPort)
 			require.Nil(t, err, "Unable to connect to service")
 			err = server.ConnectCamera()
 			require.Nil(t, err, "Unable to connect to camera")
@@ -133,7 +165,7 @@ func TestServerIntegration(t *testing.T) {
 			realDelayService := goMockableDelay.NewDelayService(false, 1)
 			server := NewTheSkyService(realDelayService, false, 1, true)
 
-			err := server.Connect("localhost", 3040)
+			err := server.Connect(integrationServer, integrationPort)
 			require.Nil(t, err, "Unable to connect to service")
 			err = server.ConnectCamera()
 			require.Nil(t, err, "Unable to connect to camera")
@@ -162,7 +194,7 @@ func TestServerIntegration(t *testing.T) {
 			realDelayService := goMockableDelay.NewDelayService(false, 1)
 			server := NewTheSkyService(realDelayService, false, 1, true)
 
-			err := server.Connect("localhost", 3040)
+			err := server.Connect(integrationServer, integrationPort)
 			require.Nil(t, err, "Unable to connect to service")
 			err = server.ConnectCamera()
 			require.Nil(t, err, "Unable to connect to camera")
@@ -195,7 +227,7 @@ func TestServerIntegration(t *testing.T) {
 
 				mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
 				server := NewTheSkyService(mockDelayService, false, 1, true)
-				err := server.Connect("localhost", 3040)
+				err := server.Connect(integrationServer, integrationPort)
 				require.Nil(t, err, "Unable to connect to service")
 				err = server.ConnectCamera()
 				require.Nil(t, err, "Unable to connect to camera")
@@ -217,7 +249,7 @@ func TestServerIntegration(t *testing.T) {
 
 				mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
 				server := NewTheSkyService(mockDelayService, false, 1, true)
-				err := server.Connect("localhost", 3040)
+				err := server.Connect(integrationServer, integrationPort)
 				require.Nil(t, err, "Unable to connect to service")
 				err = server.ConnectCamera()
 				require.Nil(t, err, "Unable to connect to camera")
@@ -239,7 +271,7 @@ func TestServerIntegration(t *testing.T) {
 
 				mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
 				server := NewTheSkyService(mockDelayService, false, 1, true)
-				err := server.Connect("localhost", 3040)
+				err := server.Connect(integrationServer, integrationPort)
 				require.Nil(t, err, "Unable to connect to service")
 				err = server.ConnectCamera()
 				require.Nil(t, err, "Unable to connect to camera")
@@ -5,7 +5,9 @@
 package goTheSkyX
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -33,6 +35,36 @@ func (m *MockTheSkyService) EXPECT() *MockTheSkyServiceMockRecorder {
 	return m.recorder
 }
 
+// CameraCapabilities mocks base method.
+func (m *MockTheSkyService) CameraCapabilities() (CameraCapabilities, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CameraCapabilities")
+	ret0, _ := ret[0].(CameraCapabilities)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CameraCapabilities indicates an expected call of CameraCapabilities.
+func (mr *MockTheSkyServiceMockRecorder) CameraCapabilities() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CameraCapabilities", reflect.TypeOf((*MockTheSkyService)(nil).CameraCapabilities))
+}
+
+// CameraCapabilitiesContext mocks base method.
+func (m *MockTheSkyService) CameraCapabilitiesContext(arg0 context.Context) (CameraCapabilities, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CameraCapabilitiesContext", arg0)
+	ret0, _ := ret[0].(CameraCapabilities)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CameraCapabilitiesContext indicates an expected call of CameraCapabilitiesContext.
+func (mr *MockTheSkyServiceMockRecorder) CameraCapabilitiesContext(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CameraCapabilitiesContext", reflect.TypeOf((*MockTheSkyService)(nil).CameraCapabilitiesContext), arg0)
+}
+
 // CaptureAndMeasureFlatFrame mocks base method.
 func (m *MockTheSkyService) CaptureAndMeasureFlatFrame(arg0 float64, arg1, arg2 int, arg3 float64, arg4 bool) (int64, error) {
 	m.ctrl.T.Helper()
@@ -42,155 +74,820 @@ func (m *MockTheSkyService) CaptureAndMeasureFlatFrame(arg0 float64, arg1, arg2
 	return ret0, ret1
 }
 
-// CaptureAndMeasureFlatFrame indicates an expected call of CaptureAndMeasureFlatFrame.
-func (mr *MockTheSkyServiceMockRecorder) CaptureAndMeasureFlatFrame(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+// CaptureAndMeasureFlatFrame indicates an expected call of CaptureAndMeasureFlatFrame.
+func (mr *MockTheSkyServiceMockRecorder) CaptureAndMeasureFlatFrame(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureAndMeasureFlatFrame", reflect.TypeOf((*MockTheSkyService)(nil).CaptureAndMeasureFlatFrame), arg0, arg1, arg2, arg3, arg4)
+}
+
+// CaptureAndMeasureFlatFrameContext mocks base method.
+func (m *MockTheSkyService) CaptureAndMeasureFlatFrameContext(arg0 context.Context, arg1 float64, arg2, arg3 int, arg4 float64, arg5 bool) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureAndMeasureFlatFrameContext", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureAndMeasureFlatFrameContext indicates an expected call of CaptureAndMeasureFlatFrameContext.
+func (mr *MockTheSkyServiceMockRecorder) CaptureAndMeasureFlatFrameContext(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureAndMeasureFlatFrameContext", reflect.TypeOf((*MockTheSkyService)(nil).CaptureAndMeasureFlatFrameContext), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// CaptureAndMeasureFlatFrameResult mocks base method.
+func (m *MockTheSkyService) CaptureAndMeasureFlatFrameResult(arg0 float64, arg1, arg2 int, arg3 float64, arg4 bool) (CaptureResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureAndMeasureFlatFrameResult", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(CaptureResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureAndMeasureFlatFrameResult indicates an expected call of CaptureAndMeasureFlatFrameResult.
+func (mr *MockTheSkyServiceMockRecorder) CaptureAndMeasureFlatFrameResult(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureAndMeasureFlatFrameResult", reflect.TypeOf((*MockTheSkyService)(nil).CaptureAndMeasureFlatFrameResult), arg0, arg1, arg2, arg3, arg4)
+}
+
+// CaptureAndMeasureFlatFrameResultContext mocks base method.
+func (m *MockTheSkyService) CaptureAndMeasureFlatFrameResultContext(arg0 context.Context, arg1 float64, arg2, arg3 int, arg4 float64, arg5 bool) (CaptureResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureAndMeasureFlatFrameResultContext", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(CaptureResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureAndMeasureFlatFrameResultContext indicates an expected call of CaptureAndMeasureFlatFrameResultContext.
+func (mr *MockTheSkyServiceMockRecorder) CaptureAndMeasureFlatFrameResultContext(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureAndMeasureFlatFrameResultContext", reflect.TypeOf((*MockTheSkyService)(nil).CaptureAndMeasureFlatFrameResultContext), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// CaptureBiasFrame mocks base method.
+func (m *MockTheSkyService) CaptureBiasFrame(arg0 int, arg1 float64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureBiasFrame", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CaptureBiasFrame indicates an expected call of CaptureBiasFrame.
+func (mr *MockTheSkyServiceMockRecorder) CaptureBiasFrame(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureBiasFrame", reflect.TypeOf((*MockTheSkyService)(nil).CaptureBiasFrame), arg0, arg1)
+}
+
+// CaptureBiasFrameContext mocks base method.
+func (m *MockTheSkyService) CaptureBiasFrameContext(arg0 context.Context, arg1 int, arg2 float64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureBiasFrameContext", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CaptureBiasFrameContext indicates an expected call of CaptureBiasFrameContext.
+func (mr *MockTheSkyServiceMockRecorder) CaptureBiasFrameContext(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureBiasFrameContext", reflect.TypeOf((*MockTheSkyService)(nil).CaptureBiasFrameContext), arg0, arg1, arg2)
+}
+
+// CaptureBiasFrameResult mocks base method.
+func (m *MockTheSkyService) CaptureBiasFrameResult(arg0 int, arg1 float64) (CaptureResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureBiasFrameResult", arg0, arg1)
+	ret0, _ := ret[0].(CaptureResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureBiasFrameResult indicates an expected call of CaptureBiasFrameResult.
+func (mr *MockTheSkyServiceMockRecorder) CaptureBiasFrameResult(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureBiasFrameResult", reflect.TypeOf((*MockTheSkyService)(nil).CaptureBiasFrameResult), arg0, arg1)
+}
+
+// CaptureBiasFrameResultContext mocks base method.
+func (m *MockTheSkyService) CaptureBiasFrameResultContext(arg0 context.Context, arg1 int, arg2 float64) (CaptureResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureBiasFrameResultContext", arg0, arg1, arg2)
+	ret0, _ := ret[0].(CaptureResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureBiasFrameResultContext indicates an expected call of CaptureBiasFrameResultContext.
+func (mr *MockTheSkyServiceMockRecorder) CaptureBiasFrameResultContext(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureBiasFrameResultContext", reflect.TypeOf((*MockTheSkyService)(nil).CaptureBiasFrameResultContext), arg0, arg1, arg2)
+}
+
+// CaptureDarkFrame mocks base method.
+func (m *MockTheSkyService) CaptureDarkFrame(arg0 int, arg1, arg2 float64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureDarkFrame", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CaptureDarkFrame indicates an expected call of CaptureDarkFrame.
+func (mr *MockTheSkyServiceMockRecorder) CaptureDarkFrame(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureDarkFrame", reflect.TypeOf((*MockTheSkyService)(nil).CaptureDarkFrame), arg0, arg1, arg2)
+}
+
+// CaptureDarkFrameContext mocks base method.
+func (m *MockTheSkyService) CaptureDarkFrameContext(arg0 context.Context, arg1 int, arg2, arg3 float64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureDarkFrameContext", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CaptureDarkFrameContext indicates an expected call of CaptureDarkFrameContext.
+func (mr *MockTheSkyServiceMockRecorder) CaptureDarkFrameContext(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureDarkFrameContext", reflect.TypeOf((*MockTheSkyService)(nil).CaptureDarkFrameContext), arg0, arg1, arg2, arg3)
+}
+
+// CaptureDarkFrameResult mocks base method.
+func (m *MockTheSkyService) CaptureDarkFrameResult(arg0 int, arg1, arg2 float64) (CaptureResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureDarkFrameResult", arg0, arg1, arg2)
+	ret0, _ := ret[0].(CaptureResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureDarkFrameResult indicates an expected call of CaptureDarkFrameResult.
+func (mr *MockTheSkyServiceMockRecorder) CaptureDarkFrameResult(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureDarkFrameResult", reflect.TypeOf((*MockTheSkyService)(nil).CaptureDarkFrameResult), arg0, arg1, arg2)
+}
+
+// CaptureDarkFrameResultContext mocks base method.
+func (m *MockTheSkyService) CaptureDarkFrameResultContext(arg0 context.Context, arg1 int, arg2, arg3 float64) (CaptureResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureDarkFrameResultContext", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(CaptureResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureDarkFrameResultContext indicates an expected call of CaptureDarkFrameResultContext.
+func (mr *MockTheSkyServiceMockRecorder) CaptureDarkFrameResultContext(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureDarkFrameResultContext", reflect.TypeOf((*MockTheSkyService)(nil).CaptureDarkFrameResultContext), arg0, arg1, arg2, arg3)
+}
+
+// CaptureFlatSets mocks base method.
+func (m *MockTheSkyService) CaptureFlatSets(arg0 FlatSetSettings) ([]FlatSetResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureFlatSets", arg0)
+	ret0, _ := ret[0].([]FlatSetResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureFlatSets indicates an expected call of CaptureFlatSets.
+func (mr *MockTheSkyServiceMockRecorder) CaptureFlatSets(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureFlatSets", reflect.TypeOf((*MockTheSkyService)(nil).CaptureFlatSets), arg0)
+}
+
+// CaptureFlatSetsContext mocks base method.
+func (m *MockTheSkyService) CaptureFlatSetsContext(arg0 context.Context, arg1 FlatSetSettings) ([]FlatSetResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureFlatSetsContext", arg0, arg1)
+	ret0, _ := ret[0].([]FlatSetResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureFlatSetsContext indicates an expected call of CaptureFlatSetsContext.
+func (mr *MockTheSkyServiceMockRecorder) CaptureFlatSetsContext(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureFlatSetsContext", reflect.TypeOf((*MockTheSkyService)(nil).CaptureFlatSetsContext), arg0, arg1)
+}
+
+// CaptureTwilightFlats mocks base method.
+func (m *MockTheSkyService) CaptureTwilightFlats(arg0 TwilightFlatSettings) ([]FlatSetResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureTwilightFlats", arg0)
+	ret0, _ := ret[0].([]FlatSetResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureTwilightFlats indicates an expected call of CaptureTwilightFlats.
+func (mr *MockTheSkyServiceMockRecorder) CaptureTwilightFlats(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureTwilightFlats", reflect.TypeOf((*MockTheSkyService)(nil).CaptureTwilightFlats), arg0)
+}
+
+// CaptureTwilightFlatsContext mocks base method.
+func (m *MockTheSkyService) CaptureTwilightFlatsContext(arg0 context.Context, arg1 TwilightFlatSettings) ([]FlatSetResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureTwilightFlatsContext", arg0, arg1)
+	ret0, _ := ret[0].([]FlatSetResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureTwilightFlatsContext indicates an expected call of CaptureTwilightFlatsContext.
+func (mr *MockTheSkyServiceMockRecorder) CaptureTwilightFlatsContext(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureTwilightFlatsContext", reflect.TypeOf((*MockTheSkyService)(nil).CaptureTwilightFlatsContext), arg0, arg1)
+}
+
+// Close mocks base method.
+func (m *MockTheSkyService) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockTheSkyServiceMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockTheSkyService)(nil).Close))
+}
+
+// Connect mocks base method.
+func (m *MockTheSkyService) Connect(arg0 string, arg1 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Connect", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Connect indicates an expected call of Connect.
+func (mr *MockTheSkyServiceMockRecorder) Connect(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Connect", reflect.TypeOf((*MockTheSkyService)(nil).Connect), arg0, arg1)
+}
+
+// ConnectCamera mocks base method.
+func (m *MockTheSkyService) ConnectCamera() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConnectCamera")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConnectCamera indicates an expected call of ConnectCamera.
+func (mr *MockTheSkyServiceMockRecorder) ConnectCamera() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectCamera", reflect.TypeOf((*MockTheSkyService)(nil).ConnectCamera))
+}
+
+// ConnectTelescope mocks base method.
+func (m *MockTheSkyService) ConnectTelescope() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConnectTelescope")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConnectTelescope indicates an expected call of ConnectTelescope.
+func (mr *MockTheSkyServiceMockRecorder) ConnectTelescope() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectTelescope", reflect.TypeOf((*MockTheSkyService)(nil).ConnectTelescope))
+}
+
+// CurrentFilterSlot mocks base method.
+func (m *MockTheSkyService) CurrentFilterSlot() (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CurrentFilterSlot")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CurrentFilterSlot indicates an expected call of CurrentFilterSlot.
+func (mr *MockTheSkyServiceMockRecorder) CurrentFilterSlot() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CurrentFilterSlot", reflect.TypeOf((*MockTheSkyService)(nil).CurrentFilterSlot))
+}
+
+// CurrentFilterSlotContext mocks base method.
+func (m *MockTheSkyService) CurrentFilterSlotContext(arg0 context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CurrentFilterSlotContext", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CurrentFilterSlotContext indicates an expected call of CurrentFilterSlotContext.
+func (mr *MockTheSkyServiceMockRecorder) CurrentFilterSlotContext(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CurrentFilterSlotContext", reflect.TypeOf((*MockTheSkyService)(nil).CurrentFilterSlotContext), arg0)
+}
+
+// DisconnectFilterWheel mocks base method.
+func (m *MockTheSkyService) DisconnectFilterWheel() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisconnectFilterWheel")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisconnectFilterWheel indicates an expected call of DisconnectFilterWheel.
+func (mr *MockTheSkyServiceMockRecorder) DisconnectFilterWheel() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisconnectFilterWheel", reflect.TypeOf((*MockTheSkyService)(nil).DisconnectFilterWheel))
+}
+
+// DisconnectFilterWheelContext mocks base method.
+func (m *MockTheSkyService) DisconnectFilterWheelContext(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisconnectFilterWheelContext", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisconnectFilterWheelContext indicates an expected call of DisconnectFilterWheelContext.
+func (mr *MockTheSkyServiceMockRecorder) DisconnectFilterWheelContext(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisconnectFilterWheelContext", reflect.TypeOf((*MockTheSkyService)(nil).DisconnectFilterWheelContext), arg0)
+}
+
+// FilterDetails mocks base method.
+func (m *MockTheSkyService) FilterDetails() ([]FilterInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FilterDetails")
+	ret0, _ := ret[0].([]FilterInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FilterDetails indicates an expected call of FilterDetails.
+func (mr *MockTheSkyServiceMockRecorder) FilterDetails() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FilterDetails", reflect.TypeOf((*MockTheSkyService)(nil).FilterDetails))
+}
+
+// FilterDetailsContext mocks base method.
+func (m *MockTheSkyService) FilterDetailsContext(arg0 context.Context) ([]FilterInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FilterDetailsContext", arg0)
+	ret0, _ := ret[0].([]FilterInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FilterDetailsContext indicates an expected call of FilterDetailsContext.
+func (mr *MockTheSkyServiceMockRecorder) FilterDetailsContext(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FilterDetailsContext", reflect.TypeOf((*MockTheSkyService)(nil).FilterDetailsContext), arg0)
+}
+
+// FilterNames mocks base method.
+func (m *MockTheSkyService) FilterNames() ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FilterNames")
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FilterNames indicates an expected call of FilterNames.
+func (mr *MockTheSkyServiceMockRecorder) FilterNames() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FilterNames", reflect.TypeOf((*MockTheSkyService)(nil).FilterNames))
+}
+
+// FilterNamesContext mocks base method.
+func (m *MockTheSkyService) FilterNamesContext(arg0 context.Context) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FilterNamesContext", arg0)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FilterNamesContext indicates an expected call of FilterNamesContext.
+func (mr *MockTheSkyServiceMockRecorder) FilterNamesContext(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FilterNamesContext", reflect.TypeOf((*MockTheSkyService)(nil).FilterNamesContext), arg0)
+}
+
+// FindFlatExposure mocks base method.
+func (m *MockTheSkyService) FindFlatExposure(arg0 FlatExposureSearch) (FlatExposureResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindFlatExposure", arg0)
+	ret0, _ := ret[0].(FlatExposureResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindFlatExposure indicates an expected call of FindFlatExposure.
+func (mr *MockTheSkyServiceMockRecorder) FindFlatExposure(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindFlatExposure", reflect.TypeOf((*MockTheSkyService)(nil).FindFlatExposure), arg0)
+}
+
+// FindFlatExposureContext mocks base method.
+func (m *MockTheSkyService) FindFlatExposureContext(arg0 context.Context, arg1 FlatExposureSearch) (FlatExposureResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindFlatExposureContext", arg0, arg1)
+	ret0, _ := ret[0].(FlatExposureResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindFlatExposureContext indicates an expected call of FindFlatExposureContext.
+func (mr *MockTheSkyServiceMockRecorder) FindFlatExposureContext(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindFlatExposureContext", reflect.TypeOf((*MockTheSkyService)(nil).FindFlatExposureContext), arg0, arg1)
+}
+
+// GetCameraTemperature mocks base method.
+func (m *MockTheSkyService) GetCameraTemperature() (float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCameraTemperature")
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCameraTemperature indicates an expected call of GetCameraTemperature.
+func (mr *MockTheSkyServiceMockRecorder) GetCameraTemperature() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCameraTemperature", reflect.TypeOf((*MockTheSkyService)(nil).GetCameraTemperature))
+}
+
+// GetCameraTemperatureContext mocks base method.
+func (m *MockTheSkyService) GetCameraTemperatureContext(arg0 context.Context) (float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCameraTemperatureContext", arg0)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCameraTemperatureContext indicates an expected call of GetCameraTemperatureContext.
+func (mr *MockTheSkyServiceMockRecorder) GetCameraTemperatureContext(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCameraTemperatureContext", reflect.TypeOf((*MockTheSkyService)(nil).GetCameraTemperatureContext), arg0)
+}
+
+// GetCoolerStatus mocks base method.
+func (m *MockTheSkyService) GetCoolerStatus() (CoolerStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCoolerStatus")
+	ret0, _ := ret[0].(CoolerStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCoolerStatus indicates an expected call of GetCoolerStatus.
+func (mr *MockTheSkyServiceMockRecorder) GetCoolerStatus() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCoolerStatus", reflect.TypeOf((*MockTheSkyService)(nil).GetCoolerStatus))
+}
+
+// GetCoolerStatusContext mocks base method.
+func (m *MockTheSkyService) GetCoolerStatusContext(arg0 context.Context) (CoolerStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCoolerStatusContext", arg0)
+	ret0, _ := ret[0].(CoolerStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCoolerStatusContext indicates an expected call of GetCoolerStatusContext.
+func (mr *MockTheSkyServiceMockRecorder) GetCoolerStatusContext(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCoolerStatusContext", reflect.TypeOf((*MockTheSkyService)(nil).GetCoolerStatusContext), arg0)
+}
+
+// GetTelescopePosition mocks base method.
+func (m *MockTheSkyService) GetTelescopePosition() (TelescopePosition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTelescopePosition")
+	ret0, _ := ret[0].(TelescopePosition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTelescopePosition indicates an expected call of GetTelescopePosition.
+func (mr *MockTheSkyServiceMockRecorder) GetTelescopePosition() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTelescopePosition", reflect.TypeOf((*MockTheSkyService)(nil).GetTelescopePosition))
+}
+
+// GetTelescopePositionContext mocks base method.
+func (m *MockTheSkyService) GetTelescopePositionContext(arg0 context.Context) (TelescopePosition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTelescopePositionContext", arg0)
+	ret0, _ := ret[0].(TelescopePosition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTelescopePositionContext indicates an expected call of GetTelescopePositionContext.
+func (mr *MockTheSkyServiceMockRecorder) GetTelescopePositionContext(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTelescopePositionContext", reflect.TypeOf((*MockTheSkyService)(nil).GetTelescopePositionContext), arg0)
+}
+
+// HasFilterWheel mocks base method.
+func (m *MockTheSkyService) HasFilterWheel() (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HasFilterWheel")
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HasFilterWheel indicates an expected call of HasFilterWheel.
+func (mr *MockTheSkyServiceMockRecorder) HasFilterWheel() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasFilterWheel", reflect.TypeOf((*MockTheSkyService)(nil).HasFilterWheel))
+}
+
+// HasFilterWheelContext mocks base method.
+func (m *MockTheSkyService) HasFilterWheelContext(arg0 context.Context) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HasFilterWheelContext", arg0)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HasFilterWheelContext indicates an expected call of HasFilterWheelContext.
+func (mr *MockTheSkyServiceMockRecorder) HasFilterWheelContext(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasFilterWheelContext", reflect.TypeOf((*MockTheSkyService)(nil).HasFilterWheelContext), arg0)
+}
+
+// ImageStats mocks base method.
+func (m *MockTheSkyService) ImageStats(arg0 float64) (ImageStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImageStats", arg0)
+	ret0, _ := ret[0].(ImageStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImageStats indicates an expected call of ImageStats.
+func (mr *MockTheSkyServiceMockRecorder) ImageStats(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImageStats", reflect.TypeOf((*MockTheSkyService)(nil).ImageStats), arg0)
+}
+
+// ImageStatsContext mocks base method.
+func (m *MockTheSkyService) ImageStatsContext(arg0 context.Context, arg1 float64) (ImageStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImageStatsContext", arg0, arg1)
+	ret0, _ := ret[0].(ImageStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImageStatsContext indicates an expected call of ImageStatsContext.
+func (mr *MockTheSkyServiceMockRecorder) ImageStatsContext(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImageStatsContext", reflect.TypeOf((*MockTheSkyService)(nil).ImageStatsContext), arg0, arg1)
+}
+
+// IsSlewComplete mocks base method.
+func (m *MockTheSkyService) IsSlewComplete() (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsSlewComplete")
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsSlewComplete indicates an expected call of IsSlewComplete.
+func (mr *MockTheSkyServiceMockRecorder) IsSlewComplete() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsSlewComplete", reflect.TypeOf((*MockTheSkyService)(nil).IsSlewComplete))
+}
+
+// MeasureDownloadTime mocks base method.
+func (m *MockTheSkyService) MeasureDownloadTime(arg0 int) (float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MeasureDownloadTime", arg0)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MeasureDownloadTime indicates an expected call of MeasureDownloadTime.
+func (mr *MockTheSkyServiceMockRecorder) MeasureDownloadTime(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MeasureDownloadTime", reflect.TypeOf((*MockTheSkyService)(nil).MeasureDownloadTime), arg0)
+}
+
+// MeasureDownloadTimeContext mocks base method.
+func (m *MockTheSkyService) MeasureDownloadTimeContext(arg0 context.Context, arg1 int) (float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MeasureDownloadTimeContext", arg0, arg1)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MeasureDownloadTimeContext indicates an expected call of MeasureDownloadTimeContext.
+func (mr *MockTheSkyServiceMockRecorder) MeasureDownloadTimeContext(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MeasureDownloadTimeContext", reflect.TypeOf((*MockTheSkyService)(nil).MeasureDownloadTimeContext), arg0, arg1)
+}
+
+// MeasureSubframeDownloadTime mocks base method.
+func (m *MockTheSkyService) MeasureSubframeDownloadTime(arg0 int, arg1 Subframe) (float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MeasureSubframeDownloadTime", arg0, arg1)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MeasureSubframeDownloadTime indicates an expected call of MeasureSubframeDownloadTime.
+func (mr *MockTheSkyServiceMockRecorder) MeasureSubframeDownloadTime(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureAndMeasureFlatFrame", reflect.TypeOf((*MockTheSkyService)(nil).CaptureAndMeasureFlatFrame), arg0, arg1, arg2, arg3, arg4)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MeasureSubframeDownloadTime", reflect.TypeOf((*MockTheSkyService)(nil).MeasureSubframeDownloadTime), arg0, arg1)
 }
 
-// CaptureBiasFrame mocks base method.
-func (m *MockTheSkyService) CaptureBiasFrame(arg0 int, arg1 float64) error {
+// MeasureSubframeDownloadTimeContext mocks base method.
+func (m *MockTheSkyService) MeasureSubframeDownloadTimeContext(arg0 context.Context, arg1 int, arg2 Subframe) (float64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CaptureBiasFrame", arg0, arg1)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "MeasureSubframeDownloadTimeContext", arg0, arg1, arg2)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// CaptureBiasFrame indicates an expected call of CaptureBiasFrame.
-func (mr *MockTheSkyServiceMockRecorder) CaptureBiasFrame(arg0, arg1 interface{}) *gomock.Call {
+// MeasureSubframeDownloadTimeContext indicates an expected call of MeasureSubframeDownloadTimeContext.
+func (mr *MockTheSkyServiceMockRecorder) MeasureSubframeDownloadTimeContext(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureBiasFrame", reflect.TypeOf((*MockTheSkyService)(nil).CaptureBiasFrame), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MeasureSubframeDownloadTimeContext", reflect.TypeOf((*MockTheSkyService)(nil).MeasureSubframeDownloadTimeContext), arg0, arg1, arg2)
 }
 
-// CaptureDarkFrame mocks base method.
-func (m *MockTheSkyService) CaptureDarkFrame(arg0 int, arg1, arg2 float64) error {
+// NumberOfFilters mocks base method.
+func (m *MockTheSkyService) NumberOfFilters() (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CaptureDarkFrame", arg0, arg1, arg2)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "NumberOfFilters")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// CaptureDarkFrame indicates an expected call of CaptureDarkFrame.
-func (mr *MockTheSkyServiceMockRecorder) CaptureDarkFrame(arg0, arg1, arg2 interface{}) *gomock.Call {
+// NumberOfFilters indicates an expected call of NumberOfFilters.
+func (mr *MockTheSkyServiceMockRecorder) NumberOfFilters() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureDarkFrame", reflect.TypeOf((*MockTheSkyService)(nil).CaptureDarkFrame), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NumberOfFilters", reflect.TypeOf((*MockTheSkyService)(nil).NumberOfFilters))
 }
 
-// Close mocks base method.
-func (m *MockTheSkyService) Close() error {
+// NumberOfFiltersContext mocks base method.
+func (m *MockTheSkyService) NumberOfFiltersContext(arg0 context.Context) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Close")
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "NumberOfFiltersContext", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// Close indicates an expected call of Close.
-func (mr *MockTheSkyServiceMockRecorder) Close() *gomock.Call {
+// NumberOfFiltersContext indicates an expected call of NumberOfFiltersContext.
+func (mr *MockTheSkyServiceMockRecorder) NumberOfFiltersContext(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockTheSkyService)(nil).Close))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NumberOfFiltersContext", reflect.TypeOf((*MockTheSkyService)(nil).NumberOfFiltersContext), arg0)
 }
 
-// Connect mocks base method.
-func (m *MockTheSkyService) Connect(arg0 string, arg1 int) error {
+// Park mocks base method.
+func (m *MockTheSkyService) Park(arg0, arg1 int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Connect", arg0, arg1)
+	ret := m.ctrl.Call(m, "Park", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Connect indicates an expected call of Connect.
-func (mr *MockTheSkyServiceMockRecorder) Connect(arg0, arg1 interface{}) *gomock.Call {
+// Park indicates an expected call of Park.
+func (mr *MockTheSkyServiceMockRecorder) Park(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Connect", reflect.TypeOf((*MockTheSkyService)(nil).Connect), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Park", reflect.TypeOf((*MockTheSkyService)(nil).Park), arg0, arg1)
 }
 
-// ConnectCamera mocks base method.
-func (m *MockTheSkyService) ConnectCamera() error {
+// ParkContext mocks base method.
+func (m *MockTheSkyService) ParkContext(arg0 context.Context, arg1, arg2 int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ConnectCamera")
+	ret := m.ctrl.Call(m, "ParkContext", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// ConnectCamera indicates an expected call of ConnectCamera.
-func (mr *MockTheSkyServiceMockRecorder) ConnectCamera() *gomock.Call {
+// ParkContext indicates an expected call of ParkContext.
+func (mr *MockTheSkyServiceMockRecorder) ParkContext(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectCamera", reflect.TypeOf((*MockTheSkyService)(nil).ConnectCamera))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParkContext", reflect.TypeOf((*MockTheSkyService)(nil).ParkContext), arg0, arg1, arg2)
 }
 
-// FilterNames mocks base method.
-func (m *MockTheSkyService) FilterNames() ([]string, error) {
+// ResolveFilter mocks base method.
+func (m *MockTheSkyService) ResolveFilter(arg0 string) (FilterInfo, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FilterNames")
-	ret0, _ := ret[0].([]string)
+	ret := m.ctrl.Call(m, "ResolveFilter", arg0)
+	ret0, _ := ret[0].(FilterInfo)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// FilterNames indicates an expected call of FilterNames.
-func (mr *MockTheSkyServiceMockRecorder) FilterNames() *gomock.Call {
+// ResolveFilter indicates an expected call of ResolveFilter.
+func (mr *MockTheSkyServiceMockRecorder) ResolveFilter(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FilterNames", reflect.TypeOf((*MockTheSkyService)(nil).FilterNames))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveFilter", reflect.TypeOf((*MockTheSkyService)(nil).ResolveFilter), arg0)
 }
 
-// GetCameraTemperature mocks base method.
-func (m *MockTheSkyService) GetCameraTemperature() (float64, error) {
+// ResolveFilterContext mocks base method.
+func (m *MockTheSkyService) ResolveFilterContext(arg0 context.Context, arg1 string) (FilterInfo, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetCameraTemperature")
-	ret0, _ := ret[0].(float64)
+	ret := m.ctrl.Call(m, "ResolveFilterContext", arg0, arg1)
+	ret0, _ := ret[0].(FilterInfo)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetCameraTemperature indicates an expected call of GetCameraTemperature.
-func (mr *MockTheSkyServiceMockRecorder) GetCameraTemperature() *gomock.Call {
+// ResolveFilterContext indicates an expected call of ResolveFilterContext.
+func (mr *MockTheSkyServiceMockRecorder) ResolveFilterContext(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCameraTemperature", reflect.TypeOf((*MockTheSkyService)(nil).GetCameraTemperature))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveFilterContext", reflect.TypeOf((*MockTheSkyService)(nil).ResolveFilterContext), arg0, arg1)
 }
 
-// HasFilterWheel mocks base method.
-func (m *MockTheSkyService) HasFilterWheel() (bool, error) {
+// SelectFilter mocks base method.
+func (m *MockTheSkyService) SelectFilter(arg0 int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "HasFilterWheel")
-	ret0, _ := ret[0].(bool)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "SelectFilter", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// HasFilterWheel indicates an expected call of HasFilterWheel.
-func (mr *MockTheSkyServiceMockRecorder) HasFilterWheel() *gomock.Call {
+// SelectFilter indicates an expected call of SelectFilter.
+func (mr *MockTheSkyServiceMockRecorder) SelectFilter(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasFilterWheel", reflect.TypeOf((*MockTheSkyService)(nil).HasFilterWheel))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectFilter", reflect.TypeOf((*MockTheSkyService)(nil).SelectFilter), arg0)
 }
 
-// MeasureDownloadTime mocks base method.
-func (m *MockTheSkyService) MeasureDownloadTime(arg0 int) (float64, error) {
+// SelectFilterByName mocks base method.
+func (m *MockTheSkyService) SelectFilterByName(arg0 string) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "MeasureDownloadTime", arg0)
-	ret0, _ := ret[0].(float64)
+	ret := m.ctrl.Call(m, "SelectFilterByName", arg0)
+	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// MeasureDownloadTime indicates an expected call of MeasureDownloadTime.
-func (mr *MockTheSkyServiceMockRecorder) MeasureDownloadTime(arg0 interface{}) *gomock.Call {
+// SelectFilterByName indicates an expected call of SelectFilterByName.
+func (mr *MockTheSkyServiceMockRecorder) SelectFilterByName(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MeasureDownloadTime", reflect.TypeOf((*MockTheSkyService)(nil).MeasureDownloadTime), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectFilterByName", reflect.TypeOf((*MockTheSkyService)(nil).SelectFilterByName), arg0)
 }
 
-// NumberOfFilters mocks base method.
-func (m *MockTheSkyService) NumberOfFilters() (int, error) {
+// SelectFilterByNameContext mocks base method.
+func (m *MockTheSkyService) SelectFilterByNameContext(arg0 context.Context, arg1 string) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "NumberOfFilters")
+	ret := m.ctrl.Call(m, "SelectFilterByNameContext", arg0, arg1)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// NumberOfFilters indicates an expected call of NumberOfFilters.
-func (mr *MockTheSkyServiceMockRecorder) NumberOfFilters() *gomock.Call {
+// SelectFilterByNameContext indicates an expected call of SelectFilterByNameContext.
+func (mr *MockTheSkyServiceMockRecorder) SelectFilterByNameContext(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NumberOfFilters", reflect.TypeOf((*MockTheSkyService)(nil).NumberOfFilters))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectFilterByNameContext", reflect.TypeOf((*MockTheSkyService)(nil).SelectFilterByNameContext), arg0, arg1)
+}
+
+// SelectFilterContext mocks base method.
+func (m *MockTheSkyService) SelectFilterContext(arg0 context.Context, arg1 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectFilterContext", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SelectFilterContext indicates an expected call of SelectFilterContext.
+func (mr *MockTheSkyServiceMockRecorder) SelectFilterContext(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectFilterContext", reflect.TypeOf((*MockTheSkyService)(nil).SelectFilterContext), arg0, arg1)
+}
+
+// SetDarkQualityCheck mocks base method.
+func (m *MockTheSkyService) SetDarkQualityCheck(arg0 DarkQualityCheck) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDarkQualityCheck", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDarkQualityCheck indicates an expected call of SetDarkQualityCheck.
+func (mr *MockTheSkyServiceMockRecorder) SetDarkQualityCheck(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDarkQualityCheck", reflect.TypeOf((*MockTheSkyService)(nil).SetDarkQualityCheck), arg0)
 }
 
 // SetDebug mocks base method.
@@ -217,6 +914,44 @@ func (mr *MockTheSkyServiceMockRecorder) SetDriver(arg0 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDriver", reflect.TypeOf((*MockTheSkyService)(nil).SetDriver), arg0)
 }
 
+// SetFilterOverrides mocks base method.
+func (m *MockTheSkyService) SetFilterOverrides(arg0 FilterOverrides) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetFilterOverrides", arg0)
+}
+
+// SetFilterOverrides indicates an expected call of SetFilterOverrides.
+func (mr *MockTheSkyServiceMockRecorder) SetFilterOverrides(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFilterOverrides", reflect.TypeOf((*MockTheSkyService)(nil).SetFilterOverrides), arg0)
+}
+
+// SetFlatSimulationModel mocks base method.
+func (m *MockTheSkyService) SetFlatSimulationModel(arg0 FlatSimulationModel) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetFlatSimulationModel", arg0)
+}
+
+// SetFlatSimulationModel indicates an expected call of SetFlatSimulationModel.
+func (mr *MockTheSkyServiceMockRecorder) SetFlatSimulationModel(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFlatSimulationModel", reflect.TypeOf((*MockTheSkyService)(nil).SetFlatSimulationModel), arg0)
+}
+
+// SetSaveSettings mocks base method.
+func (m *MockTheSkyService) SetSaveSettings(arg0 SaveSettings) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetSaveSettings", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetSaveSettings indicates an expected call of SetSaveSettings.
+func (mr *MockTheSkyServiceMockRecorder) SetSaveSettings(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSaveSettings", reflect.TypeOf((*MockTheSkyService)(nil).SetSaveSettings), arg0)
+}
+
 // SetSimulateFlatCapture mocks base method.
 func (m *MockTheSkyService) SetSimulateFlatCapture(arg0 bool) {
 	m.ctrl.T.Helper()
@@ -229,6 +964,18 @@ func (mr *MockTheSkyServiceMockRecorder) SetSimulateFlatCapture(arg0 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSimulateFlatCapture", reflect.TypeOf((*MockTheSkyService)(nil).SetSimulateFlatCapture), arg0)
 }
 
+// SetSimulatedSkyBrightness mocks base method.
+func (m *MockTheSkyService) SetSimulatedSkyBrightness(arg0 func(now time.Time) float64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSimulatedSkyBrightness", arg0)
+}
+
+// SetSimulatedSkyBrightness indicates an expected call of SetSimulatedSkyBrightness.
+func (mr *MockTheSkyServiceMockRecorder) SetSimulatedSkyBrightness(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSimulatedSkyBrightness", reflect.TypeOf((*MockTheSkyService)(nil).SetSimulatedSkyBrightness), arg0)
+}
+
 // SetSimulationNoiseFraction mocks base method.
 func (m *MockTheSkyService) SetSimulationNoiseFraction(arg0 float64) {
 	m.ctrl.T.Helper()
@@ -241,6 +988,34 @@ func (mr *MockTheSkyServiceMockRecorder) SetSimulationNoiseFraction(arg0 interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSimulationNoiseFraction", reflect.TypeOf((*MockTheSkyService)(nil).SetSimulationNoiseFraction), arg0)
 }
 
+// SetTracking mocks base method.
+func (m *MockTheSkyService) SetTracking(arg0 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTracking", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTracking indicates an expected call of SetTracking.
+func (mr *MockTheSkyServiceMockRecorder) SetTracking(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTracking", reflect.TypeOf((*MockTheSkyService)(nil).SetTracking), arg0)
+}
+
+// SetTrackingContext mocks base method.
+func (m *MockTheSkyService) SetTrackingContext(arg0 context.Context, arg1 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTrackingContext", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTrackingContext indicates an expected call of SetTrackingContext.
+func (mr *MockTheSkyServiceMockRecorder) SetTrackingContext(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTrackingContext", reflect.TypeOf((*MockTheSkyService)(nil).SetTrackingContext), arg0, arg1)
+}
+
 // SetVerbosity mocks base method.
 func (m *MockTheSkyService) SetVerbosity(arg0 int) {
 	m.ctrl.T.Helper()
@@ -253,6 +1028,34 @@ func (mr *MockTheSkyServiceMockRecorder) SetVerbosity(arg0 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVerbosity", reflect.TypeOf((*MockTheSkyService)(nil).SetVerbosity), arg0)
 }
 
+// SlewToAltAz mocks base method.
+func (m *MockTheSkyService) SlewToAltAz(arg0, arg1 float64, arg2, arg3 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SlewToAltAz", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SlewToAltAz indicates an expected call of SlewToAltAz.
+func (mr *MockTheSkyServiceMockRecorder) SlewToAltAz(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SlewToAltAz", reflect.TypeOf((*MockTheSkyService)(nil).SlewToAltAz), arg0, arg1, arg2, arg3)
+}
+
+// SlewToAltAzContext mocks base method.
+func (m *MockTheSkyService) SlewToAltAzContext(arg0 context.Context, arg1, arg2 float64, arg3, arg4 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SlewToAltAzContext", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SlewToAltAzContext indicates an expected call of SlewToAltAzContext.
+func (mr *MockTheSkyServiceMockRecorder) SlewToAltAzContext(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SlewToAltAzContext", reflect.TypeOf((*MockTheSkyService)(nil).SlewToAltAzContext), arg0, arg1, arg2, arg3, arg4)
+}
+
 // StartCooling mocks base method.
 func (m *MockTheSkyService) StartCooling(arg0 float64) error {
 	m.ctrl.T.Helper()
@@ -267,6 +1070,20 @@ func (mr *MockTheSkyServiceMockRecorder) StartCooling(arg0 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartCooling", reflect.TypeOf((*MockTheSkyService)(nil).StartCooling), arg0)
 }
 
+// StartCoolingContext mocks base method.
+func (m *MockTheSkyService) StartCoolingContext(arg0 context.Context, arg1 float64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartCoolingContext", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StartCoolingContext indicates an expected call of StartCoolingContext.
+func (mr *MockTheSkyServiceMockRecorder) StartCoolingContext(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartCoolingContext", reflect.TypeOf((*MockTheSkyService)(nil).StartCoolingContext), arg0, arg1)
+}
+
 // StopCooling mocks base method.
 func (m *MockTheSkyService) StopCooling() error {
 	m.ctrl.T.Helper()
@@ -281,6 +1098,48 @@ func (mr *MockTheSkyServiceMockRecorder) StopCooling() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopCooling", reflect.TypeOf((*MockTheSkyService)(nil).StopCooling))
 }
 
+// StopCoolingContext mocks base method.
+func (m *MockTheSkyService) StopCoolingContext(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StopCoolingContext", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StopCoolingContext indicates an expected call of StopCoolingContext.
+func (mr *MockTheSkyServiceMockRecorder) StopCoolingContext(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopCoolingContext", reflect.TypeOf((*MockTheSkyService)(nil).StopCoolingContext), arg0)
+}
+
+// Unpark mocks base method.
+func (m *MockTheSkyService) Unpark() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unpark")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unpark indicates an expected call of Unpark.
+func (mr *MockTheSkyServiceMockRecorder) Unpark() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unpark", reflect.TypeOf((*MockTheSkyService)(nil).Unpark))
+}
+
+// UnparkContext mocks base method.
+func (m *MockTheSkyService) UnparkContext(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnparkContext", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnparkContext indicates an expected call of UnparkContext.
+func (mr *MockTheSkyServiceMockRecorder) UnparkContext(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnparkContext", reflect.TypeOf((*MockTheSkyService)(nil).UnparkContext), arg0)
+}
+
 // WaitForCameraInactive mocks base method.
 func (m *MockTheSkyService) WaitForCameraInactive(arg0, arg1 int) error {
 	m.ctrl.T.Helper()
@@ -294,3 +1153,107 @@ func (mr *MockTheSkyServiceMockRecorder) WaitForCameraInactive(arg0, arg1 interf
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForCameraInactive", reflect.TypeOf((*MockTheSkyService)(nil).WaitForCameraInactive), arg0, arg1)
 }
+
+// WaitForCameraInactiveContext mocks base method.
+func (m *MockTheSkyService) WaitForCameraInactiveContext(arg0 context.Context, arg1, arg2 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitForCameraInactiveContext", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WaitForCameraInactiveContext indicates an expected call of WaitForCameraInactiveContext.
+func (mr *MockTheSkyServiceMockRecorder) WaitForCameraInactiveContext(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForCameraInactiveContext", reflect.TypeOf((*MockTheSkyService)(nil).WaitForCameraInactiveContext), arg0, arg1, arg2)
+}
+
+// WaitForFilterWheel mocks base method.
+func (m *MockTheSkyService) WaitForFilterWheel(arg0 int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitForFilterWheel", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WaitForFilterWheel indicates an expected call of WaitForFilterWheel.
+func (mr *MockTheSkyServiceMockRecorder) WaitForFilterWheel(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForFilterWheel", reflect.TypeOf((*MockTheSkyService)(nil).WaitForFilterWheel), arg0)
+}
+
+// WaitForFilterWheelContext mocks base method.
+func (m *MockTheSkyService) WaitForFilterWheelContext(arg0 context.Context, arg1 int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitForFilterWheelContext", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WaitForFilterWheelContext indicates an expected call of WaitForFilterWheelContext.
+func (mr *MockTheSkyServiceMockRecorder) WaitForFilterWheelContext(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForFilterWheelContext", reflect.TypeOf((*MockTheSkyService)(nil).WaitForFilterWheelContext), arg0, arg1)
+}
+
+// WaitForTargetTemperature mocks base method.
+func (m *MockTheSkyService) WaitForTargetTemperature(arg0 CoolingWaitSettings) (float64, float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitForTargetTemperature", arg0)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(float64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// WaitForTargetTemperature indicates an expected call of WaitForTargetTemperature.
+func (mr *MockTheSkyServiceMockRecorder) WaitForTargetTemperature(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForTargetTemperature", reflect.TypeOf((*MockTheSkyService)(nil).WaitForTargetTemperature), arg0)
+}
+
+// WaitForTargetTemperatureContext mocks base method.
+func (m *MockTheSkyService) WaitForTargetTemperatureContext(arg0 context.Context, arg1 CoolingWaitSettings) (float64, float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitForTargetTemperatureContext", arg0, arg1)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(float64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// WaitForTargetTemperatureContext indicates an expected call of WaitForTargetTemperatureContext.
+func (mr *MockTheSkyServiceMockRecorder) WaitForTargetTemperatureContext(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForTargetTemperatureContext", reflect.TypeOf((*MockTheSkyService)(nil).WaitForTargetTemperatureContext), arg0, arg1)
+}
+
+// WarmUpAndStopCooling mocks base method.
+func (m *MockTheSkyService) WarmUpAndStopCooling(arg0 WarmUpSettings) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WarmUpAndStopCooling", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WarmUpAndStopCooling indicates an expected call of WarmUpAndStopCooling.
+func (mr *MockTheSkyServiceMockRecorder) WarmUpAndStopCooling(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WarmUpAndStopCooling", reflect.TypeOf((*MockTheSkyService)(nil).WarmUpAndStopCooling), arg0)
+}
+
+// WarmUpAndStopCoolingContext mocks base method.
+func (m *MockTheSkyService) WarmUpAndStopCoolingContext(arg0 context.Context, arg1 WarmUpSettings) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WarmUpAndStopCoolingContext", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WarmUpAndStopCoolingContext indicates an expected call of WarmUpAndStopCoolingContext.
+func (mr *MockTheSkyServiceMockRecorder) WarmUpAndStopCoolingContext(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WarmUpAndStopCoolingContext", reflect.TypeOf((*MockTheSkyService)(nil).WarmUpAndStopCoolingContext), arg0, arg1)
+}
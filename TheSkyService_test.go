@@ -1,12 +1,15 @@
 package goTheSkyX
 
 import (
+	"context"
 	"errors"
 	"github.com/RMcDOttawa/goMockableDelay"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 	"math"
+	"runtime"
 	"testing"
+	"time"
 )
 
 // TestDarkCapture tests the ability to capture a single dark frame.
@@ -204,6 +207,16 @@ func TestFlatCapture(t *testing.T) {
 		require.ErrorContains(t, err, "Timeout waiting for capture to finish")
 	})
 
+	t.Run("zero exposure is an error, not a panic", func(t *testing.T) {
+		mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
+		service := NewTheSkyService(mockDelayService, false, 0, true)
+		mockDriver := NewMockTheSkyDriver(ctrl)
+		service.SetDriver(mockDriver)
+
+		_, err := service.CaptureAndMeasureFlatFrame(0.0, 1, 1, 5.0, false)
+		require.ErrorContains(t, err, "exposure must be greater than 0")
+	})
+
 }
 
 func TestFilterWheel(t *testing.T) {
@@ -253,7 +266,7 @@ func TestFilterWheel(t *testing.T) {
 		service.SetDriver(mockDriver)
 
 		mockDriver.EXPECT().FilterWheelIsConnected().Return(false, nil)
-		mockDriver.EXPECT().FilterWheelConnect().Return(errors.New("can't connect"))
+		mockDriver.EXPECT().FilterWheelConnect().Return(&TheSkyXError{Code: 1166, Message: "No filter wheel"})
 
 		hasFilterWheel, err := service.HasFilterWheel()
 		require.Nil(t, err, "Unable to query filter wheel")
@@ -261,4 +274,129 @@ func TestFilterWheel(t *testing.T) {
 
 	})
 
+	// Other failures to connect the wheel don't tell us whether there is one, so are reported
+	t.Run("filter wheel connect fails for another reason", func(t *testing.T) {
+		mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
+		service := NewTheSkyService(mockDelayService, false, 0, true)
+		// Plug mock driver into service
+		mockDriver := NewMockTheSkyDriver(ctrl)
+		service.SetDriver(mockDriver)
+
+		mockDriver.EXPECT().FilterWheelIsConnected().Return(false, nil)
+		mockDriver.EXPECT().FilterWheelConnect().Return(errors.New("can't connect"))
+
+		_, err := service.HasFilterWheel()
+		require.NotNil(t, err, "Expected error to be reported")
+	})
+
+}
+
+func TestCancellation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Cancel the context while waiting for the exposure to finish.  The service should stop
+	// waiting, ask the camera to abort the exposure, and return the context's error.
+	t.Run("cancel dark frame capture while waiting", func(t *testing.T) {
+		mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
+		service := NewTheSkyService(mockDelayService, false, 0, true)
+		// Plug mock driver into service
+		mockDriver := NewMockTheSkyDriver(ctrl)
+		service.SetDriver(mockDriver)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		const binning = 1
+		const seconds = 600.0
+		const downloadTime = 5.0
+		mockDriver.EXPECT().StartDarkFrameCapture(binning, seconds, downloadTime).Return(nil)
+		//	Operator cancels during the initial wait
+		initialDelay := int(math.Round(seconds + downloadTime + AndALittleExtra)) // from service
+		mockDelayService.EXPECT().DelayDuration(initialDelay).DoAndReturn(func(int) (int, error) {
+			cancel()
+			return 1, nil
+		})
+		mockDriver.EXPECT().AbortExposure().Return(nil)
+
+		err := service.CaptureDarkFrameContext(ctx, binning, seconds, downloadTime)
+		require.ErrorIs(t, err, context.Canceled, "Expected capture to be cancelled")
+	})
+
+	// Cancel while polling for a flat frame to be done
+	t.Run("cancel flat frame capture while polling", func(t *testing.T) {
+		mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
+		service := NewTheSkyService(mockDelayService, false, 0, true)
+		service.SetSimulateFlatCapture(false)
+		// Plug mock driver into service
+		mockDriver := NewMockTheSkyDriver(ctrl)
+		service.SetDriver(mockDriver)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		const binning = 1
+		const seconds = 14.0
+		const downloadTime = 5.0
+		const filterSlot = 1
+		mockDriver.EXPECT().StartFlatFrameCapture(binning, seconds, filterSlot, downloadTime, false).Return(nil)
+		initialDelay := int(math.Round(seconds + downloadTime + AndALittleExtra)) // from service
+		mockDelayService.EXPECT().DelayDuration(initialDelay).Return(initialDelay, nil)
+		mockDriver.EXPECT().IsCaptureDone().Return(false, nil)
+		mockDelayService.EXPECT().DelayDuration(2).DoAndReturn(func(int) (int, error) {
+			cancel()
+			return 2, nil
+		})
+		mockDriver.EXPECT().AbortExposure().Return(nil)
+
+		_, err := service.CaptureAndMeasureFlatFrameContext(ctx, seconds, binning, filterSlot, downloadTime, false)
+		require.ErrorIs(t, err, context.Canceled, "Expected capture to be cancelled")
+	})
+
+	// A context that is already done means nothing is sent to the camera at all
+	t.Run("bias frame with expired context", func(t *testing.T) {
+		mockDelayService := goMockableDelay.NewMockDelayService(ctrl)
+		service := NewTheSkyService(mockDelayService, false, 0, true)
+		// Plug mock driver into service
+		mockDriver := NewMockTheSkyDriver(ctrl)
+		service.SetDriver(mockDriver)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := service.CaptureBiasFrameContext(ctx, 1, 5.0)
+		require.ErrorIs(t, err, context.Canceled, "Expected capture to be cancelled")
+	})
+
+	// Cancel while a command is waiting for the server's reply.  The service should pass the
+	// context down to the driver, so the wait for the network exchange is interrupted.
+	t.Run("cancel while waiting for server reply", func(t *testing.T) {
+		fake, service := startFakeForSaving(t, ctrl)
+		defer fake.Close()
+		defer service.Close()
+		fake.SetReplyChunking(1, 300*time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := service.GetCameraTemperatureContext(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded, "Expected reply wait to be interrupted")
+		require.Less(t, time.Since(start), 2*time.Second, "Expected wait to end at the deadline")
+	})
+
+	// Cancel part way through a real delay.  The wait should end promptly, and no goroutine
+	// should be left sleeping out the rest of the delay.
+	t.Run("cancel during real delay", func(t *testing.T) {
+		delayService := goMockableDelay.NewDelayService(false, 0)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		goroutinesBefore := runtime.NumGoroutine()
+
+		time.AfterFunc(50*time.Millisecond, cancel)
+		start := time.Now()
+		err := delayWithContext(ctx, delayService, 60)
+		require.ErrorIs(t, err, context.Canceled, "Expected delay to be cancelled")
+		require.Less(t, time.Since(start), 5*time.Second, "Expected delay to end soon after cancel")
+		for i := 0; i < 100 && runtime.NumGoroutine() > goroutinesBefore; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		require.LessOrEqual(t, runtime.NumGoroutine(), goroutinesBefore, "Expected no goroutine left running the delay")
+	})
 }
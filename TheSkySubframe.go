@@ -0,0 +1,120 @@
+package goTheSkyX
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+//	Subframe (region of interest) capture.  Finding a flat exposure takes several test frames
+//	whose only purpose is their average ADU, and on a camera with a slow download most of the
+//	time goes in downloading them.  Reading out only a small central crop is much faster and
+//	measures the same ADU, so FindFlatExposure (and CaptureFlatSets, for its search) can take
+//	its test frames as a subframe.  Frames that are kept - darks, bias frames and saved flats -
+//	always use the full sensor.
+//
+//	A subframe's download time is shorter than the full frame's, so measure it with
+//	MeasureSubframeDownloadTime and pass that as the search's download time.
+
+// Subframe is a rectangle of the sensor, in binned pixels as with TheSkyX's
+// ccdsoftCamera.SubframeLeft etc.  Right and Bottom are exclusive.  The zero value is the
+// full sensor.
+type Subframe struct {
+	Left   int
+	Top    int
+	Right  int
+	Bottom int
+}
+
+// ErrInvalidSubframe is returned (wrapped) when a subframe is empty or doesn't fit the sensor
+var ErrInvalidSubframe = errors.New("invalid subframe")
+
+// IsFullFrame reports whether this is the zero value, meaning the full sensor
+func (subframe Subframe) IsFullFrame() bool {
+	return subframe == Subframe{}
+}
+
+// Width is the subframe's width in binned pixels
+func (subframe Subframe) Width() int {
+	return subframe.Right - subframe.Left
+}
+
+// Height is the subframe's height in binned pixels
+func (subframe Subframe) Height() int {
+	return subframe.Bottom - subframe.Top
+}
+
+// CentralSubframe is the subframe covering the given fraction of the width and height of the
+// sensor, at the given binning, centred on it
+func (capabilities CameraCapabilities) CentralSubframe(binning int, fraction float64) Subframe {
+	width := capabilities.WidthPixels / binning
+	height := capabilities.HeightPixels / binning
+	cropWidth := int(math.Max(1.0, math.Round(float64(width)*fraction)))
+	cropHeight := int(math.Max(1.0, math.Round(float64(height)*fraction)))
+	left := (width - cropWidth) / 2
+	top := (height - cropHeight) / 2
+	return Subframe{Left: left, Top: top, Right: left + cropWidth, Bottom: top + cropHeight}
+}
+
+// checkSubframe refuses a subframe that is empty or doesn't fit on the sensor at the given
+// binning.  The sensor size is asked of the camera if CameraCapabilities hasn't been called;
+// if the camera doesn't report it, only empty subframes are refused.
+func (service *TheSkyServiceInstance) checkSubframe(ctx context.Context, subframe Subframe, binning int) error {
+	if subframe.IsFullFrame() {
+		return nil
+	}
+	if subframe.Left < 0 || subframe.Top < 0 || subframe.Width() < 1 || subframe.Height() < 1 {
+		return fmt.Errorf("%w: %+v is empty", ErrInvalidSubframe, subframe)
+	}
+	if binning < 1 {
+		return nil
+	}
+	capabilities := service.cameraCapabilities
+	if capabilities == nil {
+		fetched, err := service.CameraCapabilitiesContext(ctx)
+		if err != nil {
+			return err
+		}
+		capabilities = &fetched
+	}
+	if capabilities.WidthPixels < 1 || capabilities.HeightPixels < 1 {
+		return nil
+	}
+	width := capabilities.WidthPixels / binning
+	height := capabilities.HeightPixels / binning
+	if subframe.Right > width || subframe.Bottom > height {
+		return fmt.Errorf("%w: %+v is outside the %dx%d image at binning %d", ErrInvalidSubframe, subframe, width, height, binning)
+	}
+	return nil
+}
+
+// useSubframe has the driver take flats and download time measurements as the given subframe,
+// and returns a function that goes back to the full frame
+func (service *TheSkyServiceInstance) useSubframe(ctx context.Context, subframe Subframe, binning int) (func(), error) {
+	if subframe.IsFullFrame() {
+		return func() {}, nil
+	}
+	if err := service.checkSubframe(ctx, subframe, binning); err != nil {
+		return nil, err
+	}
+	service.driverFor(ctx).SetSubframe(subframe)
+	return func() { service.driverFor(ctx).SetSubframe(Subframe{}) }, nil
+}
+
+// MeasureSubframeDownloadTime is MeasureDownloadTime for a subframe of the sensor
+func (service *TheSkyServiceInstance) MeasureSubframeDownloadTime(binning int, subframe Subframe) (float64, error) {
+	return service.MeasureSubframeDownloadTimeContext(context.Background(), binning, subframe)
+}
+
+func (service *TheSkyServiceInstance) MeasureSubframeDownloadTimeContext(ctx context.Context, binning int, subframe Subframe) (float64, error) {
+	if !service.isOpen {
+		return 0.0, errors.New("TheSkyServiceInstance/MeasureSubframeDownloadTime: Connection not open")
+	}
+	restoreFullFrame, err := service.useSubframe(ctx, subframe, binning)
+	if err != nil {
+		return 0.0, fmt.Errorf("TheSkyServiceInstance/MeasureSubframeDownloadTime: %w", err)
+	}
+	defer restoreFullFrame()
+	return service.MeasureDownloadTimeContext(ctx, binning)
+}
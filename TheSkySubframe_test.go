@@ -0,0 +1,132 @@
+package goTheSkyX
+
+import (
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSubframe(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("central subframe", func(t *testing.T) {
+		capabilities := CameraCapabilities{WidthPixels: 4656, HeightPixels: 3520}
+		require.Equal(t, Subframe{Left: 1047, Top: 792, Right: 1280, Bottom: 968}, capabilities.CentralSubframe(2, 0.1))
+		subframe := capabilities.CentralSubframe(1, 0.5)
+		require.Equal(t, 2328, subframe.Width())
+		require.Equal(t, 1760, subframe.Height())
+		require.False(t, subframe.IsFullFrame())
+		require.True(t, Subframe{}.IsFullFrame())
+	})
+
+	t.Run("flat search uses subframe and flats use full frame", func(t *testing.T) {
+		fake, service := startFakeForSaving(t, ctrl)
+		defer fake.Close()
+		defer service.Close()
+		fake.SetSensor(400, 300, 3.8)
+		capabilities, err := service.CameraCapabilities()
+		require.Nil(t, err)
+
+		results, err := service.CaptureFlatSets(FlatSetSettings{
+			FilterSlots: []int{1}, Binning: 1, Count: 2, TargetADU: 10000, Tolerance: 0.05,
+			MinExposure: 0.1, MaxExposure: 30.0,
+			SearchSubframe: capabilities.CentralSubframe(1, 0.1), SearchDownloadTime: 0.01,
+		})
+		require.Nil(t, err)
+		require.Nil(t, results[0].Err)
+
+		taken := fake.TakenImages()
+		searchFrames := len(results[0].Search.History)
+		require.Len(t, taken, searchFrames+2)
+		for _, image := range taken[:searchFrames] {
+			require.True(t, image.Subframe, "Search frames should be subframed")
+			require.Equal(t, 40, image.Width)
+			require.Equal(t, 30, image.Height)
+		}
+		for _, image := range taken[searchFrames:] {
+			require.False(t, image.Subframe, "Flats should be full frame")
+			require.Equal(t, 400, image.Width)
+		}
+	})
+
+	t.Run("dark after subframed search is full frame", func(t *testing.T) {
+		fake, service := startFakeForSaving(t, ctrl)
+		defer fake.Close()
+		defer service.Close()
+		fake.SetSensor(400, 300, 3.8)
+
+		_, err := service.FindFlatExposure(FlatExposureSearch{
+			FilterSlot: 1, Binning: 2, TargetADU: 10000, Tolerance: 0.05, MinExposure: 0.1, MaxExposure: 30.0,
+			Subframe: Subframe{Left: 50, Top: 50, Right: 70, Bottom: 60},
+		})
+		require.Nil(t, err)
+		require.Nil(t, service.CaptureDarkFrame(2, 1.0, 0.0))
+
+		taken := fake.TakenImages()
+		require.Equal(t, 20, taken[0].Width)
+		require.Equal(t, 10, taken[0].Height)
+		last := taken[len(taken)-1]
+		require.False(t, last.Subframe)
+		require.Equal(t, 200, last.Width)
+		require.Equal(t, 150, last.Height)
+	})
+
+	t.Run("subframe download is faster", func(t *testing.T) {
+		fake, driver := startFakeForDriver(t)
+		defer fake.Close()
+		defer driver.Close()
+		fake.SetSensor(400, 300, 3.8)
+		fake.SetDownloadTime(0.5)
+
+		fullFrame, err := driver.MeasureDownloadTime(1)
+		require.Nil(t, err)
+		driver.SetSubframe(Subframe{Left: 0, Top: 0, Right: 40, Bottom: 30})
+		subframe, err := driver.MeasureDownloadTime(1)
+		require.Nil(t, err)
+		require.InDelta(t, 0.5, fullFrame, 0.2)
+		require.Less(t, subframe, fullFrame/4.0)
+
+		taken := fake.TakenImages()
+		require.False(t, taken[0].Subframe)
+		require.True(t, taken[1].Subframe)
+	})
+
+	t.Run("invalid subframes refused", func(t *testing.T) {
+		service, mockDriver, _ := setUpConnectedMockService(ctrl)
+		_, err := service.MeasureSubframeDownloadTime(1, Subframe{Left: 10, Top: 10, Right: 5, Bottom: 20})
+		require.ErrorIs(t, err, ErrInvalidSubframe)
+
+		mockDriver.EXPECT().GetCameraCapabilities().Return(CameraCapabilities{WidthPixels: 400, HeightPixels: 300}, nil)
+		_, err = service.CameraCapabilities()
+		require.Nil(t, err)
+		_, err = service.FindFlatExposure(FlatExposureSearch{
+			FilterSlot: 1, Binning: 2, TargetADU: 10000, Tolerance: 0.05, MinExposure: 0.1, MaxExposure: 30.0,
+			Subframe: Subframe{Left: 150, Top: 100, Right: 250, Bottom: 140},
+		})
+		require.ErrorIs(t, err, ErrInvalidSubframe, "Subframe is outside the 200x150 binned image")
+	})
+
+	t.Run("subframe checked against capabilities fetched from camera", func(t *testing.T) {
+		service, mockDriver, _ := setUpConnectedMockService(ctrl)
+		mockDriver.EXPECT().GetCameraCapabilities().Return(CameraCapabilities{WidthPixels: 400, HeightPixels: 300}, nil)
+		_, err := service.MeasureSubframeDownloadTime(2, Subframe{Left: 150, Top: 100, Right: 250, Bottom: 140})
+		require.ErrorIs(t, err, ErrInvalidSubframe, "Subframe is outside the 200x150 binned image")
+		_, err = service.MeasureSubframeDownloadTime(1, Subframe{Left: 350, Top: 250, Right: 450, Bottom: 290})
+		require.ErrorIs(t, err, ErrInvalidSubframe, "Capabilities should be remembered, not fetched again")
+	})
+
+	t.Run("subframe download measured and cleared through service", func(t *testing.T) {
+		service, mockDriver, _ := setUpConnectedMockService(ctrl)
+		subframe := Subframe{Left: 10, Top: 10, Right: 50, Bottom: 40}
+		mockDriver.EXPECT().GetCameraCapabilities().Return(CameraCapabilities{WidthPixels: 400, HeightPixels: 300}, nil)
+		gomock.InOrder(
+			mockDriver.EXPECT().SetSubframe(subframe),
+			mockDriver.EXPECT().MeasureDownloadTimeContext(gomock.Any(), 1).Return(0.3, nil),
+			mockDriver.EXPECT().SetSubframe(Subframe{}),
+		)
+		downloadTime, err := service.MeasureSubframeDownloadTime(1, subframe)
+		require.Nil(t, err)
+		require.Equal(t, 0.3, downloadTime)
+	})
+}
@@ -0,0 +1,224 @@
+package goTheSkyX
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+//	Twilight sky flats.  At dusk and dawn the sky's brightness changes roughly exponentially
+//	with time, so a fixed exposure soon gives flats that are too dark or too bright.  Instead we
+//	measure the sky's brightness (ADU per second of exposure, less the bias level) from each
+//	flat, fit an exponential to the latest measurements, and predict the exposure the next
+//	flat needs.
+//
+//	Filter order matters: at dusk the sky is brightest at the start, so we take the narrowest
+//	filters (which need the most light) first; at dawn it's the other way round.
+
+// TwilightDirection says whether the sky is getting darker (Dusk) or brighter (Dawn)
+type TwilightDirection int
+
+const (
+	Dusk TwilightDirection = iota
+	Dawn
+)
+
+func (direction TwilightDirection) String() string {
+	if direction == Dawn {
+		return "dawn"
+	}
+	return "dusk"
+}
+
+// TwilightFilter is a filter to take sky flats through, with its bandwidth so the filters can
+// be taken in the right order
+type TwilightFilter struct {
+	Slot        int     // One-based
+	BandwidthNm float64 // Pass band width, in nanometres
+}
+
+// TwilightFlatSettings describes a twilight flat session
+type TwilightFlatSettings struct {
+	Direction    TwilightDirection
+	Filters      []TwilightFilter
+	Binning      int
+	Count        int // Number of good flats wanted per filter
+	TargetADU    int64
+	Tolerance    float64 // Fraction of TargetADU a flat may be off by
+	MinExposure  float64 // Stop a filter when the sky needs shorter exposures than this
+	MaxExposure  float64 // Stop a filter when the sky needs longer exposures than this
+	BiasADU      float64 // Camera's bias level, subtracted when measuring sky brightness
+	MaxRejected  int     // Give up on a filter after this many rejected flats; 0 for the same as Count
+	DownloadTime float64
+}
+
+// ErrTwilightExposureLimit is recorded (wrapped) against a filter when the sky has become too
+// dark (dusk) or too bright (dawn) to continue within the exposure limits
+var ErrTwilightExposureLimit = errors.New("sky brightness out of range for exposure limits")
+
+// skyBrightnessSample is the sky's measured brightness at the middle of one exposure
+type skyBrightnessSample struct {
+	at           time.Time
+	aduPerSecond float64
+}
+
+// CaptureTwilightFlats captures sky flats through each filter, tracking the changing sky.
+// Filters are taken narrowest first at dusk and broadest first at dawn.  For each filter the
+// starting exposure is found with FindFlatExposure; after that each exposure is predicted from
+// the trend of the previous flats.  A filter stops when it has Count good flats, when the
+// predicted exposure goes outside the limits (ErrTwilightExposureLimit), or when too many
+// flats are out of tolerance; rejected flats have been saved, and their paths are recorded in
+// the result's Rejected.  Results are in the order the filters were taken.
+func (service *TheSkyServiceInstance) CaptureTwilightFlats(settings TwilightFlatSettings) ([]FlatSetResult, error) {
+	return service.CaptureTwilightFlatsContext(context.Background(), settings)
+}
+
+func (service *TheSkyServiceInstance) CaptureTwilightFlatsContext(ctx context.Context, settings TwilightFlatSettings) ([]FlatSetResult, error) {
+	if service.verbosity >= 4 || service.debug {
+		fmt.Printf("TheSkyServiceInstance/CaptureTwilightFlats(%s, %d filters, %d each)\n",
+			settings.Direction, len(settings.Filters), settings.Count)
+	}
+	if !service.isOpen {
+		return nil, errors.New("TheSkyServiceInstance/CaptureTwilightFlats: Connection not open")
+	}
+	if settings.Count < 1 {
+		return nil, errors.New("TheSkyServiceInstance/CaptureTwilightFlats: count must be at least 1")
+	}
+	if settings.MinExposure <= 0 || settings.MaxExposure < settings.MinExposure {
+		return nil, errors.New("TheSkyServiceInstance/CaptureTwilightFlats: need 0 < minimum exposure <= maximum exposure")
+	}
+	filterNames, err := service.FilterNamesContext(ctx)
+	if err != nil {
+		fmt.Println("TheSkyServiceInstance/CaptureTwilightFlats error from FilterNames:", err)
+		return nil, err
+	}
+	for _, filter := range settings.Filters {
+		if filter.Slot < 1 || filter.Slot > len(filterNames) {
+			return nil, fmt.Errorf("TheSkyServiceInstance/CaptureTwilightFlats: no filter in slot %d", filter.Slot)
+		}
+	}
+
+	var results []FlatSetResult
+	for _, filter := range twilightFilterOrder(settings.Filters, settings.Direction) {
+		result := service.captureTwilightFlatSet(ctx, settings, filter.Slot, filterNames[filter.Slot-1])
+		results = append(results, result)
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// twilightFilterOrder sorts the filters narrowest first for dusk, broadest first for dawn
+func twilightFilterOrder(filters []TwilightFilter, direction TwilightDirection) []TwilightFilter {
+	ordered := append([]TwilightFilter(nil), filters...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if direction == Dawn {
+			return ordered[i].BandwidthNm > ordered[j].BandwidthNm
+		}
+		return ordered[i].BandwidthNm < ordered[j].BandwidthNm
+	})
+	return ordered
+}
+
+// captureTwilightFlatSet captures the flats for one filter
+func (service *TheSkyServiceInstance) captureTwilightFlatSet(ctx context.Context, settings TwilightFlatSettings, slot int, filterName string) FlatSetResult {
+	result := FlatSetResult{FilterSlot: slot, FilterName: filterName}
+	search := FlatExposureSearch{
+		FilterSlot:   slot,
+		Binning:      settings.Binning,
+		TargetADU:    settings.TargetADU,
+		Tolerance:    settings.Tolerance,
+		MinExposure:  settings.MinExposure,
+		MaxExposure:  settings.MaxExposure,
+		DownloadTime: settings.DownloadTime,
+	}
+	result.Search, result.Err = service.FindFlatExposureContext(ctx, search)
+	if result.Err != nil {
+		return result
+	}
+	result.Exposure = result.Search.Exposure
+
+	maxRejected := valueOrDefault(settings.MaxRejected, settings.Count)
+	lowADU := float64(settings.TargetADU) * (1.0 - settings.Tolerance)
+	highADU := float64(settings.TargetADU) * (1.0 + settings.Tolerance)
+	var samples []skyBrightnessSample
+	for len(result.ADUs) < settings.Count {
+		startTime := service.clock()
+		captured, err := service.CaptureAndMeasureFlatFrameResultContext(ctx, result.Exposure, settings.Binning, slot, settings.DownloadTime, true)
+		if err != nil {
+			fmt.Println("TheSkyServiceInstance/CaptureTwilightFlats error from CaptureAndMeasureFlatFrame:", err)
+			result.Err = err
+			break
+		}
+		adu := captured.ADU
+		midExposure := startTime.Add(time.Duration(result.Exposure / 2.0 * float64(time.Second)))
+		samples = append(samples, skyBrightnessSample{
+			at:           midExposure,
+			aduPerSecond: (float64(adu) - settings.BiasADU) / result.Exposure,
+		})
+
+		if float64(adu) >= lowADU && float64(adu) <= highADU {
+			result.Exposures = append(result.Exposures, result.Exposure)
+			result.ADUs = append(result.ADUs, adu)
+		} else {
+			if service.verbosity >= 3 {
+				fmt.Printf("Twilight flat in %s at %g seconds was %d ADU, out of tolerance\n", filterName, result.Exposure, adu)
+			}
+			result.Rejected = append(result.Rejected, FlatMeasurement{Exposure: result.Exposure, ADU: adu, Path: captured.Path})
+			if len(result.Rejected) >= maxRejected {
+				result.Err = fmt.Errorf("%w: %d rejected in filter %s", ErrTooManyRejectedFlats, len(result.Rejected), filterName)
+				break
+			}
+		}
+		if len(result.ADUs) >= settings.Count {
+			break
+		}
+
+		nextExposure := predictTwilightExposure(samples, settings, service.clock())
+		if nextExposure > settings.MaxExposure || nextExposure < settings.MinExposure {
+			result.Err = fmt.Errorf("%w: %s needs %.3g second exposures (limits %g to %g)",
+				ErrTwilightExposureLimit, filterName, nextExposure, settings.MinExposure, settings.MaxExposure)
+			break
+		}
+		result.Exposure = math.Round(nextExposure*1000.0) / 1000.0
+	}
+	result.MeanADU, result.StdDevADU = meanAndStdDev(result.ADUs)
+	return result
+}
+
+// predictTwilightExposure predicts the exposure that will give the target ADU if started now.
+// From the last two brightness samples we get the sky's rate of change, which we only allow in
+// the expected direction (so noise can't make us think the sky is brightening at dusk).  Since
+// the exposure's midpoint depends on the exposure itself, we refine the estimate a few times.
+func predictTwilightExposure(samples []skyBrightnessSample, settings TwilightFlatSettings, now time.Time) float64 {
+	latest := samples[len(samples)-1]
+	rate := 0.0 // Exponential rate of change of brightness, per second
+	if len(samples) >= 2 {
+		previous := samples[len(samples)-2]
+		interval := latest.at.Sub(previous.at).Seconds()
+		if interval > 0 && previous.aduPerSecond > 0 && latest.aduPerSecond > 0 {
+			rate = math.Log(latest.aduPerSecond/previous.aduPerSecond) / interval
+		}
+	}
+	if settings.Direction == Dusk {
+		rate = math.Min(rate, 0.0)
+	} else {
+		rate = math.Max(rate, 0.0)
+	}
+	if latest.aduPerSecond <= 0 {
+		return math.Inf(1)
+	}
+
+	wantedADU := float64(settings.TargetADU) - settings.BiasADU
+	exposure := wantedADU / latest.aduPerSecond
+	for i := 0; i < 3; i++ {
+		midExposure := now.Add(time.Duration(exposure / 2.0 * float64(time.Second)))
+		predictedBrightness := latest.aduPerSecond * math.Exp(rate*midExposure.Sub(latest.at).Seconds())
+		exposure = wantedADU / predictedBrightness
+	}
+	return exposure
+}
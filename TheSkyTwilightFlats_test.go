@@ -0,0 +1,118 @@
+package goTheSkyX
+
+import (
+	"github.com/RMcDOttawa/goMockableDelay"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+	"time"
+)
+
+// setUpTwilightSky gives the service a simulated clock, advanced by the mock delay service, and
+// a simulated sky whose brightness changes exponentially with the given time constant (negative
+// for dusk).  Flat frames come from the service's own flat simulation.
+func setUpTwilightSky(service TheSkyService, mockDriver *MockTheSkyDriver, mockDelayService *goMockableDelay.MockDelayService, timeConstantSeconds float64) {
+	start := time.Date(2024, time.March, 1, 18, 30, 0, 0, time.UTC)
+	now := start
+	service.(*TheSkyServiceInstance).clock = func() time.Time { return now }
+	mockDelayService.EXPECT().DelayDuration(gomock.Any()).DoAndReturn(func(seconds int) (int, error) {
+		now = now.Add(time.Duration(seconds) * time.Second)
+		return seconds, nil
+	}).AnyTimes()
+	model := NewDefaultFlatSimulationModel(42)
+	model.NoiseFraction = 0.01
+	service.SetFlatSimulationModel(model)
+	service.SetSimulatedSkyBrightness(func(at time.Time) float64 {
+		return math.Exp(at.Sub(start).Seconds() / timeConstantSeconds)
+	})
+	mockDriver.EXPECT().FilterNames().Return([]string{"Red", "Green", "Blue", "Lum", "Ha"}, nil)
+	mockDriver.EXPECT().StartFlatFrameCapture(2, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	mockDriver.EXPECT().IsCaptureDone().Return(true, nil).AnyTimes()
+	mockDriver.EXPECT().GetADUValue().Return(int64(0), nil).AnyTimes()
+	mockDriver.EXPECT().GetCapturedImageInfo().Return(CaptureResult{Path: "/flats/twilight.fit"}, nil).AnyTimes()
+}
+
+func TestCaptureTwilightFlats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	filters := []TwilightFilter{{Slot: 1, BandwidthNm: 100}, {Slot: 2, BandwidthNm: 90}, {Slot: 3, BandwidthNm: 110}}
+
+	t.Run("tracks darkening sky at dusk", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		setUpTwilightSky(service, mockDriver, mockDelayService, -900.0)
+
+		results, err := service.CaptureTwilightFlats(TwilightFlatSettings{
+			Direction: Dusk, Filters: filters, Binning: 2, Count: 8,
+			TargetADU: 25000, Tolerance: 0.05, MinExposure: 0.5, MaxExposure: 60.0,
+		})
+		require.Nil(t, err, "CaptureTwilightFlats failed")
+		require.Equal(t, []int{2, 1, 3}, []int{results[0].FilterSlot, results[1].FilterSlot, results[2].FilterSlot},
+			"Dusk should take narrowest filter first")
+		for _, result := range results {
+			require.Nil(t, result.Err, "Filter %s failed", result.FilterName)
+			require.Len(t, result.ADUs, 8)
+			require.InDelta(t, 25000.0, result.MeanADU, 1250.0)
+			require.Greater(t, result.Exposures[7], result.Exposures[0], "Exposures should lengthen as the sky darkens")
+		}
+	})
+
+	t.Run("tracks brightening sky at dawn", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		setUpTwilightSky(service, mockDriver, mockDelayService, 900.0)
+
+		results, err := service.CaptureTwilightFlats(TwilightFlatSettings{
+			Direction: Dawn, Filters: filters, Binning: 2, Count: 6,
+			TargetADU: 25000, Tolerance: 0.05, MinExposure: 0.1, MaxExposure: 60.0,
+		})
+		require.Nil(t, err, "CaptureTwilightFlats failed")
+		require.Equal(t, []int{3, 1, 2}, []int{results[0].FilterSlot, results[1].FilterSlot, results[2].FilterSlot},
+			"Dawn should take broadest filter first")
+		for _, result := range results {
+			require.Nil(t, result.Err, "Filter %s failed", result.FilterName)
+			require.Len(t, result.ADUs, 6)
+			require.Less(t, result.Exposures[5], result.Exposures[0], "Exposures should shorten as the sky brightens")
+		}
+	})
+
+	t.Run("stops when sky gets too dark", func(t *testing.T) {
+		service, mockDriver, mockDelayService := setUpConnectedMockService(ctrl)
+		setUpTwilightSky(service, mockDriver, mockDelayService, -120.0)
+
+		results, err := service.CaptureTwilightFlats(TwilightFlatSettings{
+			Direction: Dusk, Filters: filters[:1], Binning: 2, Count: 50,
+			TargetADU: 25000, Tolerance: 0.05, MinExposure: 0.5, MaxExposure: 10.0,
+		})
+		require.Nil(t, err, "Filter stopping should not fail the run")
+		result := results[0]
+		require.ErrorIs(t, result.Err, ErrTwilightExposureLimit)
+		require.NotEmpty(t, result.ADUs, "Expected some flats before the sky got too dark")
+		require.Less(t, len(result.ADUs), 50)
+		for _, exposure := range result.Exposures {
+			require.LessOrEqual(t, exposure, 10.0)
+		}
+		for _, rejected := range result.Rejected {
+			require.Equal(t, "/flats/twilight.fit", rejected.Path, "Rejected flat's path should be recorded")
+		}
+	})
+
+	t.Run("prediction follows exponential trend", func(t *testing.T) {
+		start := time.Date(2024, time.March, 1, 18, 30, 0, 0, time.UTC)
+		samples := []skyBrightnessSample{
+			{at: start, aduPerSecond: 10000.0},
+			{at: start.Add(60 * time.Second), aduPerSecond: 5000.0},
+		}
+		settings := TwilightFlatSettings{Direction: Dusk, TargetADU: 20000}
+		// Brightness halves every minute.  Starting now, an exposure of e has its midpoint
+		// e/2 seconds from now, when the brightness is 5000 * 2^-(e/2)/60
+		exposure := predictTwilightExposure(samples, settings, start.Add(60*time.Second))
+		midBrightness := 5000.0 * math.Pow(2.0, -exposure/2.0/60.0)
+		require.InDelta(t, 20000.0, exposure*midBrightness, 200.0)
+
+		// Noise suggesting the sky is brightening at dusk is ignored
+		samples[1].aduPerSecond = 11000.0
+		exposure = predictTwilightExposure(samples, settings, start.Add(60*time.Second))
+		require.InDelta(t, 20000.0/11000.0, exposure, 0.001)
+	})
+}
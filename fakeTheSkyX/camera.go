@@ -0,0 +1,536 @@
+package fakeTheSkyX
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+//	Simulated TheSkyX objects.  Each implements scriptObject so the interpreter can read and
+//	write its properties and call its methods.  All state lives in a single simulatedCamera,
+//	shared by ccdsoftCamera and ccdsoftCameraImage, and is protected by the server's mutex
+//	(only one script runs at a time, as with the real TheSkyX).
+
+// Frame types, as used in ccdsoftCamera.Frame
+const (
+	frameLight = 1
+	frameBias  = 2
+	frameDark  = 3
+	frameFlat  = 4
+)
+
+// Error codes reported in the "Error = N." part of a reply, from TheSkyX's sberrorx.h so that
+// clients see realistic values.  Script errors, and requests the simulated devices can't carry
+// out (bias frames, too high a binning, cooling), are reported as ERR_CMDFAILED; the real
+// camera's driver would report its own code.
+const (
+	errorNone              = 0
+	errorCommandFailed     = 206  // ERR_CMDFAILED
+	errorNoLink            = 215  // ERR_NOLINK
+	errorDeviceParked      = 216  // ERR_DEVICEPARKED
+	errorCommandInProgress = 219  // ERR_COMMANDINPROGRESS
+	errorCannotColorGrab   = 1166 // ERR_CANNOT_COLORGRAB, i.e. no filter wheel
+
+	errorSyntax        = errorCommandFailed
+	errorReference     = errorCommandFailed
+	errorUnknownMember = errorCommandFailed
+	errorNoImage       = errorCommandFailed // No active image to attach to
+	errorNotSupported  = errorCommandFailed
+)
+
+// ErrorCodes returns every error code the fake server can report
+func ErrorCodes() []int {
+	return []int{errorCommandFailed, errorNoLink, errorDeviceParked, errorCommandInProgress, errorCannotColorGrab}
+}
+
+// capturedImage describes the most recent image taken, which ccdsoftCameraImage can attach to
+type capturedImage struct {
+	frame        int
+	binning      int
+	exposure     float64
+	filterIndex  int
+	saved        bool
+	averageValue float64
+	keywords     map[string]string // Added with ccdsoftCameraImage.setFITSKeyword
+	path         string            // Set with ccdsoftCameraImage.Path
+	left         int               // Position and size on the binned sensor, less than all of it if subframed
+	top          int
+	width        int
+	height       int
+}
+
+// TakenImage records an exposure started with ccdsoftCamera.TakeImage
+type TakenImage struct {
+	Frame    int // As in ccdsoftCamera.Frame: 1 light, 2 bias, 3 dark, 4 flat
+	Binning  int
+	Exposure float64
+	Subframe bool
+	Width    int // Of the image read out, in binned pixels
+	Height   int
+}
+
+// SavedImage records an image saved with ccdsoftCameraImage.Save
+type SavedImage struct {
+	Path     string
+	Frame    int // As in ccdsoftCamera.Frame: 1 light, 2 bias, 3 dark, 4 flat
+	Binning  int
+	Exposure float64
+	Keywords map[string]string
+}
+
+type simulatedCamera struct {
+	server *FakeTheSkyServer
+
+	connected            bool
+	filterWheelConnected bool
+	properties           map[string]scriptValue
+
+	temperature         float64
+	temperatureTime     time.Time
+	setPoint            float64
+	regulating          bool
+	filterMoveDoneAt    time.Time
+	exposureInProgress  bool
+	exposureCompleteAt  time.Time
+	pendingImage        capturedImage
+	activeImage         *capturedImage
+	attachedImage       *capturedImage
+	savedImageCount     int
+	savedImages         []SavedImage
+	takenImages         []TakenImage
+	universalTimeResult float64
+}
+
+// writableCameraProperties lists the ccdsoftCamera properties a script may assign, with defaults
+var writableCameraProperties = map[string]scriptValue{
+	"Autoguider":           false,
+	"Asynchronous":         false,
+	"Frame":                float64(frameLight),
+	"ImageReduction":       0.0,
+	"ToNewWindow":          true,
+	"ccdsoftAutoSaveAs":    0.0,
+	"AutoSaveOn":           false,
+	"AutoSavePath":         "C:/Users/Observer/Documents/Software Bisque/TheSkyX Professional Edition/Camera AutoSave",
+	"BinX":                 1.0,
+	"BinY":                 1.0,
+	"ExposureTime":         1.0,
+	"FilterIndexZeroBased": 0.0,
+	"Subframe":             false,
+	"SubframeLeft":         0.0,
+	"SubframeTop":          0.0,
+	"SubframeRight":        0.0,
+	"SubframeBottom":       0.0,
+	"ShutDownTemperatureRegulationOnDisconnect": true,
+}
+
+func newSimulatedCamera(server *FakeTheSkyServer) *simulatedCamera {
+	camera := &simulatedCamera{
+		server:     server,
+		properties: make(map[string]scriptValue),
+	}
+	for name, value := range writableCameraProperties {
+		camera.properties[name] = value
+	}
+	camera.temperature = server.ambientTemperature
+	camera.temperatureTime = server.now()
+	camera.setPoint = server.ambientTemperature
+	return camera
+}
+
+// updateTemperature moves the sensor temperature toward the set point (if regulating) or
+// toward ambient (if not) at the configured rate, for the time elapsed since the last update
+func (camera *simulatedCamera) updateTemperature() {
+	now := camera.server.now()
+	elapsed := now.Sub(camera.temperatureTime).Seconds()
+	camera.temperatureTime = now
+	target := camera.server.ambientTemperature
+	if camera.regulating {
+		target = math.Max(camera.setPoint, camera.coldestReachable())
+	}
+	maxChange := camera.server.coolingRate * elapsed
+	difference := target - camera.temperature
+	if math.Abs(difference) <= maxChange {
+		camera.temperature = target
+	} else {
+		camera.temperature += math.Copysign(maxChange, difference)
+	}
+}
+
+func (camera *simulatedCamera) coldestReachable() float64 {
+	return camera.server.ambientTemperature - camera.server.maxCoolingDelta
+}
+
+// coolerPower is the percentage power the TEC is using: full power while pulling the
+// temperature down, otherwise in proportion to how far below ambient it is holding the sensor
+func (camera *simulatedCamera) coolerPower() float64 {
+	if !camera.regulating {
+		return 0.0
+	}
+	if camera.temperature > camera.setPoint+0.05 {
+		return 100.0
+	}
+	power := (camera.server.ambientTemperature - camera.temperature) / camera.server.maxCoolingDelta * 100.0
+	return math.Round(math.Min(math.Max(power, 0.0), 100.0))
+}
+
+// updateExposure completes an asynchronous exposure if its time has passed
+func (camera *simulatedCamera) updateExposure() {
+	if camera.exposureInProgress && !camera.server.now().Before(camera.exposureCompleteAt) {
+		camera.finishExposure()
+	}
+}
+
+// frameNames are used in AutoSave file names, as TheSkyX does
+var frameNames = map[int]string{frameLight: "Light", frameBias: "Bias", frameDark: "Dark", frameFlat: "Flat"}
+
+func (camera *simulatedCamera) finishExposure() {
+	camera.exposureInProgress = false
+	image := camera.pendingImage
+	camera.activeImage = &image
+	if image.saved {
+		camera.savedImageCount++
+		image.path = fmt.Sprintf("%s/Image.%08d.%s.fit",
+			formatValue(camera.properties["AutoSavePath"]), camera.savedImageCount, frameNames[image.frame])
+	}
+}
+
+func (camera *simulatedCamera) getProperty(name string) (scriptValue, error) {
+	switch name {
+	case "Temperature":
+		if !camera.connected {
+			return nil, newScriptError(errorNoLink, "TypeError: Camera is not connected.")
+		}
+		camera.updateTemperature()
+		return math.Round(camera.temperature*100.0) / 100.0, nil
+	case "ThermoElectricCoolerPower":
+		if !camera.connected {
+			return nil, newScriptError(errorNoLink, "TypeError: Camera is not connected.")
+		}
+		camera.updateTemperature()
+		return camera.coolerPower(), nil
+	case "TemperatureSetPoint":
+		return camera.setPoint, nil
+	case "RegulateTemperature":
+		return camera.regulating, nil
+	case "IsExposureComplete":
+		if !camera.connected {
+			return nil, newScriptError(errorNoLink, "TypeError: Camera is not connected.")
+		}
+		camera.updateExposure()
+		if camera.exposureInProgress {
+			return 0.0, nil
+		}
+		return 1.0, nil
+	case "WidthInPixels":
+		return float64(camera.server.sensorWidth), nil
+	case "HeightInPixels":
+		return float64(camera.server.sensorHeight), nil
+	case "PixelSize1x1":
+		// Not every camera driver reports it
+		if camera.server.pixelSize <= 0 {
+			return nil, newScriptError(errorCommandFailed, "TypeError: Camera does not report its pixel size.")
+		}
+		return camera.server.pixelSize, nil
+	case "lNumberFilters":
+		// Names come from TheSkyX's filter wheel setup, so are available even when not connected
+		if !camera.server.hasFilterWheel {
+			return 0.0, nil
+		}
+		return float64(len(camera.server.filterNames)), nil
+	case "FilterIndexZeroBased":
+		// A wheel still moving reports -1 until it arrives
+		if camera.server.now().Before(camera.filterMoveDoneAt) {
+			return -1.0, nil
+		}
+	}
+	if value, exists := camera.properties[name]; exists {
+		return value, nil
+	}
+	return nil, newScriptError(errorUnknownMember, "TypeError: ccdsoftCamera has no property %s", name)
+}
+
+func (camera *simulatedCamera) setProperty(name string, value scriptValue) error {
+	switch name {
+	case "TemperatureSetPoint":
+		camera.updateTemperature()
+		camera.setPoint = toNumber(value)
+		return nil
+	case "RegulateTemperature":
+		if toBool(value) && !camera.server.hasCooler {
+			return newScriptError(errorNotSupported, "TypeError: Camera has no temperature control.")
+		}
+		camera.updateTemperature()
+		camera.regulating = toBool(value)
+		return nil
+	case "FilterIndexZeroBased":
+		if !camera.filterWheelConnected {
+			return newScriptError(errorNoLink, "TypeError: Filter wheel is not connected.")
+		}
+		slotsToMove := math.Abs(toNumber(value) - toNumber(camera.properties[name]))
+		duration := time.Duration(slotsToMove * camera.server.filterMoveTime * float64(time.Second))
+		camera.filterMoveDoneAt = camera.server.now().Add(duration)
+	}
+	if _, exists := writableCameraProperties[name]; !exists {
+		return newScriptError(errorUnknownMember, "TypeError: ccdsoftCamera has no writable property %s", name)
+	}
+	camera.properties[name] = value
+	return nil
+}
+
+func (camera *simulatedCamera) callMethod(name string, args []scriptValue) (scriptValue, error) {
+	switch name {
+	case "Connect":
+		camera.connected = true
+		camera.updateTemperature()
+		return 0.0, nil
+	case "Disconnect":
+		camera.connected = false
+		return 0.0, nil
+	case "TakeImage":
+		return camera.takeImage()
+	case "Abort":
+		if camera.exposureInProgress {
+			camera.exposureInProgress = false
+		}
+		return 0.0, nil
+	case "filterWheelConnect":
+		if !camera.server.hasFilterWheel {
+			return nil, newScriptError(errorCannotColorGrab, "TypeError: Error, no filter wheel.")
+		}
+		camera.filterWheelConnected = true
+		return 0.0, nil
+	case "filterWheelDisconnect":
+		camera.filterWheelConnected = false
+		return 0.0, nil
+	case "filterWheelIsConnected":
+		if camera.filterWheelConnected {
+			return 1.0, nil
+		}
+		return 0.0, nil
+	case "szFilterName":
+		if len(args) != 1 {
+			return nil, newScriptError(errorSyntax, "TypeError: szFilterName expects one argument")
+		}
+		index := int(toNumber(args[0]))
+		if !camera.server.hasFilterWheel || index < 0 || index >= len(camera.server.filterNames) {
+			return "", nil
+		}
+		return camera.server.filterNames[index], nil
+	}
+	return nil, newScriptError(errorUnknownMember, "TypeError: ccdsoftCamera has no method %s", name)
+}
+
+// takeImage starts an exposure using the current property settings.  Synchronous exposures
+// block (holding the server lock, as TheSkyX does) until the exposure and download are over.
+func (camera *simulatedCamera) takeImage() (scriptValue, error) {
+	if !camera.connected {
+		return nil, newScriptError(errorNoLink, "TypeError: Camera is not connected.")
+	}
+	camera.updateExposure()
+	if camera.exposureInProgress {
+		return nil, newScriptError(errorCommandInProgress, "TypeError: Camera is busy with another exposure.")
+	}
+	frame := int(toNumber(camera.properties["Frame"]))
+	binning := int(toNumber(camera.properties["BinX"]))
+	if binning < 1 {
+		binning = 1
+	}
+	if binning > camera.server.maxBinning {
+		return nil, newScriptError(errorNotSupported, "TypeError: Camera does not support binning %dx%d.", binning, binning)
+	}
+	if frame == frameBias && !camera.server.supportsBias {
+		return nil, newScriptError(errorNotSupported, "TypeError: Camera does not support bias frames.")
+	}
+	exposure := toNumber(camera.properties["ExposureTime"])
+	if frame == frameBias {
+		exposure = 0.0
+	}
+	filterIndex := int(toNumber(camera.properties["FilterIndexZeroBased"]))
+	fullWidth := camera.server.sensorWidth / binning
+	fullHeight := camera.server.sensorHeight / binning
+	left, top, right, bottom := 0, 0, fullWidth, fullHeight
+	subframe := toBool(camera.properties["Subframe"])
+	if subframe {
+		left = int(toNumber(camera.properties["SubframeLeft"]))
+		top = int(toNumber(camera.properties["SubframeTop"]))
+		right = int(toNumber(camera.properties["SubframeRight"]))
+		bottom = int(toNumber(camera.properties["SubframeBottom"]))
+		if left < 0 || top < 0 || right <= left || bottom <= top || right > fullWidth || bottom > fullHeight {
+			return nil, newScriptError(errorNotSupported, "TypeError: Subframe %d,%d-%d,%d is outside the %dx%d image.",
+				left, top, right, bottom, fullWidth, fullHeight)
+		}
+	}
+	camera.takenImages = append(camera.takenImages, TakenImage{
+		Frame: frame, Binning: binning, Exposure: exposure, Subframe: subframe, Width: right - left, Height: bottom - top,
+	})
+	camera.updateTemperature()
+	camera.pendingImage = capturedImage{
+		frame:        frame,
+		binning:      binning,
+		exposure:     exposure,
+		filterIndex:  filterIndex,
+		saved:        toBool(camera.properties["AutoSaveOn"]),
+		averageValue: camera.server.simulatedAverageValue(frame, exposure),
+		keywords: map[string]string{
+			"DATE-OBS": camera.server.now().UTC().Format("2006-01-02T15:04:05.000"),
+			"CCD-TEMP": strconv.FormatFloat(math.Round(camera.temperature*100.0)/100.0, 'f', -1, 64),
+		},
+		left:   left,
+		top:    top,
+		width:  right - left,
+		height: bottom - top,
+	}
+	// Download time is in proportion to the number of pixels read out
+	downloadTime := camera.server.downloadTime / float64(binning*binning) *
+		float64((right-left)*(bottom-top)) / float64(fullWidth*fullHeight)
+	duration := time.Duration((exposure + downloadTime) * float64(time.Second))
+	camera.exposureInProgress = true
+	camera.exposureCompleteAt = camera.server.now().Add(duration)
+	if !toBool(camera.properties["Asynchronous"]) {
+		time.Sleep(duration)
+		camera.finishExposure()
+	}
+	return 0.0, nil
+}
+
+// simulatedImage is ccdsoftCameraImage: it attaches to the camera's active image and measures it
+type simulatedImage struct {
+	camera *simulatedCamera
+}
+
+func (image *simulatedImage) getProperty(name string) (scriptValue, error) {
+	attached := image.camera.attachedImage
+	if attached != nil {
+		switch name {
+		case "Path":
+			return attached.path, nil
+		case "WidthInPixels":
+			return float64(attached.width), nil
+		case "HeightInPixels":
+			return float64(attached.height), nil
+		}
+	}
+	return nil, newScriptError(errorUnknownMember, "TypeError: ccdsoftCameraImage has no property %s", name)
+}
+
+func (image *simulatedImage) setProperty(name string, value scriptValue) error {
+	if name == "Path" {
+		if image.camera.attachedImage == nil {
+			return newScriptError(errorNoImage, "TypeError: No image attached.")
+		}
+		image.camera.attachedImage.path = formatValue(value)
+		return nil
+	}
+	return newScriptError(errorUnknownMember, "TypeError: ccdsoftCameraImage has no writable property %s", name)
+}
+
+func (image *simulatedImage) callMethod(name string, args []scriptValue) (scriptValue, error) {
+	switch name {
+	case "AttachToActive":
+		image.camera.updateExposure()
+		if image.camera.activeImage == nil {
+			return nil, newScriptError(errorNoImage, "TypeError: No active image.")
+		}
+		image.camera.attachedImage = image.camera.activeImage
+		return 0.0, nil
+	case "averagePixelValue":
+		if image.camera.attachedImage == nil {
+			return nil, newScriptError(errorNoImage, "TypeError: No image attached.")
+		}
+		return image.camera.attachedImage.averageValue, nil
+	case "scanLine":
+		attached := image.camera.attachedImage
+		if attached == nil {
+			return nil, newScriptError(errorNoImage, "TypeError: No image attached.")
+		}
+		if len(args) != 1 {
+			return nil, newScriptError(errorSyntax, "TypeError: scanLine expects one argument")
+		}
+		y := int(toNumber(args[0]))
+		if y < 0 || y >= attached.height {
+			return nil, newScriptError(errorSyntax, "RangeError: scanLine row %d outside image.", y)
+		}
+		// Pixels are simulated where they are on the full sensor, so a subframe is a true crop
+		fullWidth := image.camera.server.sensorWidth / attached.binning
+		fullHeight := image.camera.server.sensorHeight / attached.binning
+		row := &scriptArray{elements: make([]scriptValue, attached.width)}
+		for x := 0; x < attached.width; x++ {
+			row.elements[x] = image.camera.server.simulatedPixelValue(attached.averageValue,
+				attached.left+x, attached.top+y, fullWidth, fullHeight)
+		}
+		return row, nil
+	case "FITSKeyword":
+		if image.camera.attachedImage == nil {
+			return nil, newScriptError(errorNoImage, "TypeError: No image attached.")
+		}
+		if len(args) != 1 {
+			return nil, newScriptError(errorSyntax, "TypeError: FITSKeyword expects one argument")
+		}
+		value, exists := image.camera.attachedImage.keywords[formatValue(args[0])]
+		if !exists {
+			return nil, newScriptError(errorUnknownMember, "TypeError: Image has no FITS keyword %s.", formatValue(args[0]))
+		}
+		return value, nil
+	case "setFITSKeyword":
+		if image.camera.attachedImage == nil {
+			return nil, newScriptError(errorNoImage, "TypeError: No image attached.")
+		}
+		if len(args) != 2 {
+			return nil, newScriptError(errorSyntax, "TypeError: setFITSKeyword expects two arguments")
+		}
+		attached := image.camera.attachedImage
+		if attached.keywords == nil {
+			attached.keywords = make(map[string]string)
+		}
+		attached.keywords[formatValue(args[0])] = formatValue(args[1])
+		return 0.0, nil
+	case "Save":
+		attached := image.camera.attachedImage
+		if attached == nil {
+			return nil, newScriptError(errorNoImage, "TypeError: No image attached.")
+		}
+		if attached.path == "" {
+			return nil, newScriptError(errorSyntax, "TypeError: Image has no path.")
+		}
+		keywords := make(map[string]string)
+		for keyword, value := range attached.keywords {
+			keywords[keyword] = value
+		}
+		image.camera.savedImages = append(image.camera.savedImages, SavedImage{
+			Path:     attached.path,
+			Frame:    attached.frame,
+			Binning:  attached.binning,
+			Exposure: attached.exposure,
+			Keywords: keywords,
+		})
+		return 0.0, nil
+	}
+	return nil, newScriptError(errorUnknownMember, "TypeError: ccdsoftCameraImage has no method %s", name)
+}
+
+// simulatedUtils is sky6Utils.  ComputeUniversalTime leaves its result in dOut0, as TheSkyX does.
+type simulatedUtils struct {
+	camera *simulatedCamera
+}
+
+func (utils *simulatedUtils) getProperty(name string) (scriptValue, error) {
+	if name == "dOut0" {
+		return utils.camera.universalTimeResult, nil
+	}
+	return nil, newScriptError(errorUnknownMember, "TypeError: sky6Utils has no property %s", name)
+}
+
+func (utils *simulatedUtils) setProperty(name string, _ scriptValue) error {
+	return newScriptError(errorUnknownMember, "TypeError: sky6Utils has no writable property %s", name)
+}
+
+func (utils *simulatedUtils) callMethod(name string, _ []scriptValue) (scriptValue, error) {
+	if name == "ComputeUniversalTime" {
+		now := utils.camera.server.now().UTC()
+		midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		utils.camera.universalTimeResult = now.Sub(midnight).Hours()
+		return nil, nil
+	}
+	return nil, newScriptError(errorUnknownMember, "TypeError: sky6Utils has no method %s", name)
+}
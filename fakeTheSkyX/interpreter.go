@@ -0,0 +1,1005 @@
+package fakeTheSkyX
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+//	A very small JavaScript interpreter, just large enough to run the command packets that
+//	TheSkyDriver builds.  It understands:
+//		var declarations, with or without an initializer
+//		assignment to variables and to object properties  (ccdsoftCamera.BinX=2;)
+//		method calls on objects  (ccdsoftCamera.TakeImage();)
+//		for loops of the form  for (i = 0; i < n; i++) { ... }
+//		if statements, with or without an else block
+//		arrays: the empty literal [], and reading and assigning elements  (histogram[v]++;)
+//		the operators + - * / < <= > >= == != and unary minus
+//		number, string and boolean literals
+//		Math.floor, Math.round, Math.sqrt, Math.min and Math.max
+//	Anything else is reported as a script error, which is what we want: if the driver starts
+//	emitting JavaScript the fake doesn't understand, the tests should tell us.
+
+// scriptValue is a JavaScript value: float64, string, bool, *scriptArray, or nil for "undefined"
+type scriptValue interface{}
+
+// scriptArray is a JavaScript array.  It is held by pointer so that, as in JavaScript, every
+// variable referring to it sees changes to its elements.
+type scriptArray struct {
+	elements []scriptValue
+}
+
+// scriptObject is implemented by the simulated TheSkyX objects (ccdsoftCamera, sky6Utils, ...)
+type scriptObject interface {
+	getProperty(name string) (scriptValue, error)
+	setProperty(name string, value scriptValue) error
+	callMethod(name string, args []scriptValue) (scriptValue, error)
+}
+
+// ScriptError is an error raised while running a script.  It is reported back to the
+// client in TheSkyX's reply format, with the given error code.
+type ScriptError struct {
+	Code    int
+	Message string
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("%s Error = %d.", e.Message, e.Code)
+}
+
+func newScriptError(code int, format string, args ...interface{}) *ScriptError {
+	return &ScriptError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// scriptContext holds the variables and objects visible to a running script, and the
+// completion value that will be returned to the client
+type scriptContext struct {
+	objects    map[string]scriptObject
+	variables  map[string]scriptValue
+	completion scriptValue
+}
+
+// lookupObject finds a named object: one of the simulated TheSkyX objects, or a built-in
+func (context *scriptContext) lookupObject(name string) (scriptObject, bool) {
+	if object, exists := context.objects[name]; exists {
+		return object, true
+	}
+	object, exists := builtinObjects[name]
+	return object, exists
+}
+
+// runScript parses and executes the given script text, returning its completion value
+// (the value of the last assignment or expression statement executed)
+func runScript(source string, objects map[string]scriptObject) (scriptValue, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	statements, err := p.parseStatements(false)
+	if err != nil {
+		return nil, err
+	}
+	context := &scriptContext{
+		objects:   objects,
+		variables: make(map[string]scriptValue),
+	}
+	if err := executeStatements(statements, context); err != nil {
+		return nil, err
+	}
+	return context.completion, nil
+}
+
+// formatValue converts a value to a string the way JavaScript's string concatenation would
+func formatValue(value scriptValue) string {
+	switch v := value.(type) {
+	case nil:
+		return "undefined"
+	case string:
+		return v
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case float64:
+		if math.IsNaN(v) {
+			return "NaN"
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case *scriptArray:
+		parts := make([]string, len(v.elements))
+		for i, element := range v.elements {
+			if element != nil {
+				parts[i] = formatValue(element)
+			}
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// toNumber converts a value to a number the way JavaScript's arithmetic operators would
+func toNumber(value scriptValue) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1.0
+		}
+		return 0.0
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return math.NaN()
+		}
+		return parsed
+	}
+	return math.NaN()
+}
+
+// toBool converts a value to a boolean the way JavaScript's conditionals would
+func toBool(value scriptValue) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case string:
+		return v != ""
+	case *scriptArray:
+		return true
+	}
+	return false
+}
+
+//	Tokenizer
+
+type tokenKind int
+
+const (
+	tokenIdentifier tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenPunctuation
+	tokenEnd
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	line := 1
+	i := 0
+	for i < len(source) {
+		c := source[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(source[i:], "/*"):
+			end := strings.Index(source[i+2:], "*/")
+			if end < 0 {
+				return nil, newScriptError(errorSyntax, "SyntaxError: unterminated comment on line %d.", line)
+			}
+			line += strings.Count(source[i:i+2+end], "\n")
+			i += end + 4
+		case strings.HasPrefix(source[i:], "//"):
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+		case isLetter(c):
+			start := i
+			for i < len(source) && (isLetter(source[i]) || isDigit(source[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdentifier, text: source[start:i], line: line})
+		case isDigit(c) || (c == '.' && i+1 < len(source) && isDigit(source[i+1])):
+			start := i
+			for i < len(source) && (isDigit(source[i]) || source[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[start:i], line: line})
+		case c == '"' || c == '\'':
+			var text strings.Builder
+			i++
+			for {
+				if i >= len(source) || source[i] == '\n' {
+					return nil, newScriptError(errorSyntax, "SyntaxError: unterminated string on line %d.", line)
+				}
+				if source[i] == c {
+					i++
+					break
+				}
+				if source[i] == '\\' && i+1 < len(source) {
+					switch source[i+1] {
+					case 'n':
+						text.WriteByte('\n')
+					case 't':
+						text.WriteByte('\t')
+					case 'r':
+						text.WriteByte('\r')
+					default:
+						text.WriteByte(source[i+1])
+					}
+					i += 2
+					continue
+				}
+				text.WriteByte(source[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text.String(), line: line})
+		default:
+			twoChar := ""
+			if i+1 < len(source) {
+				twoChar = source[i : i+2]
+			}
+			switch twoChar {
+			case "++", "--", "<=", ">=", "==", "!=", "+=", "-=":
+				tokens = append(tokens, token{kind: tokenPunctuation, text: twoChar, line: line})
+				i += 2
+				continue
+			}
+			if !strings.ContainsRune("(){}[];,.=+-*/<>", rune(c)) {
+				return nil, newScriptError(errorSyntax, "SyntaxError: unexpected character '%c' on line %d.", c, line)
+			}
+			tokens = append(tokens, token{kind: tokenPunctuation, text: string(c), line: line})
+			i++
+		}
+	}
+	tokens = append(tokens, token{kind: tokenEnd, line: line})
+	return tokens, nil
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || c == '$'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+//	Parser.  Produces a small tree of statements and expressions that can be executed.
+
+type statement interface {
+	execute(context *scriptContext) error
+}
+
+type expression interface {
+	evaluate(context *scriptContext) (scriptValue, error)
+}
+
+type parser struct {
+	tokens   []token
+	position int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.position]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.position]
+	if t.kind != tokenEnd {
+		p.position++
+	}
+	return t
+}
+
+func (p *parser) isPunctuation(text string) bool {
+	t := p.peek()
+	return t.kind == tokenPunctuation && t.text == text
+}
+
+func (p *parser) expect(text string) error {
+	t := p.next()
+	if t.kind != tokenPunctuation || t.text != text {
+		return newScriptError(errorSyntax, "SyntaxError: expected '%s' but found '%s' on line %d.", text, t.text, t.line)
+	}
+	return nil
+}
+
+func (p *parser) expectIdentifier() (string, error) {
+	t := p.next()
+	if t.kind != tokenIdentifier {
+		return "", newScriptError(errorSyntax, "SyntaxError: expected a name but found '%s' on line %d.", t.text, t.line)
+	}
+	return t.text, nil
+}
+
+// parseStatements reads statements until end of input, or a closing brace if inBlock
+func (p *parser) parseStatements(inBlock bool) ([]statement, error) {
+	var statements []statement
+	for {
+		if p.peek().kind == tokenEnd {
+			if inBlock {
+				return nil, newScriptError(errorSyntax, "SyntaxError: missing '}' at end of script.")
+			}
+			return statements, nil
+		}
+		if inBlock && p.isPunctuation("}") {
+			p.next()
+			return statements, nil
+		}
+		if p.isPunctuation(";") {
+			p.next()
+			continue
+		}
+		s, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, s)
+	}
+}
+
+func (p *parser) parseStatement() (statement, error) {
+	t := p.peek()
+	if t.kind == tokenIdentifier && t.text == "var" {
+		p.next()
+		name, err := p.expectIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		s := &varStatement{name: name}
+		if p.isPunctuation("=") {
+			p.next()
+			if s.initializer, err = p.parseExpression(); err != nil {
+				return nil, err
+			}
+		}
+		return s, p.endStatement()
+	}
+	if t.kind == tokenIdentifier && t.text == "for" {
+		return p.parseFor()
+	}
+	if t.kind == tokenIdentifier && t.text == "if" {
+		return p.parseIf()
+	}
+	s, err := p.parseSimpleStatement()
+	if err != nil {
+		return nil, err
+	}
+	return s, p.endStatement()
+}
+
+// endStatement accepts a semicolon, or a statement ending implicitly at a brace or end of input
+func (p *parser) endStatement() error {
+	if p.isPunctuation(";") {
+		p.next()
+		return nil
+	}
+	if p.isPunctuation("}") || p.peek().kind == tokenEnd {
+		return nil
+	}
+	t := p.peek()
+	return newScriptError(errorSyntax, "SyntaxError: expected ';' but found '%s' on line %d.", t.text, t.line)
+}
+
+// parseSimpleStatement handles assignments, increments and bare expressions (usually calls)
+func (p *parser) parseSimpleStatement() (statement, error) {
+	start := p.position
+	target, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	ref, isReference := target.(assignableExpression)
+	switch {
+	case p.isPunctuation("="), p.isPunctuation("+="), p.isPunctuation("-="):
+		if !isReference {
+			t := p.tokens[start]
+			return nil, newScriptError(errorSyntax, "SyntaxError: invalid assignment target on line %d.", t.line)
+		}
+		operator := p.next().text
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if operator != "=" {
+			value = &binaryExpression{operator: operator[:1], left: ref, right: value}
+		}
+		return &assignStatement{target: ref, value: value}, nil
+	case p.isPunctuation("++"), p.isPunctuation("--"):
+		if !isReference {
+			t := p.tokens[start]
+			return nil, newScriptError(errorSyntax, "SyntaxError: invalid increment target on line %d.", t.line)
+		}
+		operator := p.next().text
+		value := &binaryExpression{operator: operator[:1], left: ref, right: &literalExpression{value: 1.0}}
+		return &assignStatement{target: ref, value: value}, nil
+	}
+	return &expressionStatement{expression: target}, nil
+}
+
+func (p *parser) parseIf() (statement, error) {
+	p.next() // "if"
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	s := &ifStatement{}
+	var err error
+	if s.condition, err = p.parseExpression(); err != nil {
+		return nil, err
+	}
+	if err = p.expect(")"); err != nil {
+		return nil, err
+	}
+	if err = p.expect("{"); err != nil {
+		return nil, err
+	}
+	if s.body, err = p.parseStatements(true); err != nil {
+		return nil, err
+	}
+	if p.peek().kind == tokenIdentifier && p.peek().text == "else" {
+		p.next()
+		if err = p.expect("{"); err != nil {
+			return nil, err
+		}
+		if s.elseBody, err = p.parseStatements(true); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (p *parser) parseFor() (statement, error) {
+	p.next() // "for"
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	s := &forStatement{}
+	var err error
+	if p.peek().kind == tokenIdentifier && p.peek().text == "var" {
+		p.next()
+	}
+	if s.initializer, err = p.parseSimpleStatement(); err != nil {
+		return nil, err
+	}
+	if err = p.expect(";"); err != nil {
+		return nil, err
+	}
+	if s.condition, err = p.parseExpression(); err != nil {
+		return nil, err
+	}
+	if err = p.expect(";"); err != nil {
+		return nil, err
+	}
+	if s.update, err = p.parseSimpleStatement(); err != nil {
+		return nil, err
+	}
+	if err = p.expect(")"); err != nil {
+		return nil, err
+	}
+	if err = p.expect("{"); err != nil {
+		return nil, err
+	}
+	if s.body, err = p.parseStatements(true); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+//	Expressions, in increasing order of precedence: comparison, additive, multiplicative, unary, primary
+
+func (p *parser) parseExpression() (expression, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokenPunctuation {
+			return left, nil
+		}
+		switch t.text {
+		case "<", "<=", ">", ">=", "==", "!=":
+			p.next()
+			right, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			left = &binaryExpression{operator: t.text, left: left, right: right}
+		default:
+			return left, nil
+		}
+	}
+}
+
+func (p *parser) parseAdditive() (expression, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.isPunctuation("+") || p.isPunctuation("-") {
+		operator := p.next().text
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpression{operator: operator, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseMultiplicative() (expression, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isPunctuation("*") || p.isPunctuation("/") {
+		operator := p.next().text
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpression{operator: operator, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (expression, error) {
+	if p.isPunctuation("-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryExpression{operator: "-", left: &literalExpression{value: 0.0}, right: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (expression, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, newScriptError(errorSyntax, "SyntaxError: bad number '%s' on line %d.", t.text, t.line)
+		}
+		return &literalExpression{value: value}, nil
+	case tokenString:
+		return &literalExpression{value: t.text}, nil
+	case tokenIdentifier:
+		switch t.text {
+		case "true":
+			return &literalExpression{value: true}, nil
+		case "false":
+			return &literalExpression{value: false}, nil
+		}
+		ref := &referenceExpression{names: []string{t.text}}
+		for p.isPunctuation(".") {
+			p.next()
+			name, err := p.expectIdentifier()
+			if err != nil {
+				return nil, err
+			}
+			ref.names = append(ref.names, name)
+		}
+		if p.isPunctuation("[") {
+			return p.parseIndex(ref)
+		}
+		if !p.isPunctuation("(") {
+			return ref, nil
+		}
+		p.next()
+		call := &callExpression{names: ref.names}
+		for !p.isPunctuation(")") {
+			arg, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if p.isPunctuation(",") {
+				p.next()
+			}
+		}
+		p.next()
+		return call, nil
+	case tokenPunctuation:
+		if t.text == "[" {
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			return &arrayLiteralExpression{}, nil
+		}
+		if t.text == "(" {
+			inner, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		}
+	}
+	return nil, newScriptError(errorSyntax, "SyntaxError: unexpected '%s' on line %d.", t.text, t.line)
+}
+
+// parseIndex reads one or more element subscripts after an array reference:  row[x]
+func (p *parser) parseIndex(array expression) (expression, error) {
+	for p.isPunctuation("[") {
+		p.next()
+		index, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		array = &indexExpression{array: array, index: index}
+	}
+	return array, nil
+}
+
+//	Statement and expression implementations
+
+type varStatement struct {
+	name        string
+	initializer expression
+}
+
+func (s *varStatement) execute(context *scriptContext) error {
+	if s.initializer == nil {
+		if _, exists := context.variables[s.name]; !exists {
+			context.variables[s.name] = nil
+		}
+		return nil
+	}
+	value, err := s.initializer.evaluate(context)
+	if err != nil {
+		return err
+	}
+	context.variables[s.name] = value
+	context.completion = value
+	return nil
+}
+
+// assignableExpression is an expression that can be the target of an assignment: a variable,
+// an object property or an array element
+type assignableExpression interface {
+	expression
+	assign(context *scriptContext, value scriptValue) error
+}
+
+type assignStatement struct {
+	target assignableExpression
+	value  expression
+}
+
+func (s *assignStatement) execute(context *scriptContext) error {
+	value, err := s.value.evaluate(context)
+	if err != nil {
+		return err
+	}
+	if err := s.target.assign(context, value); err != nil {
+		return err
+	}
+	context.completion = value
+	return nil
+}
+
+type expressionStatement struct {
+	expression expression
+}
+
+func (s *expressionStatement) execute(context *scriptContext) error {
+	value, err := s.expression.evaluate(context)
+	if err != nil {
+		return err
+	}
+	context.completion = value
+	return nil
+}
+
+// maxLoopIterations guards against a runaway loop hanging the fake server
+const maxLoopIterations = 100000
+
+type forStatement struct {
+	initializer statement
+	condition   expression
+	update      statement
+	body        []statement
+}
+
+func (s *forStatement) execute(context *scriptContext) error {
+	if err := s.initializer.execute(context); err != nil {
+		return err
+	}
+	for iterations := 0; ; iterations++ {
+		if iterations > maxLoopIterations {
+			return newScriptError(errorSyntax, "RangeError: loop did not terminate.")
+		}
+		condition, err := s.condition.evaluate(context)
+		if err != nil {
+			return err
+		}
+		if !toBool(condition) {
+			return nil
+		}
+		if err := executeStatements(s.body, context); err != nil {
+			return err
+		}
+		if err := s.update.execute(context); err != nil {
+			return err
+		}
+	}
+}
+
+type ifStatement struct {
+	condition expression
+	body      []statement
+	elseBody  []statement
+}
+
+func (s *ifStatement) execute(context *scriptContext) error {
+	condition, err := s.condition.evaluate(context)
+	if err != nil {
+		return err
+	}
+	if toBool(condition) {
+		return executeStatements(s.body, context)
+	}
+	return executeStatements(s.elseBody, context)
+}
+
+func executeStatements(statements []statement, context *scriptContext) error {
+	for _, s := range statements {
+		if err := s.execute(context); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type literalExpression struct {
+	value scriptValue
+}
+
+func (e *literalExpression) evaluate(_ *scriptContext) (scriptValue, error) {
+	return e.value, nil
+}
+
+// referenceExpression is a variable name or an object.property reference
+type referenceExpression struct {
+	names []string
+}
+
+func (e *referenceExpression) evaluate(context *scriptContext) (scriptValue, error) {
+	if len(e.names) == 1 {
+		value, exists := context.variables[e.names[0]]
+		if !exists {
+			return nil, newScriptError(errorReference, "ReferenceError: Can't find variable: %s", e.names[0])
+		}
+		return value, nil
+	}
+	object, err := e.object(context)
+	if err != nil {
+		return nil, err
+	}
+	return object.getProperty(e.names[1])
+}
+
+func (e *referenceExpression) assign(context *scriptContext, value scriptValue) error {
+	if len(e.names) == 1 {
+		// Assignment to an undeclared name creates a global, as in non-strict JavaScript
+		context.variables[e.names[0]] = value
+		return nil
+	}
+	object, err := e.object(context)
+	if err != nil {
+		return err
+	}
+	return object.setProperty(e.names[1], value)
+}
+
+func (e *referenceExpression) object(context *scriptContext) (scriptObject, error) {
+	if len(e.names) != 2 {
+		return nil, newScriptError(errorSyntax, "TypeError: unsupported reference %s", strings.Join(e.names, "."))
+	}
+	object, exists := context.lookupObject(e.names[0])
+	if !exists {
+		return nil, newScriptError(errorReference, "ReferenceError: Can't find variable: %s", e.names[0])
+	}
+	return object, nil
+}
+
+type arrayLiteralExpression struct{}
+
+func (e *arrayLiteralExpression) evaluate(_ *scriptContext) (scriptValue, error) {
+	return &scriptArray{}, nil
+}
+
+// maxArrayLength guards against a script indexing far enough to exhaust memory
+const maxArrayLength = 1 << 20
+
+// indexExpression is an array element:  row[x]
+type indexExpression struct {
+	array expression
+	index expression
+}
+
+func (e *indexExpression) evaluate(context *scriptContext) (scriptValue, error) {
+	array, index, err := e.arrayAndIndex(context)
+	if err != nil {
+		return nil, err
+	}
+	if index >= len(array.elements) {
+		return nil, nil
+	}
+	return array.elements[index], nil
+}
+
+// assign sets an element, growing the array if need be, as JavaScript does
+func (e *indexExpression) assign(context *scriptContext, value scriptValue) error {
+	array, index, err := e.arrayAndIndex(context)
+	if err != nil {
+		return err
+	}
+	if index >= maxArrayLength {
+		return newScriptError(errorSyntax, "RangeError: array index %d too large.", index)
+	}
+	for len(array.elements) <= index {
+		array.elements = append(array.elements, nil)
+	}
+	array.elements[index] = value
+	return nil
+}
+
+func (e *indexExpression) arrayAndIndex(context *scriptContext) (*scriptArray, int, error) {
+	value, err := e.array.evaluate(context)
+	if err != nil {
+		return nil, 0, err
+	}
+	array, isArray := value.(*scriptArray)
+	if !isArray {
+		return nil, 0, newScriptError(errorSyntax, "TypeError: %s is not an array", formatValue(value))
+	}
+	indexValue, err := e.index.evaluate(context)
+	if err != nil {
+		return nil, 0, err
+	}
+	index := toNumber(indexValue)
+	if index < 0 || index != math.Trunc(index) {
+		return nil, 0, newScriptError(errorSyntax, "RangeError: invalid array index %s", formatValue(indexValue))
+	}
+	return array, int(index), nil
+}
+
+type callExpression struct {
+	names []string
+	args  []expression
+}
+
+func (e *callExpression) evaluate(context *scriptContext) (scriptValue, error) {
+	if len(e.names) != 2 {
+		return nil, newScriptError(errorSyntax, "TypeError: %s is not a function", strings.Join(e.names, "."))
+	}
+	object, exists := context.lookupObject(e.names[0])
+	if !exists {
+		return nil, newScriptError(errorReference, "ReferenceError: Can't find variable: %s", e.names[0])
+	}
+	var args []scriptValue
+	for _, argExpression := range e.args {
+		value, err := argExpression.evaluate(context)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+	return object.callMethod(e.names[1], args)
+}
+
+type binaryExpression struct {
+	operator string
+	left     expression
+	right    expression
+}
+
+func (e *binaryExpression) evaluate(context *scriptContext) (scriptValue, error) {
+	left, err := e.left.evaluate(context)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.evaluate(context)
+	if err != nil {
+		return nil, err
+	}
+	if e.operator == "+" {
+		_, leftIsString := left.(string)
+		_, rightIsString := right.(string)
+		if leftIsString || rightIsString {
+			return formatValue(left) + formatValue(right), nil
+		}
+	}
+	if e.operator == "==" || e.operator == "!=" {
+		equal := formatValue(left) == formatValue(right)
+		if _, isString := left.(string); !isString {
+			equal = toNumber(left) == toNumber(right)
+		}
+		return equal == (e.operator == "=="), nil
+	}
+	a, b := toNumber(left), toNumber(right)
+	switch e.operator {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/":
+		return a / b, nil
+	case "<":
+		return a < b, nil
+	case "<=":
+		return a <= b, nil
+	case ">":
+		return a > b, nil
+	case ">=":
+		return a >= b, nil
+	}
+	return nil, newScriptError(errorSyntax, "SyntaxError: unsupported operator %s", e.operator)
+}
+
+//	Built-in objects
+
+var builtinObjects = map[string]scriptObject{
+	"Math": mathObject{},
+}
+
+// mathObject is JavaScript's Math, with just the functions the driver uses
+type mathObject struct{}
+
+func (mathObject) getProperty(name string) (scriptValue, error) {
+	if name == "PI" {
+		return math.Pi, nil
+	}
+	return nil, newScriptError(errorUnknownMember, "TypeError: Math has no property %s", name)
+}
+
+func (mathObject) setProperty(name string, _ scriptValue) error {
+	return newScriptError(errorUnknownMember, "TypeError: Math has no writable property %s", name)
+}
+
+func (mathObject) callMethod(name string, args []scriptValue) (scriptValue, error) {
+	numbers := make([]float64, len(args))
+	for i, arg := range args {
+		numbers[i] = toNumber(arg)
+	}
+	switch name {
+	case "floor", "round", "sqrt":
+		if len(numbers) != 1 {
+			return nil, newScriptError(errorSyntax, "TypeError: Math.%s expects one argument", name)
+		}
+		switch name {
+		case "floor":
+			return math.Floor(numbers[0]), nil
+		case "round":
+			return math.Floor(numbers[0] + 0.5), nil
+		}
+		return math.Sqrt(numbers[0]), nil
+	case "min", "max":
+		result := math.Inf(1)
+		if name == "max" {
+			result = math.Inf(-1)
+		}
+		for _, number := range numbers {
+			if math.IsNaN(number) {
+				return math.NaN(), nil
+			}
+			if name == "min" {
+				result = math.Min(result, number)
+			} else {
+				result = math.Max(result, number)
+			}
+		}
+		return result, nil
+	}
+	return nil, newScriptError(errorUnknownMember, "TypeError: Math has no method %s", name)
+}
@@ -0,0 +1,460 @@
+package fakeTheSkyX
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+//	FakeTheSkyServer is an in-process stand-in for TheSkyX's TCP scripting server, so the
+//	driver and service can be tested without a real TheSkyX.  It accepts the same
+//	"/* Java Script */ ... /* Socket End Packet */" packets, runs them against a simulated
+//	camera, filter wheel and mount, and replies in TheSkyX's "<data>|No error. Error = 0." format.
+//
+//	Typical use in a test:
+//		fake := fakeTheSkyX.NewFakeTheSkyServer(false, 0)
+//		err := fake.Start()
+//		defer fake.Close()
+//		err = service.Connect("localhost", fake.Port())
+
+const packetStart = "/* Java Script */"
+const packetEnd = "/* Socket End Packet */"
+
+// maxPacketSize limits how much we will buffer waiting for an end-of-packet marker
+const maxPacketSize = 64 * 1024
+
+var defaultFilterNames = []string{"Red", "Green", "Blue", "Luminance", "Ha", "", "", ""}
+
+type FakeTheSkyServer struct {
+	debug     bool
+	verbosity int
+
+	// Simulation settings; change these before Start
+	ambientTemperature float64
+	coolingRate        float64 // degrees per second
+	maxCoolingDelta    float64 // furthest below ambient the cooler can reach, in degrees
+	downloadTime       float64 // seconds, at binning 1
+	sensorWidth        int
+	sensorHeight       int
+	pixelSize          float64 // microns
+	maxBinning         int
+	supportsBias       bool
+	hasCooler          bool
+	hasFilterWheel     bool
+	filterNames        []string
+	filterMoveTime     float64 // seconds per slot moved
+	biasLevel          float64
+	darkCurrent        float64 // ADU per second
+	flatRate           float64 // ADU per second
+	hotSpotRadius      float64 // fraction of the image height; 0 for none
+	hotSpotADU         float64 // added to pixels in the hot spot
+	slewRate           float64 // degrees per second
+	parkAltitude       float64
+	parkAzimuth        float64
+	now                func() time.Time
+	replyChunkSize     int           // if non-zero, replies are written in pieces of this size
+	replyChunkPause    time.Duration // pause between the pieces
+	replyFilter        func(reply string) string
+
+	listener        net.Listener
+	mutex           sync.Mutex
+	connections     map[net.Conn]bool
+	waitGroup       sync.WaitGroup
+	camera          *simulatedCamera
+	telescope       *simulatedTelescope
+	objects         map[string]scriptObject
+	packetCount     int
+	connectionCount int
+}
+
+// NewFakeTheSkyServer is the constructor for a fake server with a typical simulated camera:
+// ambient temperature 20, fast cooling, short download time and a 5-filter wheel; and a
+// mount, parked, that slews at 20 degrees per second
+func NewFakeTheSkyServer(debug bool, verbosity int) *FakeTheSkyServer {
+	server := &FakeTheSkyServer{
+		debug:              debug,
+		verbosity:          verbosity,
+		ambientTemperature: 20.0,
+		coolingRate:        20.0,
+		maxCoolingDelta:    40.0,
+		downloadTime:       0.2,
+		sensorWidth:        4656,
+		sensorHeight:       3520,
+		pixelSize:          3.8,
+		maxBinning:         4,
+		supportsBias:       true,
+		hasCooler:          true,
+		hasFilterWheel:     true,
+		filterNames:        append([]string{}, defaultFilterNames...),
+		biasLevel:          1000.0,
+		darkCurrent:        2.0,
+		flatRate:           1500.0,
+		slewRate:           20.0,
+		parkAltitude:       45.0,
+		parkAzimuth:        0.0,
+		now:                time.Now,
+		connections:        make(map[net.Conn]bool),
+	}
+	return server
+}
+
+func (server *FakeTheSkyServer) SetAmbientTemperature(temperature float64) {
+	server.ambientTemperature = temperature
+}
+
+// SetCoolingRate sets how quickly, in degrees per second, the sensor temperature moves
+func (server *FakeTheSkyServer) SetCoolingRate(degreesPerSecond float64) {
+	server.coolingRate = degreesPerSecond
+}
+
+// SetMaxCoolingDelta sets how far below ambient the cooler can take the sensor, running at
+// full power.  Set points below that are never reached.
+func (server *FakeTheSkyServer) SetMaxCoolingDelta(degrees float64) {
+	server.maxCoolingDelta = degrees
+}
+
+// SetDownloadTime sets the simulated download time at binning 1; higher binnings are faster
+func (server *FakeTheSkyServer) SetDownloadTime(seconds float64) {
+	server.downloadTime = seconds
+}
+
+// SetSensor sets the camera's reported sensor size, unbinned, and pixel size in microns; a
+// pixel size of 0 makes reading PixelSize1x1 fail, as with camera drivers that don't report it
+func (server *FakeTheSkyServer) SetSensor(width int, height int, pixelSize float64) {
+	server.sensorWidth = width
+	server.sensorHeight = height
+	server.pixelSize = pixelSize
+}
+
+// SetMaxBinning sets the highest binning the camera can do; images binned higher are refused
+func (server *FakeTheSkyServer) SetMaxBinning(binning int) {
+	server.maxBinning = binning
+}
+
+// SetSupportsBiasFrames sets whether the camera can take bias frames; if not, they are refused
+// with ERR_CMDFAILED
+func (server *FakeTheSkyServer) SetSupportsBiasFrames(flag bool) {
+	server.supportsBias = flag
+}
+
+// SetHasCooler sets whether the camera has a cooler; if not, temperature regulation is refused
+func (server *FakeTheSkyServer) SetHasCooler(flag bool) {
+	server.hasCooler = flag
+}
+
+// SetHotSpot adds a bright disc, of the given radius as a fraction of the image height, to the
+// centre of every image, e.g. a light leak or a flat panel's bulb showing through.  Pixels in it
+// are extraADU brighter, up to saturation.  A radius of 0 removes it.
+func (server *FakeTheSkyServer) SetHotSpot(radiusFraction float64, extraADU float64) {
+	server.hotSpotRadius = radiusFraction
+	server.hotSpotADU = extraADU
+}
+
+func (server *FakeTheSkyServer) SetHasFilterWheel(flag bool) {
+	server.hasFilterWheel = flag
+}
+
+// SetFilterNames sets the slot names reported by the filter wheel.  Like TheSkyX's simulator,
+// trailing blank names may be included to pad the wheel out to its physical slot count.
+func (server *FakeTheSkyServer) SetFilterNames(names []string) {
+	server.filterNames = append([]string{}, names...)
+}
+
+// SetFilterMoveTime sets how long the filter wheel takes to move one slot.  While moving, it
+// reports FilterIndexZeroBased as -1.
+func (server *FakeTheSkyServer) SetFilterMoveTime(secondsPerSlot float64) {
+	server.filterMoveTime = secondsPerSlot
+}
+
+// SetSlewRate sets how fast, in degrees per second, the mount moves in altitude and azimuth
+func (server *FakeTheSkyServer) SetSlewRate(degreesPerSecond float64) {
+	server.slewRate = degreesPerSecond
+}
+
+// SetParkPosition sets where the mount goes when parked.  It starts the session parked there.
+func (server *FakeTheSkyServer) SetParkPosition(altitude float64, azimuth float64) {
+	server.parkAltitude = altitude
+	server.parkAzimuth = azimuth
+}
+
+// SetClock replaces the time source, for tests that want to control simulated time
+func (server *FakeTheSkyServer) SetClock(now func() time.Time) {
+	server.now = now
+}
+
+// SetReplyChunking makes the server write each reply in pieces of the given size, pausing
+// between them, so clients see the reply split across several TCP segments
+func (server *FakeTheSkyServer) SetReplyChunking(chunkSize int, pause time.Duration) {
+	server.replyChunkSize = chunkSize
+	server.replyChunkPause = pause
+}
+
+// SetReplyFilter installs a function that may alter each reply before it is sent, so tests
+// can simulate malformed replies
+func (server *FakeTheSkyServer) SetReplyFilter(filter func(reply string) string) {
+	server.replyFilter = filter
+}
+
+// Start begins listening on a free port on the loopback interface, and serving connections
+func (server *FakeTheSkyServer) Start() error {
+	if server.listener != nil {
+		return errors.New("FakeTheSkyServer/Start: already started")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	server.listener = listener
+	server.camera = newSimulatedCamera(server)
+	server.telescope = newSimulatedTelescope(server)
+	server.objects = map[string]scriptObject{
+		"sky6RASCOMTele":     server.telescope,
+		"ccdsoftCamera":      server.camera,
+		"ccdsoftCameraImage": &simulatedImage{camera: server.camera},
+		"sky6Utils":          &simulatedUtils{camera: server.camera},
+	}
+	if server.verbosity >= 4 || server.debug {
+		fmt.Println("FakeTheSkyServer listening on", listener.Addr())
+	}
+	server.waitGroup.Add(1)
+	go server.acceptConnections()
+	return nil
+}
+
+// Port returns the port number the server is listening on
+func (server *FakeTheSkyServer) Port() int {
+	if server.listener == nil {
+		return 0
+	}
+	return server.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Close stops listening, drops any open connections, and waits for them to finish
+func (server *FakeTheSkyServer) Close() error {
+	if server.listener == nil {
+		return nil
+	}
+	err := server.listener.Close()
+	server.mutex.Lock()
+	for conn := range server.connections {
+		_ = conn.Close()
+	}
+	server.mutex.Unlock()
+	server.waitGroup.Wait()
+	server.listener = nil
+	return err
+}
+
+// PacketCount returns the number of command packets received so far
+func (server *FakeTheSkyServer) PacketCount() int {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return server.packetCount
+}
+
+// ConnectionCount returns the number of client connections accepted so far
+func (server *FakeTheSkyServer) ConnectionCount() int {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return server.connectionCount
+}
+
+// DropConnections abruptly closes every open client connection, while continuing to listen
+// for new ones.  This simulates a network glitch or TheSkyX restarting its server.
+func (server *FakeTheSkyServer) DropConnections() {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	for conn := range server.connections {
+		_ = conn.Close()
+	}
+}
+
+// SavedImageCount returns the number of images that would have been saved by AutoSave
+func (server *FakeTheSkyServer) SavedImageCount() int {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return server.camera.savedImageCount
+}
+
+// SavedImages returns the images saved by scripts with ccdsoftCameraImage.Save (not those
+// saved by AutoSave, which are only counted)
+func (server *FakeTheSkyServer) SavedImages() []SavedImage {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return append([]SavedImage{}, server.camera.savedImages...)
+}
+
+// TakenImages returns every exposure started, including those not saved
+func (server *FakeTheSkyServer) TakenImages() []TakenImage {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return append([]TakenImage{}, server.camera.takenImages...)
+}
+
+// FilterWheelConnected reports whether the filter wheel is currently connected
+func (server *FakeTheSkyServer) FilterWheelConnected() bool {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return server.camera.filterWheelConnected
+}
+
+// CameraTemperature returns the current simulated sensor temperature
+func (server *FakeTheSkyServer) CameraTemperature() float64 {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.camera.updateTemperature()
+	return server.camera.temperature
+}
+
+func (server *FakeTheSkyServer) acceptConnections() {
+	defer server.waitGroup.Done()
+	for {
+		conn, err := server.listener.Accept()
+		if err != nil {
+			return // listener closed
+		}
+		server.mutex.Lock()
+		server.connections[conn] = true
+		server.connectionCount++
+		server.mutex.Unlock()
+		server.waitGroup.Add(1)
+		go server.serveConnection(conn)
+	}
+}
+
+// serveConnection reads packets from one client connection until it is closed.  A client may
+// send one packet per connection (dial, send, read, close) or many on a single connection.
+func (server *FakeTheSkyServer) serveConnection(conn net.Conn) {
+	defer server.waitGroup.Done()
+	defer func() {
+		server.mutex.Lock()
+		delete(server.connections, conn)
+		server.mutex.Unlock()
+		_ = conn.Close()
+	}()
+
+	var pending strings.Builder
+	buffer := make([]byte, 4096)
+	for {
+		numRead, err := conn.Read(buffer)
+		if numRead > 0 {
+			pending.WriteString(string(buffer[:numRead]))
+			for {
+				received := pending.String()
+				endIndex := strings.Index(received, packetEnd)
+				if endIndex < 0 {
+					break
+				}
+				packet := received[:endIndex+len(packetEnd)]
+				pending.Reset()
+				pending.WriteString(received[endIndex+len(packetEnd):])
+				reply := server.handlePacket(packet)
+				if err := server.writeReply(conn, reply); err != nil {
+					return
+				}
+			}
+			if pending.Len() > maxPacketSize {
+				_, _ = conn.Write([]byte(formatReply("", newScriptError(errorSyntax, "Packet too large."))))
+				return
+			}
+		}
+		if err != nil {
+			if err != io.EOF && server.verbosity >= 5 {
+				fmt.Println("FakeTheSkyServer read error:", err)
+			}
+			return
+		}
+	}
+}
+
+// writeReply sends a reply to the client, in pieces if reply chunking has been set
+func (server *FakeTheSkyServer) writeReply(conn net.Conn, reply string) error {
+	if server.replyFilter != nil {
+		reply = server.replyFilter(reply)
+	}
+	if server.replyChunkSize <= 0 {
+		_, err := conn.Write([]byte(reply))
+		return err
+	}
+	for start := 0; start < len(reply); start += server.replyChunkSize {
+		end := min(start+server.replyChunkSize, len(reply))
+		if _, err := conn.Write([]byte(reply[start:end])); err != nil {
+			return err
+		}
+		time.Sleep(server.replyChunkPause)
+	}
+	return nil
+}
+
+// handlePacket runs one command packet and returns the reply text
+func (server *FakeTheSkyServer) handlePacket(packet string) string {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.packetCount++
+	if server.verbosity >= 6 || server.debug {
+		fmt.Println("FakeTheSkyServer received packet:", packet)
+	}
+
+	script := strings.TrimLeft(packet, " \t\r\n")
+	if !strings.HasPrefix(script, packetStart) {
+		return formatReply("", newScriptError(errorSyntax, "TypeError: Packet does not begin with %s", packetStart))
+	}
+	result, err := runScript(script, server.objects)
+	reply := ""
+	if err == nil && result != nil {
+		reply = formatValue(result)
+	}
+	formatted := formatReply(reply, err)
+	if server.verbosity >= 6 || server.debug {
+		fmt.Println("FakeTheSkyServer replying:", formatted)
+	}
+	return formatted
+}
+
+// formatReply builds a reply in TheSkyX's format: the script's result, a "|", and an error line
+func formatReply(result string, err error) string {
+	if err == nil {
+		return result + "|No error. Error = 0."
+	}
+	var scriptError *ScriptError
+	if errors.As(err, &scriptError) {
+		return result + "|" + scriptError.Error()
+	}
+	return result + "|" + err.Error() + " Error = 1."
+}
+
+// simulatedAverageValue is the mean ADU a frame of the given type and exposure would have
+func (server *FakeTheSkyServer) simulatedAverageValue(frame int, exposure float64) float64 {
+	value := server.biasLevel
+	switch frame {
+	case frameDark:
+		value += server.darkCurrent * exposure
+	case frameFlat, frameLight:
+		value += server.flatRate * exposure
+	}
+	if value > 65535.0 {
+		value = 65535.0
+	}
+	return value
+}
+
+// simulatedPixelValue is the ADU of one pixel of an image with the given mean.  A fixed
+// pattern of noise, roughly the size of shot noise, averages out to zero over a few rows; the
+// hot spot, if any, is added on top.
+func (server *FakeTheSkyServer) simulatedPixelValue(averageValue float64, x int, y int, width int, height int) float64 {
+	noise := float64((x*7+y*13)%11-5) * math.Sqrt(averageValue) / 5.0
+	value := averageValue + noise
+	if server.hotSpotRadius > 0 {
+		dx := float64(x) - float64(width)/2.0
+		dy := float64(y) - float64(height)/2.0
+		if math.Hypot(dx, dy) <= server.hotSpotRadius*float64(height) {
+			value += server.hotSpotADU
+		}
+	}
+	return math.Round(math.Max(0.0, math.Min(65535.0, value)))
+}
@@ -0,0 +1,171 @@
+package fakeTheSkyX
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sendPacket wraps the given script in TheSkyX packet framing, sends it on the connection,
+// and reads back one reply
+func sendPacket(t *testing.T, conn net.Conn, script string) string {
+	packet := "/* Java Script */\n/* Socket Start Packet */\n" + script + "/* Socket End Packet */\n"
+	_, err := conn.Write([]byte(packet))
+	require.Nil(t, err, "Unable to write packet")
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buffer := make([]byte, 4096)
+	numRead, err := conn.Read(buffer)
+	require.Nil(t, err, "Unable to read reply")
+	return string(buffer[:numRead])
+}
+
+func startFakeServer(t *testing.T) (*FakeTheSkyServer, net.Conn) {
+	fake := NewFakeTheSkyServer(false, 0)
+	require.Nil(t, fake.Start(), "Unable to start fake server")
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", fake.Port()))
+	require.Nil(t, err, "Unable to connect to fake server")
+	return fake, conn
+}
+
+func TestFakeServer(t *testing.T) {
+
+	t.Run("simple script returns result in TheSkyX reply format", func(t *testing.T) {
+		fake, conn := startFakeServer(t)
+		defer fake.Close()
+		defer conn.Close()
+
+		reply := sendPacket(t, conn, "var Out;\nOut=2+3+\"\\n\";\n")
+		require.Equal(t, "5\n|No error. Error = 0.", reply)
+	})
+
+	t.Run("several packets on one connection", func(t *testing.T) {
+		fake, conn := startFakeServer(t)
+		defer fake.Close()
+		defer conn.Close()
+
+		require.Equal(t, "0|No error. Error = 0.", sendPacket(t, conn, "ccdsoftCamera.Connect();\nvar Out;\nOut=0;\n"))
+		require.Equal(t, "20\n|No error. Error = 0.", sendPacket(t, conn, "var temp=ccdsoftCamera.Temperature;\nvar Out;\nOut=temp + \"\\n\";\n"))
+		require.Equal(t, 2, fake.PacketCount())
+	})
+
+	t.Run("camera not connected is reported as an error", func(t *testing.T) {
+		fake, conn := startFakeServer(t)
+		defer fake.Close()
+		defer conn.Close()
+
+		reply := sendPacket(t, conn, "var temp=ccdsoftCamera.Temperature;\nvar Out;\nOut=temp + \"\\n\";\n")
+		require.Equal(t, "|TypeError: Camera is not connected. Error = 215.", reply)
+	})
+
+	t.Run("unknown property is reported as an error", func(t *testing.T) {
+		fake, conn := startFakeServer(t)
+		defer fake.Close()
+		defer conn.Close()
+
+		reply := sendPacket(t, conn, "ccdsoftCamera.NoSuchThing=1;\n")
+		require.Contains(t, reply, "no writable property NoSuchThing")
+	})
+
+	t.Run("cooling drifts toward set point", func(t *testing.T) {
+		fake := NewFakeTheSkyServer(false, 0)
+		simulatedTime := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+		fake.SetClock(func() time.Time { return simulatedTime })
+		fake.SetCoolingRate(1.0)
+		require.Nil(t, fake.Start())
+		defer fake.Close()
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", fake.Port()))
+		require.Nil(t, err)
+		defer conn.Close()
+
+		sendPacket(t, conn, "ccdsoftCamera.Connect();\n")
+		sendPacket(t, conn, "ccdsoftCamera.TemperatureSetPoint=-10.00;\nccdsoftCamera.RegulateTemperature=true;\n")
+		simulatedTime = simulatedTime.Add(10 * time.Second)
+		require.InDelta(t, 10.0, fake.CameraTemperature(), 0.001)
+		simulatedTime = simulatedTime.Add(60 * time.Second)
+		require.InDelta(t, -10.0, fake.CameraTemperature(), 0.001)
+	})
+
+	t.Run("asynchronous exposure completes after exposure plus download", func(t *testing.T) {
+		fake := NewFakeTheSkyServer(false, 0)
+		simulatedTime := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+		fake.SetClock(func() time.Time { return simulatedTime })
+		fake.SetDownloadTime(4.0)
+		require.Nil(t, fake.Start())
+		defer fake.Close()
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", fake.Port()))
+		require.Nil(t, err)
+		defer conn.Close()
+
+		const isComplete = "var complete = ccdsoftCamera.IsExposureComplete;\nvar Out;\nOut=complete+\"\\n\";\n"
+		sendPacket(t, conn, "ccdsoftCamera.Connect();\n")
+		sendPacket(t, conn, "ccdsoftCamera.Asynchronous=true;\nccdsoftCamera.Frame=3;\nccdsoftCamera.AutoSaveOn=true;\n"+
+			"ccdsoftCamera.BinX=2;\nccdsoftCamera.BinY=2;\nccdsoftCamera.ExposureTime=30.00;\nccdsoftCamera.TakeImage();\n")
+		require.Equal(t, "0\n|No error. Error = 0.", sendPacket(t, conn, isComplete))
+		simulatedTime = simulatedTime.Add(30 * time.Second)
+		require.Equal(t, "0\n|No error. Error = 0.", sendPacket(t, conn, isComplete))
+		simulatedTime = simulatedTime.Add(1 * time.Second) // download at 2x2 is 4/4 seconds
+		require.Equal(t, "1\n|No error. Error = 0.", sendPacket(t, conn, isComplete))
+		require.Equal(t, 1, fake.SavedImageCount())
+	})
+
+	t.Run("filter names loop", func(t *testing.T) {
+		fake, conn := startFakeServer(t)
+		defer fake.Close()
+		defer conn.Close()
+		fake.SetFilterNames([]string{"L", "R", ""})
+
+		var script strings.Builder
+		script.WriteString("var numFilters = ccdsoftCamera.lNumberFilters;\n")
+		script.WriteString("var result = \"\";\n")
+		script.WriteString("var i;\n")
+		script.WriteString("for (i = 0; i < numFilters; i++) {\n")
+		script.WriteString("   filterName = ccdsoftCamera.szFilterName(i);\n")
+		script.WriteString("   result = result + \"\\t\" + filterName;\n")
+		script.WriteString("}\n")
+		script.WriteString("var out = result + \"\\n\";\n")
+		reply := sendPacket(t, conn, script.String())
+		require.Equal(t, "\tL\tR\t\n|No error. Error = 0.", reply)
+	})
+
+	t.Run("arrays, if and Math", func(t *testing.T) {
+		fake, conn := startFakeServer(t)
+		defer fake.Close()
+		defer conn.Close()
+
+		var script strings.Builder
+		script.WriteString("var counts = [];\n")
+		script.WriteString("for (var i = 0; i < 6; i++) { counts[i] = 0; }\n")
+		script.WriteString("for (i = 0; i < 10; i++) {\n")
+		script.WriteString("   var bucket = Math.floor(i / 2);\n")
+		script.WriteString("   if (bucket > 3) { counts[5]++; } else { counts[bucket] += 1; }\n")
+		script.WriteString("}\n")
+		script.WriteString("var out = counts + \"\\t\" + Math.max(2, Math.sqrt(16)) + \"\\n\";\n")
+		reply := sendPacket(t, conn, script.String())
+		require.Equal(t, "2,2,2,2,0,2\t4\n|No error. Error = 0.", reply)
+	})
+
+	t.Run("no filter wheel", func(t *testing.T) {
+		fake := NewFakeTheSkyServer(false, 0)
+		fake.SetHasFilterWheel(false)
+		require.Nil(t, fake.Start())
+		defer fake.Close()
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", fake.Port()))
+		require.Nil(t, err)
+		defer conn.Close()
+
+		reply := sendPacket(t, conn, "result = ccdsoftCamera.filterWheelConnect();\nvar out;\nout = result + \"\\n\";\n")
+		require.True(t, strings.HasSuffix(reply, "Error = 1166."), "Expected ERR_CANNOT_COLORGRAB, got %s", reply)
+	})
+
+	t.Run("syntax error", func(t *testing.T) {
+		fake, conn := startFakeServer(t)
+		defer fake.Close()
+		defer conn.Close()
+
+		reply := sendPacket(t, conn, "var x = (1 + ;\n")
+		require.Contains(t, reply, "SyntaxError")
+	})
+}
@@ -0,0 +1,170 @@
+package fakeTheSkyX
+
+import (
+	"math"
+	"time"
+)
+
+//	Simulated mount, sky6RASCOMTele.  It moves in a straight line in altitude and azimuth at
+//	the server's slew rate, which is all the driver's calibration use needs.
+
+type simulatedTelescope struct {
+	server *FakeTheSkyServer
+
+	connected     bool
+	asynchronous  bool
+	tracking      bool
+	parked        bool
+	startAltitude float64
+	startAzimuth  float64
+	altitude      float64 // Target of the current slew, or position if not slewing
+	azimuth       float64
+	slewStart     time.Time
+	slewEnd       time.Time
+	dAlt          float64 // Results of GetAzAlt, as TheSkyX leaves them
+	dAz           float64
+}
+
+func newSimulatedTelescope(server *FakeTheSkyServer) *simulatedTelescope {
+	return &simulatedTelescope{
+		server:        server,
+		parked:        true,
+		startAltitude: server.parkAltitude,
+		startAzimuth:  server.parkAzimuth,
+		altitude:      server.parkAltitude,
+		azimuth:       server.parkAzimuth,
+	}
+}
+
+// position returns where the mount is pointing now, part way through a slew if one is going on
+func (telescope *simulatedTelescope) position() (float64, float64) {
+	now := telescope.server.now()
+	if !now.Before(telescope.slewEnd) {
+		return telescope.altitude, telescope.azimuth
+	}
+	fraction := now.Sub(telescope.slewStart).Seconds() / telescope.slewEnd.Sub(telescope.slewStart).Seconds()
+	return telescope.startAltitude + fraction*(telescope.altitude-telescope.startAltitude),
+		telescope.startAzimuth + fraction*(telescope.azimuth-telescope.startAzimuth)
+}
+
+// startSlew begins moving to the given position; synchronous slews block until it's reached
+func (telescope *simulatedTelescope) startSlew(altitude float64, azimuth float64) {
+	telescope.startAltitude, telescope.startAzimuth = telescope.position()
+	telescope.altitude = altitude
+	telescope.azimuth = azimuth
+	distance := math.Max(math.Abs(altitude-telescope.startAltitude), math.Abs(azimuth-telescope.startAzimuth))
+	duration := time.Duration(distance / telescope.server.slewRate * float64(time.Second))
+	telescope.slewStart = telescope.server.now()
+	telescope.slewEnd = telescope.slewStart.Add(duration)
+	if !telescope.asynchronous {
+		time.Sleep(duration)
+	}
+}
+
+func (telescope *simulatedTelescope) getProperty(name string) (scriptValue, error) {
+	if !telescope.connected {
+		return nil, newScriptError(errorNoLink, "TypeError: Telescope is not connected.")
+	}
+	switch name {
+	case "Asynchronous":
+		return telescope.asynchronous, nil
+	case "IsSlewComplete":
+		if telescope.server.now().Before(telescope.slewEnd) {
+			return 0.0, nil
+		}
+		return 1.0, nil
+	case "IsTracking":
+		if telescope.tracking {
+			return 1.0, nil
+		}
+		return 0.0, nil
+	case "dAlt":
+		return telescope.dAlt, nil
+	case "dAz":
+		return telescope.dAz, nil
+	}
+	return nil, newScriptError(errorUnknownMember, "TypeError: sky6RASCOMTele has no property %s", name)
+}
+
+func (telescope *simulatedTelescope) setProperty(name string, value scriptValue) error {
+	if name == "Asynchronous" {
+		telescope.asynchronous = toBool(value)
+		return nil
+	}
+	return newScriptError(errorUnknownMember, "TypeError: sky6RASCOMTele has no writable property %s", name)
+}
+
+func (telescope *simulatedTelescope) callMethod(name string, args []scriptValue) (scriptValue, error) {
+	if name == "Connect" {
+		telescope.connected = true
+		return 0.0, nil
+	}
+	if !telescope.connected {
+		return nil, newScriptError(errorNoLink, "TypeError: Telescope is not connected.")
+	}
+	switch name {
+	case "Disconnect":
+		telescope.connected = false
+		return 0.0, nil
+	case "SlewToAzAlt":
+		if len(args) != 3 {
+			return nil, newScriptError(errorSyntax, "TypeError: SlewToAzAlt expects three arguments")
+		}
+		if telescope.parked {
+			return nil, newScriptError(errorDeviceParked, "TypeError: Telescope is parked.")
+		}
+		telescope.startSlew(toNumber(args[1]), toNumber(args[0]))
+		return 0.0, nil
+	case "Jog":
+		if len(args) != 2 {
+			return nil, newScriptError(errorSyntax, "TypeError: Jog expects two arguments")
+		}
+		if telescope.parked {
+			return nil, newScriptError(errorDeviceParked, "TypeError: Telescope is parked.")
+		}
+		// Near enough for the fake: north and south move in altitude, east and west in azimuth
+		degrees := toNumber(args[0]) / 60.0
+		altitude, azimuth := telescope.position()
+		switch formatValue(args[1]) {
+		case "N":
+			altitude += degrees
+		case "S":
+			altitude -= degrees
+		case "E":
+			azimuth += degrees
+		case "W":
+			azimuth -= degrees
+		default:
+			return nil, newScriptError(errorSyntax, "TypeError: Jog direction must be N, S, E or W")
+		}
+		telescope.startSlew(altitude, azimuth)
+		return 0.0, nil
+	case "GetAzAlt":
+		telescope.dAlt, telescope.dAz = telescope.position()
+		return 0.0, nil
+	case "Park", "ParkAndDoNotDisconnect":
+		telescope.tracking = false
+		telescope.parked = true
+		telescope.startSlew(telescope.server.parkAltitude, telescope.server.parkAzimuth)
+		if name == "Park" {
+			telescope.connected = false
+		}
+		return 0.0, nil
+	case "Unpark":
+		telescope.parked = false
+		return 0.0, nil
+	case "IsParked":
+		return telescope.parked, nil
+	case "SetTracking":
+		if len(args) != 4 {
+			return nil, newScriptError(errorSyntax, "TypeError: SetTracking expects four arguments")
+		}
+		telescope.tracking = toBool(args[0])
+		return 0.0, nil
+	case "Abort":
+		telescope.altitude, telescope.azimuth = telescope.position()
+		telescope.slewEnd = telescope.server.now()
+		return 0.0, nil
+	}
+	return nil, newScriptError(errorUnknownMember, "TypeError: sky6RASCOMTele has no method %s", name)
+}